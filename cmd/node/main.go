@@ -1,51 +1,93 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/oleglegun/blockchain-btc/internal/cryptography"
 	"github.com/oleglegun/blockchain-btc/internal/genproto"
 	"github.com/oleglegun/blockchain-btc/internal/node"
-	"github.com/oleglegun/blockchain-btc/internal/random"
+	"github.com/oleglegun/blockchain-btc/internal/types"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
 	nodeCount := flag.Int("nodeCount", 3, "Number of nodes in the network")
+	dataDir := flag.String("dataDir", "", "Directory for the node databases (in-memory storage if empty)")
+	genesisFile := flag.String("genesis", "", "Path to the JSON genesis file (default network if empty)")
 	flag.Parse()
 
+	params := node.DefaultChainParams()
+	if *genesisFile != "" {
+		var err error
+		if params, err = node.LoadChainParams(*genesisFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	log.Printf("Running blockchain with %d nodes", *nodeCount)
 
+	var demoChain *node.Chain
+
 	// Create and start the specified number of nodes
 	for i := 1; i <= *nodeCount; i++ {
 		port := 3000 + i
 		listenAddr := fmt.Sprintf(":%d", port)
 		bootstrapNodes := make([]string, 0, *nodeCount)
 
+		// In the proof-of-work and proof-of-stake modes every node can produce blocks
+		isProducer := params.Consensus == node.ConsensusProofOfWork || params.Consensus == node.ConsensusProofOfStake
+
+		var (
+			chain *node.Chain
+			err   error
+		)
 		if i == 1 {
 			// The first node is a validator and does not have any bootstrap nodes
-			makeNode(listenAddr, true, bootstrapNodes)
+			chain, err = makeNode(listenAddr, true, bootstrapNodes, params, *dataDir)
 		} else {
 			// Subsequent nodes are not validators and bootstrap from the previous node
 			// Nodes will discover each other through the nodes gossip protocol
-			makeNode(listenAddr, false, []string{fmt.Sprintf("localhost:%d", port-1)})
+			chain, err = makeNode(listenAddr, isProducer, []string{fmt.Sprintf("localhost:%d", port-1)}, params, *dataDir)
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+		if port == 3002 {
+			demoChain = chain
 		}
 
 		// Sleep for a second to allow the node to start
 		time.Sleep(time.Second)
 	}
 
+	if demoChain == nil {
+		select {}
+	}
+
+	wallet, err := newDemoWallet(demoChain)
+	if err != nil {
+		// Only the genesis allocation of the default network can be spent by the demo
+		log.Println("no demo transactions:", err)
+		select {}
+	}
+
 	// Continuously make transactions to the node running on port 3002
-	// that will be shared with the network
+	// that will be shared with the network. Every transaction spends the change
+	// of the previous one, so most of them spend unconfirmed outputs.
 	for {
-		// In this demo transactions are not verified and are always accepted by the validator loop
-		// But the system itself is capable of verifying transactions
-		go makeTransaction(":3002")
+		if err := makeTransaction(":3002", wallet); err != nil {
+			log.Fatal(err)
+		}
 		time.Sleep(time.Millisecond * 1000)
 	}
 }
@@ -54,7 +96,7 @@ func main() {
  *  Temp testing functions
  *----------------------------------------------------------------------------*/
 
-func makeNode(listenAddr string, isValidator bool, bootstrapNodes []string) error {
+func makeNode(listenAddr string, isValidator bool, bootstrapNodes []string, params *node.ChainParams, dataDir string) (*node.Chain, error) {
 	nodeConfig := node.NodeConfig{
 		Version:    "1",
 		ListenAddr: listenAddr,
@@ -65,7 +107,10 @@ func makeNode(listenAddr string, isValidator bool, bootstrapNodes []string) erro
 		nodeConfig.PrivateKey = &privKey
 	}
 
-	chain := node.NewChain(node.NewMemoryBlockStore(), node.NewMemoryTxStore(), node.NewMemoryUTXOStore())
+	chain, err := makeChain(listenAddr, params, dataDir)
+	if err != nil {
+		return nil, err
+	}
 
 	nodeServer := node.NewNode(nodeConfig, chain)
 
@@ -73,54 +118,118 @@ func makeNode(listenAddr string, isValidator bool, bootstrapNodes []string) erro
 		log.Fatal(nodeServer.Start(bootstrapNodes))
 	}()
 
-	return nil
+	return chain, nil
+}
+
+func makeChain(listenAddr string, params *node.ChainParams, dataDir string) (*node.Chain, error) {
+	if dataDir == "" {
+		return node.NewChain(params, node.NewMemoryStore())
+	}
+
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+
+	store, err := node.OpenDiskStore(filepath.Join(dataDir, fmt.Sprintf("node%s.db", strings.ReplaceAll(listenAddr, ":", "-"))))
+	if err != nil {
+		return nil, err
+	}
+
+	return node.NewChain(params, store)
 }
 
 var clientConnCache = make(map[string]*grpc.ClientConn)
 
-func makeTransaction(addr string) {
-	clientConn, err := getClientConn(addr)
+// demoWallet spends the genesis allocation of the default network in a chain of transactions.
+type demoWallet struct {
+	params  *node.ChainParams
+	privKey cryptography.PrivateKey
+	// prevTx is the last transaction of the wallet, its output prevOutIndex is the change of the wallet
+	prevTx       *genproto.Transaction
+	prevOutIndex int
+}
+
+func newDemoWallet(chain *node.Chain) (*demoWallet, error) {
+	privKey := node.GenesisPrivateKey()
+	address := privKey.Public().Address().Bytes()
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	sender1PrivKey := cryptography.NewPrivateKey()
-	sender2PrivKey := cryptography.NewPrivateKey()
+	genesisTx := genesisBlock.Transactions[0]
+	for idx, output := range genesisTx.Outputs {
+		if bytes.Equal(output.Address, address) {
+			return &demoWallet{params: chain.Params(), privKey: privKey, prevTx: genesisTx, prevOutIndex: idx}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("the genesis block allocates nothing to the genesis key")
+}
+
+// nextTransaction sends 1 coin to a new address and the change back to the wallet. It pays the minimum fee.
+func (w *demoWallet) nextTransaction() (*genproto.Transaction, error) {
+	spentAmount := w.prevTx.Outputs[w.prevOutIndex].Amount
 	receiverPrivKey := cryptography.NewPrivateKey()
 
-	txIn1 := &genproto.TxInput{
-		PrevTxHash:     random.Random32ByteHash(),
-		PrevTxOutIndex: 0,
-		PublicKey:      sender1PrivKey.Public().Bytes(),
-		// Signature will be set after constructing transaction
+	tx := &genproto.Transaction{
+		Version: 1,
+		Inputs: []*genproto.TxInput{
+			{
+				PrevTxHash:     types.HashTransactionBytes(w.prevTx),
+				PrevTxOutIndex: uint32(w.prevOutIndex),
+				PublicKey:      w.privKey.Public().Bytes(),
+			},
+		},
+		Outputs: []*genproto.TxOutput{
+			{
+				Amount:  1,
+				Address: receiverPrivKey.Public().Address().Bytes(),
+			},
+			{
+				// The fee is calculated from the size of the signed transaction with the change amount
+				// at its largest, so it covers the final transaction
+				Amount:  spentAmount,
+				Address: w.privKey.Public().Address().Bytes(),
+			},
+		},
 	}
 
-	txIn2 := &genproto.TxInput{
-		PrevTxHash:     random.Random32ByteHash(),
-		PrevTxOutIndex: 1,
-		PublicKey:      sender2PrivKey.Public().Bytes(),
-		// Signature will be set after constructing transaction
+	if err := types.SignTransactionInput(w.privKey, tx, 0, []int64{spentAmount}, w.params.ChainID, types.SigHashAll); err != nil {
+		return nil, err
 	}
 
-	txOut1 := &genproto.TxOutput{
-		Amount:  9,
-		Address: receiverPrivKey.Public().Address().Bytes(),
+	change := spentAmount - 1 - w.params.MinTransactionFee(tx)
+	if change < 0 {
+		return nil, fmt.Errorf("the wallet has run out of coins")
 	}
-	txOut2 := &genproto.TxOutput{
-		Amount:  1,
-		Address: sender1PrivKey.Public().Address().Bytes(),
+	tx.Outputs[1].Amount = change
+
+	if err := types.SignTransactionInput(w.privKey, tx, 0, []int64{spentAmount}, w.params.ChainID, types.SigHashAll); err != nil {
+		return nil, err
 	}
 
-	tx := &genproto.Transaction{
-		Version: 1,
-		Inputs:  []*genproto.TxInput{txIn1, txIn2},
-		Outputs: []*genproto.TxOutput{txOut1, txOut2},
+	w.prevTx, w.prevOutIndex = tx, 1
+	return tx, nil
+}
+
+func makeTransaction(addr string, wallet *demoWallet) error {
+	clientConn, err := getClientConn(addr)
+	if err != nil {
+		return err
 	}
 
-	_, err = genproto.NewNodeClient(clientConn).HandleTransaction(context.Background(), tx)
+	tx, err := wallet.nextTransaction()
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	if _, err := genproto.NewNodeClient(clientConn).HandleTransaction(context.Background(), tx); err != nil {
+		return fmt.Errorf("transaction rejected: %w", err)
 	}
+
+	return nil
 }
 
 func getClientConn(addr string) (*grpc.ClientConn, error) {
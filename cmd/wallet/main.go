@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/node"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const usage = `Usage: wallet <command> [flags]
+
+Commands:
+  keygen            Generate a new key
+  multisig-address  Print the address of an M-of-N multisig policy
+  pay               Pay from an output of a key to a key or multisig address and send the transaction
+  multisig-spend    Create an unsigned transaction spending a multisig output
+  multisig-sign     Add the signature of a co-signer to a transaction file
+  multisig-combine  Merge the signatures of the copies of a transaction file
+  send              Send the transaction of a transaction file to a node
+
+Run "wallet <command> -h" for the flags of a command.
+`
+
+// txFile is the file format of multisig transactions passed between the co-signers.
+// It carries the spent amounts, so the co-signers can sign without access to the chain.
+type txFile struct {
+	SpentAmounts []int64         `json:"spentAmounts"`
+	Transaction  json.RawMessage `json:"transaction"`
+}
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func(args []string) error{
+		"keygen":           keygen,
+		"multisig-address": multisigAddress,
+		"pay":              pay,
+		"multisig-spend":   multisigSpend,
+		"multisig-sign":    multisigSign,
+		"multisig-combine": multisigCombine,
+		"send":             send,
+	}
+
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := command(os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func keygen(args []string) error {
+	flags := flag.NewFlagSet("keygen", flag.ExitOnError)
+	flags.Parse(args)
+
+	privKey := cryptography.NewPrivateKey()
+	fmt.Println("private key:", hex.EncodeToString(privKey.Bytes()[:cryptography.SeedLen]))
+	fmt.Println("public key: ", hex.EncodeToString(privKey.Public().Bytes()))
+	fmt.Println("address:    ", types.EncodeAddress(privKey.Public().Address().Bytes(), genproto.OutputType_TRANSFER))
+
+	return nil
+}
+
+func multisigAddress(args []string) error {
+	flags := flag.NewFlagSet("multisig-address", flag.ExitOnError)
+	threshold := flags.Int("threshold", 0, "Number of signatures required to spend the outputs (M)")
+	pubKeys := flags.String("pubKeys", "", "Comma-separated hex public keys of the co-signers (N), their order is a part of the address")
+	flags.Parse(args)
+
+	policy, err := parsePolicy(*threshold, *pubKeys)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(types.EncodeAddress(types.MultisigAddress(policy), genproto.OutputType_MULTISIG))
+	return nil
+}
+
+func pay(args []string) error {
+	flags := flag.NewFlagSet("pay", flag.ExitOnError)
+	genesisFile := flags.String("genesis", "", "Path to the JSON genesis file (default network if empty)")
+	key := flags.String("key", "", "Hex private key owning the spent output")
+	prevTx := flags.String("prevTx", "", "Hex hash of the transaction of the spent output")
+	index := flags.Uint("index", 0, "Index of the spent output in its transaction")
+	amount := flags.Int64("amount", 0, "Amount of the spent output")
+	to := flags.String("to", "", "Receiving key or multisig address")
+	value := flags.Int64("value", 0, "Amount to pay, the change goes back to the key")
+	nodeAddr := flags.String("node", ":3002", "Listen address of the local node to send the transaction to")
+	flags.Parse(args)
+
+	params, err := loadParams(*genesisFile)
+	if err != nil {
+		return err
+	}
+
+	privKey := cryptography.NewPrivateKeyFromString(*key)
+	input := &genproto.TxInput{PublicKey: privKey.Public().Bytes()}
+	if input.PrevTxHash, err = hex.DecodeString(*prevTx); err != nil {
+		return fmt.Errorf("failed to decode previous transaction hash: %w", err)
+	}
+	input.PrevTxOutIndex = uint32(*index)
+
+	output, err := newOutput(*to, *value)
+	if err != nil {
+		return err
+	}
+
+	tx := &genproto.Transaction{
+		Version: 1,
+		Inputs:  []*genproto.TxInput{input},
+		Outputs: []*genproto.TxOutput{
+			output,
+			{
+				// The fee is calculated with the change amount at its largest, so it covers the final transaction
+				Amount:  *amount,
+				Address: privKey.Public().Address().Bytes(),
+			},
+		},
+	}
+
+	spentAmounts := []int64{*amount}
+	if err := types.SignTransactionInput(privKey, tx, 0, spentAmounts, params.ChainID, types.SigHashAll); err != nil {
+		return err
+	}
+
+	if tx.Outputs[1].Amount, err = change(params, tx, *amount, *value); err != nil {
+		return err
+	}
+
+	if err := types.SignTransactionInput(privKey, tx, 0, spentAmounts, params.ChainID, types.SigHashAll); err != nil {
+		return err
+	}
+
+	if err := sendTransaction(*nodeAddr, tx); err != nil {
+		return err
+	}
+
+	fmt.Println(types.HashTransactionString(tx))
+	return nil
+}
+
+func multisigSpend(args []string) error {
+	flags := flag.NewFlagSet("multisig-spend", flag.ExitOnError)
+	genesisFile := flags.String("genesis", "", "Path to the JSON genesis file (default network if empty)")
+	threshold := flags.Int("threshold", 0, "Number of signatures required to spend the output (M)")
+	pubKeys := flags.String("pubKeys", "", "Comma-separated hex public keys of the co-signers (N) in the order of the policy")
+	prevTx := flags.String("prevTx", "", "Hex hash of the transaction of the spent output")
+	index := flags.Uint("index", 0, "Index of the spent output in its transaction")
+	amount := flags.Int64("amount", 0, "Amount of the spent output")
+	to := flags.String("to", "", "Receiving key or multisig address")
+	value := flags.Int64("value", 0, "Amount to pay, the change goes back to the multisig address")
+	out := flags.String("out", "", "Path of the created transaction file")
+	flags.Parse(args)
+
+	params, err := loadParams(*genesisFile)
+	if err != nil {
+		return err
+	}
+
+	policy, err := parsePolicy(*threshold, *pubKeys)
+	if err != nil {
+		return err
+	}
+
+	prevTxHash, err := hex.DecodeString(*prevTx)
+	if err != nil {
+		return fmt.Errorf("failed to decode previous transaction hash: %w", err)
+	}
+
+	output, err := newOutput(*to, *value)
+	if err != nil {
+		return err
+	}
+
+	tx := &genproto.Transaction{
+		Version: 1,
+		Inputs:  []*genproto.TxInput{types.NewMultisigInput(prevTxHash, uint32(*index), policy)},
+		Outputs: []*genproto.TxOutput{
+			output,
+			types.NewMultisigOutput(*amount, types.MultisigAddress(policy)),
+		},
+	}
+
+	// Signatures have a fixed size, so the fee is calculated from a copy with placeholders in threshold slots
+	signedTx := proto.Clone(tx).(*genproto.Transaction)
+	for keyIdx := range int(policy.Threshold) {
+		signedTx.Inputs[0].MultisigSignatures[keyIdx] = make([]byte, cryptography.SigLen)
+	}
+
+	if tx.Outputs[1].Amount, err = change(params, signedTx, *amount, *value); err != nil {
+		return err
+	}
+
+	return writeTxFile(*out, tx, []int64{*amount})
+}
+
+func multisigSign(args []string) error {
+	flags := flag.NewFlagSet("multisig-sign", flag.ExitOnError)
+	genesisFile := flags.String("genesis", "", "Path to the JSON genesis file (default network if empty)")
+	key := flags.String("key", "", "Hex private key of the co-signer")
+	in := flags.String("in", "", "Path of the transaction file, it is updated with the signature")
+	flags.Parse(args)
+
+	params, err := loadParams(*genesisFile)
+	if err != nil {
+		return err
+	}
+
+	tx, spentAmounts, err := readTxFile(*in)
+	if err != nil {
+		return err
+	}
+
+	privKey := cryptography.NewPrivateKeyFromString(*key)
+	for idx, input := range tx.Inputs {
+		if input.Multisig == nil {
+			continue
+		}
+
+		if err := types.SignMultisigInput(privKey, tx, idx, spentAmounts, params.ChainID, types.SigHashAll); err != nil {
+			return err
+		}
+	}
+
+	return writeTxFile(*in, tx, spentAmounts)
+}
+
+func multisigCombine(args []string) error {
+	flags := flag.NewFlagSet("multisig-combine", flag.ExitOnError)
+	out := flags.String("out", "", "Path of the combined transaction file")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("no transaction files to combine")
+	}
+
+	tx, spentAmounts, err := readTxFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range flags.Args()[1:] {
+		other, _, err := readTxFile(path)
+		if err != nil {
+			return err
+		}
+
+		if err := types.CombineMultisigSignatures(tx, other); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return writeTxFile(*out, tx, spentAmounts)
+}
+
+func send(args []string) error {
+	flags := flag.NewFlagSet("send", flag.ExitOnError)
+	in := flags.String("in", "", "Path of the transaction file")
+	nodeAddr := flags.String("node", ":3002", "Listen address of the local node to send the transaction to")
+	flags.Parse(args)
+
+	tx, _, err := readTxFile(*in)
+	if err != nil {
+		return err
+	}
+
+	if err := sendTransaction(*nodeAddr, tx); err != nil {
+		return err
+	}
+
+	fmt.Println(types.HashTransactionString(tx))
+	return nil
+}
+
+func loadParams(genesisFile string) (*node.ChainParams, error) {
+	if genesisFile == "" {
+		return node.DefaultChainParams(), nil
+	}
+
+	return node.LoadChainParams(genesisFile)
+}
+
+// parsePolicy creates the multisig policy from the threshold and the comma-separated hex public keys.
+func parsePolicy(threshold int, pubKeys string) (*genproto.MultisigPolicy, error) {
+	var keys []cryptography.PublicKey
+	for _, s := range strings.Split(pubKeys, ",") {
+		b, err := hex.DecodeString(strings.TrimSpace(s))
+		if err != nil || len(b) != cryptography.PubKeyLen {
+			return nil, fmt.Errorf("malformed public key %q", s)
+		}
+		keys = append(keys, cryptography.NewPublicKeyFromBytes(b))
+	}
+
+	return types.NewMultisigPolicy(threshold, keys)
+}
+
+// newOutput creates an output paying the amount to the key or multisig address.
+func newOutput(address string, amount int64) (*genproto.TxOutput, error) {
+	addressBytes, outputType, err := types.DecodeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &genproto.TxOutput{Amount: amount, Address: addressBytes, Type: outputType}, nil
+}
+
+// change returns the change of the spent amount after paying the value and the minimum fee of the signed transaction.
+func change(params *node.ChainParams, signedTx *genproto.Transaction, spentAmount, value int64) (int64, error) {
+	change := spentAmount - value - params.MinTransactionFee(signedTx)
+	if value <= 0 || change < 0 {
+		return 0, fmt.Errorf("can't pay %d out of %d with a fee of %d", value, spentAmount, params.MinTransactionFee(signedTx))
+	}
+
+	return change, nil
+}
+
+func readTxFile(path string) (*genproto.Transaction, []int64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var file txFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode transaction file %s: %w", path, err)
+	}
+
+	tx := &genproto.Transaction{}
+	if err := protojson.Unmarshal(file.Transaction, tx); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode transaction of %s: %w", path, err)
+	}
+
+	return tx, file.SpentAmounts, nil
+}
+
+func writeTxFile(path string, tx *genproto.Transaction, spentAmounts []int64) error {
+	txJSON, err := protojson.Marshal(tx)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(txFile{SpentAmounts: spentAmounts, Transaction: txJSON}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		_, err := fmt.Println(string(b))
+		return err
+	}
+
+	return os.WriteFile(path, append(b, '\n'), 0600)
+}
+
+func sendTransaction(addr string, tx *genproto.Transaction) error {
+	conn, err := grpc.NewClient("dns:///localhost"+addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := genproto.NewNodeClient(conn).HandleTransaction(context.Background(), tx); err != nil {
+		return fmt.Errorf("transaction rejected: %w", err)
+	}
+
+	return nil
+}
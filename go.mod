@@ -5,6 +5,7 @@ go 1.23.0
 require (
 	github.com/cbergoon/merkletree v0.2.0
 	github.com/stretchr/testify v1.9.0
+	go.etcd.io/bbolt v1.3.11
 	google.golang.org/grpc v1.66.2
 	google.golang.org/protobuf v1.34.2
 )
@@ -21,6 +21,160 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type OutputType int32
+
+const (
+	// TRANSFER outputs can be spent by their owner at any time.
+	OutputType_TRANSFER OutputType = 0
+	// STAKE outputs lock the coins as stake of the owner (proof-of-stake only).
+	// They can only be spent into UNBONDING outputs of the same owner.
+	OutputType_STAKE OutputType = 1
+	// UNBONDING outputs can be spent once the unbonding period has passed.
+	OutputType_UNBONDING OutputType = 2
+	// MULTISIG outputs are owned by a multisig policy. Their address is the hash of the policy.
+	OutputType_MULTISIG OutputType = 3
+	// DATA outputs carry arbitrary data and can never be spent. They have no amount and address
+	// and are never added to the UTXO set.
+	OutputType_DATA OutputType = 4
+)
+
+// Enum value maps for OutputType.
+var (
+	OutputType_name = map[int32]string{
+		0: "TRANSFER",
+		1: "STAKE",
+		2: "UNBONDING",
+		3: "MULTISIG",
+		4: "DATA",
+	}
+	OutputType_value = map[string]int32{
+		"TRANSFER":  0,
+		"STAKE":     1,
+		"UNBONDING": 2,
+		"MULTISIG":  3,
+		"DATA":      4,
+	}
+)
+
+func (x OutputType) Enum() *OutputType {
+	p := new(OutputType)
+	*p = x
+	return p
+}
+
+func (x OutputType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OutputType) Descriptor() protoreflect.EnumDescriptor {
+	return file_blockchain_proto_enumTypes[0].Descriptor()
+}
+
+func (OutputType) Type() protoreflect.EnumType {
+	return &file_blockchain_proto_enumTypes[0]
+}
+
+func (x OutputType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OutputType.Descriptor instead.
+func (OutputType) EnumDescriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{0}
+}
+
+type GovernanceAction_Type int32
+
+const (
+	GovernanceAction_ADD_VALIDATOR    GovernanceAction_Type = 0
+	GovernanceAction_REMOVE_VALIDATOR GovernanceAction_Type = 1
+)
+
+// Enum value maps for GovernanceAction_Type.
+var (
+	GovernanceAction_Type_name = map[int32]string{
+		0: "ADD_VALIDATOR",
+		1: "REMOVE_VALIDATOR",
+	}
+	GovernanceAction_Type_value = map[string]int32{
+		"ADD_VALIDATOR":    0,
+		"REMOVE_VALIDATOR": 1,
+	}
+)
+
+func (x GovernanceAction_Type) Enum() *GovernanceAction_Type {
+	p := new(GovernanceAction_Type)
+	*p = x
+	return p
+}
+
+func (x GovernanceAction_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GovernanceAction_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_blockchain_proto_enumTypes[1].Descriptor()
+}
+
+func (GovernanceAction_Type) Type() protoreflect.EnumType {
+	return &file_blockchain_proto_enumTypes[1]
+}
+
+func (x GovernanceAction_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GovernanceAction_Type.Descriptor instead.
+func (GovernanceAction_Type) EnumDescriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{11, 0}
+}
+
+type Vote_Type int32
+
+const (
+	Vote_PREVOTE   Vote_Type = 0
+	Vote_PRECOMMIT Vote_Type = 1
+)
+
+// Enum value maps for Vote_Type.
+var (
+	Vote_Type_name = map[int32]string{
+		0: "PREVOTE",
+		1: "PRECOMMIT",
+	}
+	Vote_Type_value = map[string]int32{
+		"PREVOTE":   0,
+		"PRECOMMIT": 1,
+	}
+)
+
+func (x Vote_Type) Enum() *Vote_Type {
+	p := new(Vote_Type)
+	*p = x
+	return p
+}
+
+func (x Vote_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Vote_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_blockchain_proto_enumTypes[2].Descriptor()
+}
+
+func (Vote_Type) Type() protoreflect.EnumType {
+	return &file_blockchain_proto_enumTypes[2]
+}
+
+func (x Vote_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Vote_Type.Descriptor instead.
+func (Vote_Type) EnumDescriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{12, 0}
+}
+
 type NodeInfo struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -31,6 +185,12 @@ type NodeInfo struct {
 	// listenAddr is an address the node is listening on for connections.
 	ListenAddr string   `protobuf:"bytes,3,opt,name=listenAddr,proto3" json:"listenAddr,omitempty"`
 	PeerList   []string `protobuf:"bytes,4,rep,name=peerList,proto3" json:"peerList,omitempty"`
+	// bestHash is the hash of the block at the tip of the node's main chain.
+	BestHash []byte `protobuf:"bytes,5,opt,name=bestHash,proto3" json:"bestHash,omitempty"`
+	// genesisHash is the hash of the genesis block. Nodes only connect to peers with the same genesis block.
+	GenesisHash []byte `protobuf:"bytes,6,opt,name=genesisHash,proto3" json:"genesisHash,omitempty"`
+	// chainId identifies the network of the node. Nodes only connect to peers with the same chain ID.
+	ChainId string `protobuf:"bytes,7,opt,name=chainId,proto3" json:"chainId,omitempty"`
 }
 
 func (x *NodeInfo) Reset() {
@@ -93,6 +253,188 @@ func (x *NodeInfo) GetPeerList() []string {
 	return nil
 }
 
+func (x *NodeInfo) GetBestHash() []byte {
+	if x != nil {
+		return x.BestHash
+	}
+	return nil
+}
+
+func (x *NodeInfo) GetGenesisHash() []byte {
+	if x != nil {
+		return x.GenesisHash
+	}
+	return nil
+}
+
+func (x *NodeInfo) GetChainId() string {
+	if x != nil {
+		return x.ChainId
+	}
+	return ""
+}
+
+// BlockRangeRequest selects a range of consecutive blocks of the main chain.
+type BlockRangeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// fromHeight is the height of the first requested block. Ignored if fromHash is set.
+	FromHeight int32 `protobuf:"varint,1,opt,name=fromHeight,proto3" json:"fromHeight,omitempty"`
+	// fromHash is the hash of the first requested block.
+	FromHash []byte `protobuf:"bytes,2,opt,name=fromHash,proto3" json:"fromHash,omitempty"`
+	// count is the maximum number of requested blocks.
+	Count int32 `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+}
+
+func (x *BlockRangeRequest) Reset() {
+	*x = BlockRangeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockchain_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockRangeRequest) ProtoMessage() {}
+
+func (x *BlockRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockRangeRequest.ProtoReflect.Descriptor instead.
+func (*BlockRangeRequest) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BlockRangeRequest) GetFromHeight() int32 {
+	if x != nil {
+		return x.FromHeight
+	}
+	return 0
+}
+
+func (x *BlockRangeRequest) GetFromHash() []byte {
+	if x != nil {
+		return x.FromHash
+	}
+	return nil
+}
+
+func (x *BlockRangeRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type BlockHeaders struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Headers []*BlockHeader `protobuf:"bytes,1,rep,name=headers,proto3" json:"headers,omitempty"`
+}
+
+func (x *BlockHeaders) Reset() {
+	*x = BlockHeaders{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockchain_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockHeaders) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockHeaders) ProtoMessage() {}
+
+func (x *BlockHeaders) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockHeaders.ProtoReflect.Descriptor instead.
+func (*BlockHeaders) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BlockHeaders) GetHeaders() []*BlockHeader {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+type Blocks struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Blocks []*Block `protobuf:"bytes,1,rep,name=blocks,proto3" json:"blocks,omitempty"`
+}
+
+func (x *Blocks) Reset() {
+	*x = Blocks{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockchain_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Blocks) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Blocks) ProtoMessage() {}
+
+func (x *Blocks) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Blocks.ProtoReflect.Descriptor instead.
+func (*Blocks) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Blocks) GetBlocks() []*Block {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
 type Block struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -107,7 +449,7 @@ type Block struct {
 func (x *Block) Reset() {
 	*x = Block{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_blockchain_proto_msgTypes[1]
+		mi := &file_blockchain_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -120,7 +462,7 @@ func (x *Block) String() string {
 func (*Block) ProtoMessage() {}
 
 func (x *Block) ProtoReflect() protoreflect.Message {
-	mi := &file_blockchain_proto_msgTypes[1]
+	mi := &file_blockchain_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -133,7 +475,7 @@ func (x *Block) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Block.ProtoReflect.Descriptor instead.
 func (*Block) Descriptor() ([]byte, []int) {
-	return file_blockchain_proto_rawDescGZIP(), []int{1}
+	return file_blockchain_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *Block) GetHeader() *BlockHeader {
@@ -178,12 +520,20 @@ type BlockHeader struct {
 	RootHash []byte `protobuf:"bytes,4,opt,name=rootHash,proto3" json:"rootHash,omitempty"`
 	// Unix timestamp when the block was created
 	Timestamp int64 `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Value miners change to find a block hash that meets the target (proof-of-work only)
+	Nonce uint64 `protobuf:"varint,6,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// Compact representation of the difficulty target (proof-of-work only)
+	Bits uint32 `protobuf:"varint,7,opt,name=bits,proto3" json:"bits,omitempty"`
+	// Secret the producer committed to in its previous block, it is mixed into the proposer draw seed (proof-of-stake only)
+	SeedReveal []byte `protobuf:"bytes,8,opt,name=seedReveal,proto3" json:"seedReveal,omitempty"`
+	// Hash of the secret the producer reveals in its next block (proof-of-stake only)
+	SeedCommitment []byte `protobuf:"bytes,9,opt,name=seedCommitment,proto3" json:"seedCommitment,omitempty"`
 }
 
 func (x *BlockHeader) Reset() {
 	*x = BlockHeader{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_blockchain_proto_msgTypes[2]
+		mi := &file_blockchain_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -196,7 +546,7 @@ func (x *BlockHeader) String() string {
 func (*BlockHeader) ProtoMessage() {}
 
 func (x *BlockHeader) ProtoReflect() protoreflect.Message {
-	mi := &file_blockchain_proto_msgTypes[2]
+	mi := &file_blockchain_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -209,7 +559,7 @@ func (x *BlockHeader) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BlockHeader.ProtoReflect.Descriptor instead.
 func (*BlockHeader) Descriptor() ([]byte, []int) {
-	return file_blockchain_proto_rawDescGZIP(), []int{2}
+	return file_blockchain_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *BlockHeader) GetVersion() int32 {
@@ -247,6 +597,34 @@ func (x *BlockHeader) GetTimestamp() int64 {
 	return 0
 }
 
+func (x *BlockHeader) GetNonce() uint64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *BlockHeader) GetBits() uint32 {
+	if x != nil {
+		return x.Bits
+	}
+	return 0
+}
+
+func (x *BlockHeader) GetSeedReveal() []byte {
+	if x != nil {
+		return x.SeedReveal
+	}
+	return nil
+}
+
+func (x *BlockHeader) GetSeedCommitment() []byte {
+	if x != nil {
+		return x.SeedCommitment
+	}
+	return nil
+}
+
 type TxInput struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -257,14 +635,29 @@ type TxInput struct {
 	// prevTxOutIndex (UTXO id) is the index of the output in the previous transaction that this input is spending from.
 	PrevTxOutIndex uint32 `protobuf:"varint,2,opt,name=prevTxOutIndex,proto3" json:"prevTxOutIndex,omitempty"`
 	PublicKey      []byte `protobuf:"bytes,3,opt,name=publicKey,proto3" json:"publicKey,omitempty"`
-	// signature signs the transaction hash and the public key of the sender.
+	// signature signs the signature hash of the input, which commits to the chain ID, the input index,
+	// the amounts of the spent outputs and the parts of the transaction selected by sigHashType.
 	Signature []byte `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
+	// sigHashType selects the parts of the transaction the signature commits to (0 is SIGHASH_ALL).
+	SigHashType uint32 `protobuf:"varint,5,opt,name=sigHashType,proto3" json:"sigHashType,omitempty"`
+	// unlockScript unlocks the lock script of the spent output. It is only set for inputs spending
+	// outputs with a lock script, which carry no public key and signature.
+	UnlockScript []byte `protobuf:"bytes,6,opt,name=unlockScript,proto3" json:"unlockScript,omitempty"`
+	// multisig is the policy of the spent MULTISIG output, which carries no public key and signature.
+	Multisig *MultisigPolicy `protobuf:"bytes,7,opt,name=multisig,proto3" json:"multisig,omitempty"`
+	// multisigSignatures holds a slot for every public key of the multisig policy in the same order.
+	// Exactly threshold slots are filled by the signatures of their keys, the others are empty.
+	MultisigSignatures [][]byte `protobuf:"bytes,8,rep,name=multisigSignatures,proto3" json:"multisigSignatures,omitempty"`
+	// sequence is the relative lock of the input in the style of BIP68. Unless its disable flag (bit 31) is set,
+	// the input can only be included once the spent output is older than the number of blocks in the low 16 bits,
+	// or with the type flag (bit 22) set, than the number of 512 second units.
+	Sequence uint32 `protobuf:"varint,9,opt,name=sequence,proto3" json:"sequence,omitempty"`
 }
 
 func (x *TxInput) Reset() {
 	*x = TxInput{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_blockchain_proto_msgTypes[3]
+		mi := &file_blockchain_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -277,7 +670,7 @@ func (x *TxInput) String() string {
 func (*TxInput) ProtoMessage() {}
 
 func (x *TxInput) ProtoReflect() protoreflect.Message {
-	mi := &file_blockchain_proto_msgTypes[3]
+	mi := &file_blockchain_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -290,7 +683,7 @@ func (x *TxInput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TxInput.ProtoReflect.Descriptor instead.
 func (*TxInput) Descriptor() ([]byte, []int) {
-	return file_blockchain_proto_rawDescGZIP(), []int{3}
+	return file_blockchain_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *TxInput) GetPrevTxHash() []byte {
@@ -321,33 +714,129 @@ func (x *TxInput) GetSignature() []byte {
 	return nil
 }
 
-type TxOutput struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	Amount int64 `protobuf:"varint,1,opt,name=amount,proto3" json:"amount,omitempty"`
-	// Address of the recipient
-	Address []byte `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+func (x *TxInput) GetSigHashType() uint32 {
+	if x != nil {
+		return x.SigHashType
+	}
+	return 0
 }
 
-func (x *TxOutput) Reset() {
-	*x = TxOutput{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_blockchain_proto_msgTypes[4]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+func (x *TxInput) GetUnlockScript() []byte {
+	if x != nil {
+		return x.UnlockScript
 	}
+	return nil
 }
 
-func (x *TxOutput) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *TxInput) GetMultisig() *MultisigPolicy {
+	if x != nil {
+		return x.Multisig
+	}
+	return nil
 }
 
-func (*TxOutput) ProtoMessage() {}
+func (x *TxInput) GetMultisigSignatures() [][]byte {
+	if x != nil {
+		return x.MultisigSignatures
+	}
+	return nil
+}
+
+func (x *TxInput) GetSequence() uint32 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+// MultisigPolicy requires the signatures of threshold out of the public keys.
+type MultisigPolicy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Threshold  uint32   `protobuf:"varint,1,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	PublicKeys [][]byte `protobuf:"bytes,2,rep,name=publicKeys,proto3" json:"publicKeys,omitempty"`
+}
+
+func (x *MultisigPolicy) Reset() {
+	*x = MultisigPolicy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockchain_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MultisigPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MultisigPolicy) ProtoMessage() {}
+
+func (x *MultisigPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MultisigPolicy.ProtoReflect.Descriptor instead.
+func (*MultisigPolicy) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *MultisigPolicy) GetThreshold() uint32 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *MultisigPolicy) GetPublicKeys() [][]byte {
+	if x != nil {
+		return x.PublicKeys
+	}
+	return nil
+}
+
+type TxOutput struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Amount int64 `protobuf:"varint,1,opt,name=amount,proto3" json:"amount,omitempty"`
+	// Address of the recipient
+	Address []byte     `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Type    OutputType `protobuf:"varint,3,opt,name=type,proto3,enum=OutputType" json:"type,omitempty"`
+	// lockScript is the condition of spending the output. Outputs with a lock script have no address.
+	LockScript []byte `protobuf:"bytes,4,opt,name=lockScript,proto3" json:"lockScript,omitempty"`
+	// data is the payload of DATA outputs. It is empty for all other outputs.
+	Data []byte `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *TxOutput) Reset() {
+	*x = TxOutput{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockchain_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TxOutput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxOutput) ProtoMessage() {}
 
 func (x *TxOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_blockchain_proto_msgTypes[4]
+	mi := &file_blockchain_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -360,7 +849,7 @@ func (x *TxOutput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TxOutput.ProtoReflect.Descriptor instead.
 func (*TxOutput) Descriptor() ([]byte, []int) {
-	return file_blockchain_proto_rawDescGZIP(), []int{4}
+	return file_blockchain_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *TxOutput) GetAmount() int64 {
@@ -377,6 +866,27 @@ func (x *TxOutput) GetAddress() []byte {
 	return nil
 }
 
+func (x *TxOutput) GetType() OutputType {
+	if x != nil {
+		return x.Type
+	}
+	return OutputType_TRANSFER
+}
+
+func (x *TxOutput) GetLockScript() []byte {
+	if x != nil {
+		return x.LockScript
+	}
+	return nil
+}
+
+func (x *TxOutput) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
 type Transaction struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -385,12 +895,25 @@ type Transaction struct {
 	Version int32       `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
 	Inputs  []*TxInput  `protobuf:"bytes,2,rep,name=inputs,proto3" json:"inputs,omitempty"`
 	Outputs []*TxOutput `protobuf:"bytes,3,rep,name=outputs,proto3" json:"outputs,omitempty"`
+	// coinbaseHeight is the height of the block a coinbase transaction (the one without inputs) pays the block producer in.
+	// It keeps the hashes of coinbase transactions unique. It is 0 for all other transactions.
+	CoinbaseHeight int32 `protobuf:"varint,4,opt,name=coinbaseHeight,proto3" json:"coinbaseHeight,omitempty"`
+	// governance is set for governance transactions (proof-of-authority only), which have no inputs and outputs.
+	Governance *GovernanceAction `protobuf:"bytes,5,opt,name=governance,proto3" json:"governance,omitempty"`
+	// governanceSignatures sign the hash of the governance action. More than half of the validators have to sign it.
+	GovernanceSignatures []*ValidatorSignature `protobuf:"bytes,6,rep,name=governanceSignatures,proto3" json:"governanceSignatures,omitempty"`
+	// slashingEvidence is set for slashing transactions (proof-of-stake only), which have no inputs and outputs.
+	SlashingEvidence *SlashingEvidence `protobuf:"bytes,7,opt,name=slashingEvidence,proto3" json:"slashingEvidence,omitempty"`
+	// lockTime is the block height (below 500000000) or the Unix timestamp the transaction is locked until.
+	// The transaction can only be included in a later block, timestamps are compared with the median time
+	// of the previous blocks. 0 disables the lock.
+	LockTime uint32 `protobuf:"varint,8,opt,name=lockTime,proto3" json:"lockTime,omitempty"`
 }
 
 func (x *Transaction) Reset() {
 	*x = Transaction{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_blockchain_proto_msgTypes[5]
+		mi := &file_blockchain_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -403,7 +926,7 @@ func (x *Transaction) String() string {
 func (*Transaction) ProtoMessage() {}
 
 func (x *Transaction) ProtoReflect() protoreflect.Message {
-	mi := &file_blockchain_proto_msgTypes[5]
+	mi := &file_blockchain_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -416,7 +939,7 @@ func (x *Transaction) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
 func (*Transaction) Descriptor() ([]byte, []int) {
-	return file_blockchain_proto_rawDescGZIP(), []int{5}
+	return file_blockchain_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *Transaction) GetVersion() int32 {
@@ -440,69 +963,509 @@ func (x *Transaction) GetOutputs() []*TxOutput {
 	return nil
 }
 
+func (x *Transaction) GetCoinbaseHeight() int32 {
+	if x != nil {
+		return x.CoinbaseHeight
+	}
+	return 0
+}
+
+func (x *Transaction) GetGovernance() *GovernanceAction {
+	if x != nil {
+		return x.Governance
+	}
+	return nil
+}
+
+func (x *Transaction) GetGovernanceSignatures() []*ValidatorSignature {
+	if x != nil {
+		return x.GovernanceSignatures
+	}
+	return nil
+}
+
+func (x *Transaction) GetSlashingEvidence() *SlashingEvidence {
+	if x != nil {
+		return x.SlashingEvidence
+	}
+	return nil
+}
+
+func (x *Transaction) GetLockTime() uint32 {
+	if x != nil {
+		return x.LockTime
+	}
+	return 0
+}
+
+// SlashingEvidence proves that a validator signed two different blocks at the same height.
+type SlashingEvidence struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PublicKey  []byte       `protobuf:"bytes,1,opt,name=publicKey,proto3" json:"publicKey,omitempty"`
+	Header1    *BlockHeader `protobuf:"bytes,2,opt,name=header1,proto3" json:"header1,omitempty"`
+	Signature1 []byte       `protobuf:"bytes,3,opt,name=signature1,proto3" json:"signature1,omitempty"`
+	Header2    *BlockHeader `protobuf:"bytes,4,opt,name=header2,proto3" json:"header2,omitempty"`
+	Signature2 []byte       `protobuf:"bytes,5,opt,name=signature2,proto3" json:"signature2,omitempty"`
+}
+
+func (x *SlashingEvidence) Reset() {
+	*x = SlashingEvidence{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockchain_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SlashingEvidence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SlashingEvidence) ProtoMessage() {}
+
+func (x *SlashingEvidence) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SlashingEvidence.ProtoReflect.Descriptor instead.
+func (*SlashingEvidence) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SlashingEvidence) GetPublicKey() []byte {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *SlashingEvidence) GetHeader1() *BlockHeader {
+	if x != nil {
+		return x.Header1
+	}
+	return nil
+}
+
+func (x *SlashingEvidence) GetSignature1() []byte {
+	if x != nil {
+		return x.Signature1
+	}
+	return nil
+}
+
+func (x *SlashingEvidence) GetHeader2() *BlockHeader {
+	if x != nil {
+		return x.Header2
+	}
+	return nil
+}
+
+func (x *SlashingEvidence) GetSignature2() []byte {
+	if x != nil {
+		return x.Signature2
+	}
+	return nil
+}
+
+// GovernanceAction changes the validator set of a proof-of-authority chain.
+type GovernanceAction struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type GovernanceAction_Type `protobuf:"varint,1,opt,name=type,proto3,enum=GovernanceAction_Type" json:"type,omitempty"`
+	// publicKey is the public key of the added or removed validator.
+	PublicKey []byte `protobuf:"bytes,2,opt,name=publicKey,proto3" json:"publicKey,omitempty"`
+	// sequence is the number of governance actions applied to the validator set before this one.
+	// It prevents the action from being replayed.
+	Sequence uint64 `protobuf:"varint,3,opt,name=sequence,proto3" json:"sequence,omitempty"`
+}
+
+func (x *GovernanceAction) Reset() {
+	*x = GovernanceAction{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockchain_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GovernanceAction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GovernanceAction) ProtoMessage() {}
+
+func (x *GovernanceAction) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GovernanceAction.ProtoReflect.Descriptor instead.
+func (*GovernanceAction) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GovernanceAction) GetType() GovernanceAction_Type {
+	if x != nil {
+		return x.Type
+	}
+	return GovernanceAction_ADD_VALIDATOR
+}
+
+func (x *GovernanceAction) GetPublicKey() []byte {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *GovernanceAction) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+// Vote is a signed finality vote of a validator for a block of the main chain.
+type Vote struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type      Vote_Type `protobuf:"varint,1,opt,name=type,proto3,enum=Vote_Type" json:"type,omitempty"`
+	Height    int32     `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	BlockHash []byte    `protobuf:"bytes,3,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+	PublicKey []byte    `protobuf:"bytes,4,opt,name=publicKey,proto3" json:"publicKey,omitempty"`
+	// signature signs the hash of the vote without the signature.
+	Signature []byte `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (x *Vote) Reset() {
+	*x = Vote{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockchain_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Vote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Vote) ProtoMessage() {}
+
+func (x *Vote) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Vote.ProtoReflect.Descriptor instead.
+func (*Vote) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Vote) GetType() Vote_Type {
+	if x != nil {
+		return x.Type
+	}
+	return Vote_PREVOTE
+}
+
+func (x *Vote) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Vote) GetBlockHash() []byte {
+	if x != nil {
+		return x.BlockHash
+	}
+	return nil
+}
+
+func (x *Vote) GetPublicKey() []byte {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *Vote) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type ValidatorSignature struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PublicKey []byte `protobuf:"bytes,1,opt,name=publicKey,proto3" json:"publicKey,omitempty"`
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (x *ValidatorSignature) Reset() {
+	*x = ValidatorSignature{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockchain_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidatorSignature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidatorSignature) ProtoMessage() {}
+
+func (x *ValidatorSignature) ProtoReflect() protoreflect.Message {
+	mi := &file_blockchain_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidatorSignature.ProtoReflect.Descriptor instead.
+func (*ValidatorSignature) Descriptor() ([]byte, []int) {
+	return file_blockchain_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ValidatorSignature) GetPublicKey() []byte {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *ValidatorSignature) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
 var File_blockchain_proto protoreflect.FileDescriptor
 
 var file_blockchain_proto_rawDesc = []byte{
 	0x0a, 0x10, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x2e, 0x70, 0x72, 0x6f,
 	0x74, 0x6f, 0x1a, 0x1b, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 	0x62, 0x75, 0x66, 0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
-	0x78, 0x0a, 0x08, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x18, 0x0a, 0x07, 0x76,
-	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65,
-	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1e, 0x0a,
-	0x0a, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x41, 0x64, 0x64, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x0a, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x41, 0x64, 0x64, 0x72, 0x12, 0x1a, 0x0a,
-	0x08, 0x70, 0x65, 0x65, 0x72, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52,
-	0x08, 0x70, 0x65, 0x65, 0x72, 0x4c, 0x69, 0x73, 0x74, 0x22, 0x9b, 0x01, 0x0a, 0x05, 0x42, 0x6c,
-	0x6f, 0x63, 0x6b, 0x12, 0x24, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65,
-	0x72, 0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x75, 0x62,
-	0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x70, 0x75,
-	0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61,
-	0x74, 0x75, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e,
-	0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x30, 0x0a, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
-	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x54, 0x72,
-	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73,
-	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x95, 0x01, 0x0a, 0x0b, 0x42, 0x6c, 0x6f, 0x63,
-	0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69,
-	0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
-	0x6e, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x05, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x65,
-	0x76, 0x48, 0x61, 0x73, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x70, 0x72, 0x65,
-	0x76, 0x48, 0x61, 0x73, 0x68, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x6f, 0x6f, 0x74, 0x48, 0x61, 0x73,
-	0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x72, 0x6f, 0x6f, 0x74, 0x48, 0x61, 0x73,
-	0x68, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x05,
-	0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x22,
-	0x8d, 0x01, 0x0a, 0x07, 0x54, 0x78, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x70,
-	0x72, 0x65, 0x76, 0x54, 0x78, 0x48, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
-	0x0a, 0x70, 0x72, 0x65, 0x76, 0x54, 0x78, 0x48, 0x61, 0x73, 0x68, 0x12, 0x26, 0x0a, 0x0e, 0x70,
-	0x72, 0x65, 0x76, 0x54, 0x78, 0x4f, 0x75, 0x74, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x0d, 0x52, 0x0e, 0x70, 0x72, 0x65, 0x76, 0x54, 0x78, 0x4f, 0x75, 0x74, 0x49, 0x6e,
-	0x64, 0x65, 0x78, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79,
-	0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65,
-	0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x04,
-	0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x22,
-	0x3c, 0x0a, 0x08, 0x54, 0x78, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x61,
-	0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x61, 0x6d, 0x6f,
-	0x75, 0x6e, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0x6e, 0x0a,
-	0x0b, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07,
-	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76,
-	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x20, 0x0a, 0x06, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73,
-	0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x54, 0x78, 0x49, 0x6e, 0x70, 0x75, 0x74,
-	0x52, 0x06, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x12, 0x23, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x70,
-	0x75, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x54, 0x78, 0x4f, 0x75,
-	0x74, 0x70, 0x75, 0x74, 0x52, 0x07, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x32, 0x64, 0x0a,
-	0x04, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x21, 0x0a, 0x09, 0x48, 0x61, 0x6e, 0x64, 0x73, 0x68, 0x61,
-	0x6b, 0x65, 0x12, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x1a, 0x09, 0x2e,
-	0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x39, 0x0a, 0x11, 0x48, 0x61, 0x6e, 0x64,
-	0x6c, 0x65, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x0c, 0x2e,
-	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x1a, 0x16, 0x2e, 0x67, 0x6f,
-	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d,
-	0x70, 0x74, 0x79, 0x42, 0x2e, 0x5a, 0x2c, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
-	0x6d, 0x2f, 0x6f, 0x6c, 0x65, 0x67, 0x6c, 0x65, 0x67, 0x75, 0x6e, 0x2f, 0x62, 0x6c, 0x6f, 0x63,
-	0x6b, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x2d, 0x62, 0x74, 0x63, 0x2f, 0x67, 0x65, 0x6e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0xd0, 0x01, 0x0a, 0x08, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x18, 0x0a, 0x07,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1e,
+	0x0a, 0x0a, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x41, 0x64, 0x64, 0x72, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x6c, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x41, 0x64, 0x64, 0x72, 0x12, 0x1a,
+	0x0a, 0x08, 0x70, 0x65, 0x65, 0x72, 0x4c, 0x69, 0x73, 0x74, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x08, 0x70, 0x65, 0x65, 0x72, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x65,
+	0x73, 0x74, 0x48, 0x61, 0x73, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x62, 0x65,
+	0x73, 0x74, 0x48, 0x61, 0x73, 0x68, 0x12, 0x20, 0x0a, 0x0b, 0x67, 0x65, 0x6e, 0x65, 0x73, 0x69,
+	0x73, 0x48, 0x61, 0x73, 0x68, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x67, 0x65, 0x6e,
+	0x65, 0x73, 0x69, 0x73, 0x48, 0x61, 0x73, 0x68, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x69,
+	0x6e, 0x49, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x68, 0x61, 0x69, 0x6e,
+	0x49, 0x64, 0x22, 0x65, 0x0a, 0x11, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x61, 0x6e, 0x67, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x66, 0x72, 0x6f, 0x6d, 0x48,
+	0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x66, 0x72, 0x6f,
+	0x6d, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x72, 0x6f, 0x6d, 0x48,
+	0x61, 0x73, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x66, 0x72, 0x6f, 0x6d, 0x48,
+	0x61, 0x73, 0x68, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x36, 0x0a, 0x0c, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x12, 0x26, 0x0a, 0x07, 0x68, 0x65, 0x61,
+	0x64, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x73, 0x22, 0x28, 0x0a, 0x06, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x1e, 0x0a, 0x06, 0x62,
+	0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x06, 0x2e, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x52, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x22, 0x9b, 0x01, 0x0a, 0x05,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x24, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61,
+	0x64, 0x65, 0x72, 0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x70,
+	0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09,
+	0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67,
+	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69,
+	0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x30, 0x0a, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73,
+	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0c, 0x2e,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0c, 0x74, 0x72, 0x61,
+	0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x87, 0x02, 0x0a, 0x0b, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x70,
+	0x72, 0x65, 0x76, 0x48, 0x61, 0x73, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x70,
+	0x72, 0x65, 0x76, 0x48, 0x61, 0x73, 0x68, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x6f, 0x6f, 0x74, 0x48,
+	0x61, 0x73, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x72, 0x6f, 0x6f, 0x74, 0x48,
+	0x61, 0x73, 0x68, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
+	0x70, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x69, 0x74, 0x73, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x62, 0x69, 0x74, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x73,
+	0x65, 0x65, 0x64, 0x52, 0x65, 0x76, 0x65, 0x61, 0x6c, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x0a, 0x73, 0x65, 0x65, 0x64, 0x52, 0x65, 0x76, 0x65, 0x61, 0x6c, 0x12, 0x26, 0x0a, 0x0e, 0x73,
+	0x65, 0x65, 0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x0e, 0x73, 0x65, 0x65, 0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x6d,
+	0x65, 0x6e, 0x74, 0x22, 0xcc, 0x02, 0x0a, 0x07, 0x54, 0x78, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12,
+	0x1e, 0x0a, 0x0a, 0x70, 0x72, 0x65, 0x76, 0x54, 0x78, 0x48, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x0a, 0x70, 0x72, 0x65, 0x76, 0x54, 0x78, 0x48, 0x61, 0x73, 0x68, 0x12,
+	0x26, 0x0a, 0x0e, 0x70, 0x72, 0x65, 0x76, 0x54, 0x78, 0x4f, 0x75, 0x74, 0x49, 0x6e, 0x64, 0x65,
+	0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0e, 0x70, 0x72, 0x65, 0x76, 0x54, 0x78, 0x4f,
+	0x75, 0x74, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x75, 0x62, 0x6c, 0x69,
+	0x63, 0x4b, 0x65, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x70, 0x75, 0x62, 0x6c,
+	0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75,
+	0x72, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x73, 0x69, 0x67, 0x48, 0x61, 0x73, 0x68, 0x54, 0x79,
+	0x70, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x73, 0x69, 0x67, 0x48, 0x61, 0x73,
+	0x68, 0x54, 0x79, 0x70, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x75, 0x6e, 0x6c, 0x6f, 0x63, 0x6b, 0x53,
+	0x63, 0x72, 0x69, 0x70, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0c, 0x75, 0x6e, 0x6c,
+	0x6f, 0x63, 0x6b, 0x53, 0x63, 0x72, 0x69, 0x70, 0x74, 0x12, 0x2b, 0x0a, 0x08, 0x6d, 0x75, 0x6c,
+	0x74, 0x69, 0x73, 0x69, 0x67, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x4d, 0x75,
+	0x6c, 0x74, 0x69, 0x73, 0x69, 0x67, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x08, 0x6d, 0x75,
+	0x6c, 0x74, 0x69, 0x73, 0x69, 0x67, 0x12, 0x2e, 0x0a, 0x12, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x73,
+	0x69, 0x67, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x18, 0x08, 0x20, 0x03,
+	0x28, 0x0c, 0x52, 0x12, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x73, 0x69, 0x67, 0x53, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e,
+	0x63, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e,
+	0x63, 0x65, 0x22, 0x4e, 0x0a, 0x0e, 0x4d, 0x75, 0x6c, 0x74, 0x69, 0x73, 0x69, 0x67, 0x50, 0x6f,
+	0x6c, 0x69, 0x63, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f,
+	0x6c, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x73,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x0a, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65,
+	0x79, 0x73, 0x22, 0x91, 0x01, 0x0a, 0x08, 0x54, 0x78, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12,
+	0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73,
+	0x73, 0x12, 0x1f, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x0b, 0x2e, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79,
+	0x70, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x63, 0x72, 0x69, 0x70, 0x74,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x63, 0x72, 0x69,
+	0x70, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0xed, 0x02, 0x0a, 0x0b, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x12, 0x20, 0x0a, 0x06, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x08, 0x2e, 0x54, 0x78, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x06, 0x69, 0x6e, 0x70, 0x75,
+	0x74, 0x73, 0x12, 0x23, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x18, 0x03, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x54, 0x78, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x52, 0x07,
+	0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x12, 0x26, 0x0a, 0x0e, 0x63, 0x6f, 0x69, 0x6e, 0x62,
+	0x61, 0x73, 0x65, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0e, 0x63, 0x6f, 0x69, 0x6e, 0x62, 0x61, 0x73, 0x65, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12,
+	0x31, 0x0a, 0x0a, 0x67, 0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x47, 0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63, 0x65,
+	0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x67, 0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e,
+	0x63, 0x65, 0x12, 0x47, 0x0a, 0x14, 0x67, 0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63, 0x65,
+	0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x13, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x53, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x14, 0x67, 0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63,
+	0x65, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x12, 0x3d, 0x0a, 0x10, 0x73,
+	0x6c, 0x61, 0x73, 0x68, 0x69, 0x6e, 0x67, 0x45, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x53, 0x6c, 0x61, 0x73, 0x68, 0x69, 0x6e, 0x67,
+	0x45, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x52, 0x10, 0x73, 0x6c, 0x61, 0x73, 0x68, 0x69,
+	0x6e, 0x67, 0x45, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x6f,
+	0x63, 0x6b, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x6c, 0x6f,
+	0x63, 0x6b, 0x54, 0x69, 0x6d, 0x65, 0x22, 0xc0, 0x01, 0x0a, 0x10, 0x53, 0x6c, 0x61, 0x73, 0x68,
+	0x69, 0x6e, 0x67, 0x45, 0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x70,
+	0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09,
+	0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x26, 0x0a, 0x07, 0x68, 0x65, 0x61,
+	0x64, 0x65, 0x72, 0x31, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x31, 0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x31, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x31, 0x12, 0x26, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x32, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x52, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x32, 0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x69, 0x67,
+	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x32, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x73,
+	0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x32, 0x22, 0xa9, 0x01, 0x0a, 0x10, 0x47, 0x6f,
+	0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2a,
+	0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x16, 0x2e, 0x47,
+	0x6f, 0x76, 0x65, 0x72, 0x6e, 0x61, 0x6e, 0x63, 0x65, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e,
+	0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x75,
+	0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x70,
+	0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75,
+	0x65, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75,
+	0x65, 0x6e, 0x63, 0x65, 0x22, 0x2f, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x11, 0x0a, 0x0d,
+	0x41, 0x44, 0x44, 0x5f, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x41, 0x54, 0x4f, 0x52, 0x10, 0x00, 0x12,
+	0x14, 0x0a, 0x10, 0x52, 0x45, 0x4d, 0x4f, 0x56, 0x45, 0x5f, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x41,
+	0x54, 0x4f, 0x52, 0x10, 0x01, 0x22, 0xbc, 0x01, 0x0a, 0x04, 0x56, 0x6f, 0x74, 0x65, 0x12, 0x1e,
+	0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0a, 0x2e, 0x56,
+	0x6f, 0x74, 0x65, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x16,
+	0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06,
+	0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x48,
+	0x61, 0x73, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
+	0x48, 0x61, 0x73, 0x68, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65,
+	0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b,
+	0x65, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x22, 0x22, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x50, 0x52, 0x45, 0x56,
+	0x4f, 0x54, 0x45, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09, 0x50, 0x52, 0x45, 0x43, 0x4f, 0x4d, 0x4d,
+	0x49, 0x54, 0x10, 0x01, 0x22, 0x50, 0x0a, 0x12, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f,
+	0x72, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x75,
+	0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x70,
+	0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67,
+	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x2a, 0x4c, 0x0a, 0x0a, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74,
+	0x54, 0x79, 0x70, 0x65, 0x12, 0x0c, 0x0a, 0x08, 0x54, 0x52, 0x41, 0x4e, 0x53, 0x46, 0x45, 0x52,
+	0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x53, 0x54, 0x41, 0x4b, 0x45, 0x10, 0x01, 0x12, 0x0d, 0x0a,
+	0x09, 0x55, 0x4e, 0x42, 0x4f, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x02, 0x12, 0x0c, 0x0a, 0x08,
+	0x4d, 0x55, 0x4c, 0x54, 0x49, 0x53, 0x49, 0x47, 0x10, 0x03, 0x12, 0x08, 0x0a, 0x04, 0x44, 0x41,
+	0x54, 0x41, 0x10, 0x04, 0x32, 0xc0, 0x02, 0x0a, 0x04, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x21, 0x0a,
+	0x09, 0x48, 0x61, 0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x12, 0x09, 0x2e, 0x4e, 0x6f, 0x64,
+	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x1a, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f,
+	0x12, 0x1e, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x09, 0x2e, 0x4e, 0x6f, 0x64,
+	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x1a, 0x09, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f,
+	0x12, 0x39, 0x0a, 0x11, 0x48, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x0c, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x2d, 0x0a, 0x0b, 0x48,
+	0x61, 0x6e, 0x64, 0x6c, 0x65, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x06, 0x2e, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x34, 0x0a, 0x0f, 0x47, 0x65,
+	0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x12, 0x12, 0x2e,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x0d, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73,
+	0x12, 0x28, 0x0a, 0x09, 0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x12, 0x2e,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x07, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x2b, 0x0a, 0x0a, 0x48, 0x61,
+	0x6e, 0x64, 0x6c, 0x65, 0x56, 0x6f, 0x74, 0x65, 0x12, 0x05, 0x2e, 0x56, 0x6f, 0x74, 0x65, 0x1a,
+	0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x2e, 0x5a, 0x2c, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6f, 0x6c, 0x65, 0x67, 0x6c, 0x65, 0x67, 0x75, 0x6e, 0x2f,
+	0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x2d, 0x62, 0x74, 0x63, 0x2f, 0x67,
+	0x65, 0x6e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -517,30 +1480,63 @@ func file_blockchain_proto_rawDescGZIP() []byte {
 	return file_blockchain_proto_rawDescData
 }
 
-var file_blockchain_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_blockchain_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_blockchain_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_blockchain_proto_goTypes = []any{
-	(*NodeInfo)(nil),      // 0: NodeInfo
-	(*Block)(nil),         // 1: Block
-	(*BlockHeader)(nil),   // 2: BlockHeader
-	(*TxInput)(nil),       // 3: TxInput
-	(*TxOutput)(nil),      // 4: TxOutput
-	(*Transaction)(nil),   // 5: Transaction
-	(*emptypb.Empty)(nil), // 6: google.protobuf.Empty
+	(OutputType)(0),            // 0: OutputType
+	(GovernanceAction_Type)(0), // 1: GovernanceAction.Type
+	(Vote_Type)(0),             // 2: Vote.Type
+	(*NodeInfo)(nil),           // 3: NodeInfo
+	(*BlockRangeRequest)(nil),  // 4: BlockRangeRequest
+	(*BlockHeaders)(nil),       // 5: BlockHeaders
+	(*Blocks)(nil),             // 6: Blocks
+	(*Block)(nil),              // 7: Block
+	(*BlockHeader)(nil),        // 8: BlockHeader
+	(*TxInput)(nil),            // 9: TxInput
+	(*MultisigPolicy)(nil),     // 10: MultisigPolicy
+	(*TxOutput)(nil),           // 11: TxOutput
+	(*Transaction)(nil),        // 12: Transaction
+	(*SlashingEvidence)(nil),   // 13: SlashingEvidence
+	(*GovernanceAction)(nil),   // 14: GovernanceAction
+	(*Vote)(nil),               // 15: Vote
+	(*ValidatorSignature)(nil), // 16: ValidatorSignature
+	(*emptypb.Empty)(nil),      // 17: google.protobuf.Empty
 }
 var file_blockchain_proto_depIdxs = []int32{
-	2, // 0: Block.header:type_name -> BlockHeader
-	5, // 1: Block.transactions:type_name -> Transaction
-	3, // 2: Transaction.inputs:type_name -> TxInput
-	4, // 3: Transaction.outputs:type_name -> TxOutput
-	0, // 4: Node.Handshake:input_type -> NodeInfo
-	5, // 5: Node.HandleTransaction:input_type -> Transaction
-	0, // 6: Node.Handshake:output_type -> NodeInfo
-	6, // 7: Node.HandleTransaction:output_type -> google.protobuf.Empty
-	6, // [6:8] is the sub-list for method output_type
-	4, // [4:6] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	8,  // 0: BlockHeaders.headers:type_name -> BlockHeader
+	7,  // 1: Blocks.blocks:type_name -> Block
+	8,  // 2: Block.header:type_name -> BlockHeader
+	12, // 3: Block.transactions:type_name -> Transaction
+	10, // 4: TxInput.multisig:type_name -> MultisigPolicy
+	0,  // 5: TxOutput.type:type_name -> OutputType
+	9,  // 6: Transaction.inputs:type_name -> TxInput
+	11, // 7: Transaction.outputs:type_name -> TxOutput
+	14, // 8: Transaction.governance:type_name -> GovernanceAction
+	16, // 9: Transaction.governanceSignatures:type_name -> ValidatorSignature
+	13, // 10: Transaction.slashingEvidence:type_name -> SlashingEvidence
+	8,  // 11: SlashingEvidence.header1:type_name -> BlockHeader
+	8,  // 12: SlashingEvidence.header2:type_name -> BlockHeader
+	1,  // 13: GovernanceAction.type:type_name -> GovernanceAction.Type
+	2,  // 14: Vote.type:type_name -> Vote.Type
+	3,  // 15: Node.Handshake:input_type -> NodeInfo
+	3,  // 16: Node.Status:input_type -> NodeInfo
+	12, // 17: Node.HandleTransaction:input_type -> Transaction
+	7,  // 18: Node.HandleBlock:input_type -> Block
+	4,  // 19: Node.GetBlockHeaders:input_type -> BlockRangeRequest
+	4,  // 20: Node.GetBlocks:input_type -> BlockRangeRequest
+	15, // 21: Node.HandleVote:input_type -> Vote
+	3,  // 22: Node.Handshake:output_type -> NodeInfo
+	3,  // 23: Node.Status:output_type -> NodeInfo
+	17, // 24: Node.HandleTransaction:output_type -> google.protobuf.Empty
+	17, // 25: Node.HandleBlock:output_type -> google.protobuf.Empty
+	5,  // 26: Node.GetBlockHeaders:output_type -> BlockHeaders
+	6,  // 27: Node.GetBlocks:output_type -> Blocks
+	17, // 28: Node.HandleVote:output_type -> google.protobuf.Empty
+	22, // [22:29] is the sub-list for method output_type
+	15, // [15:22] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
 }
 
 func init() { file_blockchain_proto_init() }
@@ -562,7 +1558,7 @@ func file_blockchain_proto_init() {
 			}
 		}
 		file_blockchain_proto_msgTypes[1].Exporter = func(v any, i int) any {
-			switch v := v.(*Block); i {
+			switch v := v.(*BlockRangeRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -574,7 +1570,7 @@ func file_blockchain_proto_init() {
 			}
 		}
 		file_blockchain_proto_msgTypes[2].Exporter = func(v any, i int) any {
-			switch v := v.(*BlockHeader); i {
+			switch v := v.(*BlockHeaders); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -586,7 +1582,7 @@ func file_blockchain_proto_init() {
 			}
 		}
 		file_blockchain_proto_msgTypes[3].Exporter = func(v any, i int) any {
-			switch v := v.(*TxInput); i {
+			switch v := v.(*Blocks); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -598,7 +1594,7 @@ func file_blockchain_proto_init() {
 			}
 		}
 		file_blockchain_proto_msgTypes[4].Exporter = func(v any, i int) any {
-			switch v := v.(*TxOutput); i {
+			switch v := v.(*Block); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -610,6 +1606,54 @@ func file_blockchain_proto_init() {
 			}
 		}
 		file_blockchain_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*BlockHeader); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_blockchain_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*TxInput); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_blockchain_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*MultisigPolicy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_blockchain_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*TxOutput); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_blockchain_proto_msgTypes[9].Exporter = func(v any, i int) any {
 			switch v := v.(*Transaction); i {
 			case 0:
 				return &v.state
@@ -621,19 +1665,68 @@ func file_blockchain_proto_init() {
 				return nil
 			}
 		}
+		file_blockchain_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*SlashingEvidence); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_blockchain_proto_msgTypes[11].Exporter = func(v any, i int) any {
+			switch v := v.(*GovernanceAction); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_blockchain_proto_msgTypes[12].Exporter = func(v any, i int) any {
+			switch v := v.(*Vote); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_blockchain_proto_msgTypes[13].Exporter = func(v any, i int) any {
+			switch v := v.(*ValidatorSignature); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_blockchain_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   6,
+			NumEnums:      3,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_blockchain_proto_goTypes,
 		DependencyIndexes: file_blockchain_proto_depIdxs,
+		EnumInfos:         file_blockchain_proto_enumTypes,
 		MessageInfos:      file_blockchain_proto_msgTypes,
 	}.Build()
 	File_blockchain_proto = out.File
@@ -21,7 +21,12 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	Node_Handshake_FullMethodName         = "/Node/Handshake"
+	Node_Status_FullMethodName            = "/Node/Status"
 	Node_HandleTransaction_FullMethodName = "/Node/HandleTransaction"
+	Node_HandleBlock_FullMethodName       = "/Node/HandleBlock"
+	Node_GetBlockHeaders_FullMethodName   = "/Node/GetBlockHeaders"
+	Node_GetBlocks_FullMethodName         = "/Node/GetBlocks"
+	Node_HandleVote_FullMethodName        = "/Node/HandleVote"
 )
 
 // NodeClient is the client API for Node service.
@@ -29,7 +34,14 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type NodeClient interface {
 	Handshake(ctx context.Context, in *NodeInfo, opts ...grpc.CallOption) (*NodeInfo, error)
+	// Status exchanges up-to-date node information with an already connected peer.
+	Status(ctx context.Context, in *NodeInfo, opts ...grpc.CallOption) (*NodeInfo, error)
 	HandleTransaction(ctx context.Context, in *Transaction, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	HandleBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetBlockHeaders(ctx context.Context, in *BlockRangeRequest, opts ...grpc.CallOption) (*BlockHeaders, error)
+	GetBlocks(ctx context.Context, in *BlockRangeRequest, opts ...grpc.CallOption) (*Blocks, error)
+	// HandleVote is called when a peer relays a finality vote of a validator.
+	HandleVote(ctx context.Context, in *Vote, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type nodeClient struct {
@@ -50,6 +62,16 @@ func (c *nodeClient) Handshake(ctx context.Context, in *NodeInfo, opts ...grpc.C
 	return out, nil
 }
 
+func (c *nodeClient) Status(ctx context.Context, in *NodeInfo, opts ...grpc.CallOption) (*NodeInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NodeInfo)
+	err := c.cc.Invoke(ctx, Node_Status_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *nodeClient) HandleTransaction(ctx context.Context, in *Transaction, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(emptypb.Empty)
@@ -60,12 +82,59 @@ func (c *nodeClient) HandleTransaction(ctx context.Context, in *Transaction, opt
 	return out, nil
 }
 
+func (c *nodeClient) HandleBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Node_HandleBlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) GetBlockHeaders(ctx context.Context, in *BlockRangeRequest, opts ...grpc.CallOption) (*BlockHeaders, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BlockHeaders)
+	err := c.cc.Invoke(ctx, Node_GetBlockHeaders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) GetBlocks(ctx context.Context, in *BlockRangeRequest, opts ...grpc.CallOption) (*Blocks, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blocks)
+	err := c.cc.Invoke(ctx, Node_GetBlocks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) HandleVote(ctx context.Context, in *Vote, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Node_HandleVote_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NodeServer is the server API for Node service.
 // All implementations must embed UnimplementedNodeServer
 // for forward compatibility.
 type NodeServer interface {
 	Handshake(context.Context, *NodeInfo) (*NodeInfo, error)
+	// Status exchanges up-to-date node information with an already connected peer.
+	Status(context.Context, *NodeInfo) (*NodeInfo, error)
 	HandleTransaction(context.Context, *Transaction) (*emptypb.Empty, error)
+	HandleBlock(context.Context, *Block) (*emptypb.Empty, error)
+	GetBlockHeaders(context.Context, *BlockRangeRequest) (*BlockHeaders, error)
+	GetBlocks(context.Context, *BlockRangeRequest) (*Blocks, error)
+	// HandleVote is called when a peer relays a finality vote of a validator.
+	HandleVote(context.Context, *Vote) (*emptypb.Empty, error)
 	mustEmbedUnimplementedNodeServer()
 }
 
@@ -79,9 +148,24 @@ type UnimplementedNodeServer struct{}
 func (UnimplementedNodeServer) Handshake(context.Context, *NodeInfo) (*NodeInfo, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Handshake not implemented")
 }
+func (UnimplementedNodeServer) Status(context.Context, *NodeInfo) (*NodeInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
 func (UnimplementedNodeServer) HandleTransaction(context.Context, *Transaction) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method HandleTransaction not implemented")
 }
+func (UnimplementedNodeServer) HandleBlock(context.Context, *Block) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleBlock not implemented")
+}
+func (UnimplementedNodeServer) GetBlockHeaders(context.Context, *BlockRangeRequest) (*BlockHeaders, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockHeaders not implemented")
+}
+func (UnimplementedNodeServer) GetBlocks(context.Context, *BlockRangeRequest) (*Blocks, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlocks not implemented")
+}
+func (UnimplementedNodeServer) HandleVote(context.Context, *Vote) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleVote not implemented")
+}
 func (UnimplementedNodeServer) mustEmbedUnimplementedNodeServer() {}
 func (UnimplementedNodeServer) testEmbeddedByValue()              {}
 
@@ -121,6 +205,24 @@ func _Node_Handshake_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Node_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_Status_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).Status(ctx, req.(*NodeInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Node_HandleTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Transaction)
 	if err := dec(in); err != nil {
@@ -139,6 +241,78 @@ func _Node_HandleTransaction_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Node_HandleBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Block)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).HandleBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_HandleBlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).HandleBlock(ctx, req.(*Block))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_GetBlockHeaders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).GetBlockHeaders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_GetBlockHeaders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).GetBlockHeaders(ctx, req.(*BlockRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_GetBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).GetBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_GetBlocks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).GetBlocks(ctx, req.(*BlockRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_HandleVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Vote)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).HandleVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_HandleVote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).HandleVote(ctx, req.(*Vote))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Node_ServiceDesc is the grpc.ServiceDesc for Node service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -150,10 +324,30 @@ var Node_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Handshake",
 			Handler:    _Node_Handshake_Handler,
 		},
+		{
+			MethodName: "Status",
+			Handler:    _Node_Status_Handler,
+		},
 		{
 			MethodName: "HandleTransaction",
 			Handler:    _Node_HandleTransaction_Handler,
 		},
+		{
+			MethodName: "HandleBlock",
+			Handler:    _Node_HandleBlock_Handler,
+		},
+		{
+			MethodName: "GetBlockHeaders",
+			Handler:    _Node_GetBlockHeaders_Handler,
+		},
+		{
+			MethodName: "GetBlocks",
+			Handler:    _Node_GetBlocks_Handler,
+		},
+		{
+			MethodName: "HandleVote",
+			Handler:    _Node_HandleVote_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "blockchain.proto",
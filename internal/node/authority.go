@@ -0,0 +1,244 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+)
+
+// ValidatorSet is the set of block producers of a proof-of-authority chain.
+// The validators produce blocks in turns, in the order of the set.
+type ValidatorSet struct {
+	// Validators are the public keys of the validators
+	Validators [][]byte
+	// Sequence is the number of governance actions applied to the set
+	Sequence uint64
+}
+
+// newValidatorSet creates the initial validator set from the chain parameters.
+// The parameters are expected to be valid.
+func newValidatorSet(params *ChainParams) *ValidatorSet {
+	vs := &ValidatorSet{
+		Validators: make([][]byte, len(params.Validators)),
+	}
+
+	for idx, validator := range params.Validators {
+		vs.Validators[idx], _ = hex.DecodeString(validator)
+	}
+
+	return vs
+}
+
+func (vs *ValidatorSet) clone() *ValidatorSet {
+	return &ValidatorSet{
+		Validators: slices.Clone(vs.Validators),
+		Sequence:   vs.Sequence,
+	}
+}
+
+func (vs *ValidatorSet) indexOf(pubKey []byte) int {
+	return slices.IndexFunc(vs.Validators, func(validator []byte) bool {
+		return bytes.Equal(validator, pubKey)
+	})
+}
+
+// producer returns the public key of the validator allowed to produce the block at the given height with the given rank.
+// The scheduled validator has rank 0, the following validators of the set take over its turn with the next ranks.
+// Nil is returned for an empty set, which lets any node produce blocks.
+func (vs *ValidatorSet) producer(height, rank int) []byte {
+	if len(vs.Validators) == 0 {
+		return nil
+	}
+
+	return vs.Validators[(height%len(vs.Validators)+rank%len(vs.Validators))%len(vs.Validators)]
+}
+
+// apply checks the governance transaction against the set and applies its action.
+// The action must be signed by more than half of the validators for the network with the given chain ID.
+func (vs *ValidatorSet) apply(tx *genproto.Transaction, chainID string) error {
+	action := tx.Governance
+	hash := types.HashTransactionString(tx)
+
+	if len(vs.Validators) == 0 {
+		return fmt.Errorf("governance transaction %s is not allowed without a validator set", hash)
+	}
+
+	if len(tx.Inputs) > 0 || len(tx.Outputs) > 0 || tx.CoinbaseHeight != 0 {
+		return fmt.Errorf("governance transaction %s transfers coins", hash)
+	}
+
+	if action.Sequence != vs.Sequence {
+		return fmt.Errorf("governance transaction %s has sequence %d, expected %d", hash, action.Sequence, vs.Sequence)
+	}
+
+	if len(action.PublicKey) != cryptography.PubKeyLen {
+		return fmt.Errorf("governance transaction %s has an invalid validator public key", hash)
+	}
+
+	actionHash := types.HashGovernanceAction(action, chainID)
+	signers := make(map[int]struct{})
+
+	for _, sig := range tx.GovernanceSignatures {
+		idx := vs.indexOf(sig.PublicKey)
+		if idx < 0 || len(sig.Signature) != cryptography.SigLen {
+			return fmt.Errorf("governance transaction %s has a signature of a non-validator", hash)
+		}
+
+		pubKey := cryptography.NewPublicKeyFromBytes(sig.PublicKey)
+		if !cryptography.NewSignatureFromBytes(sig.Signature).Verify(pubKey, actionHash) {
+			return fmt.Errorf("governance transaction %s has an invalid signature", hash)
+		}
+
+		signers[idx] = struct{}{}
+	}
+
+	if len(signers)*2 <= len(vs.Validators) {
+		return fmt.Errorf("governance transaction %s is signed by %d of %d validators", hash, len(signers), len(vs.Validators))
+	}
+
+	idx := vs.indexOf(action.PublicKey)
+
+	switch action.Type {
+	case genproto.GovernanceAction_ADD_VALIDATOR:
+		if idx >= 0 {
+			return fmt.Errorf("governance transaction %s adds an existing validator", hash)
+		}
+		vs.Validators = append(vs.Validators, action.PublicKey)
+	case genproto.GovernanceAction_REMOVE_VALIDATOR:
+		if idx < 0 {
+			return fmt.Errorf("governance transaction %s removes an unknown validator", hash)
+		}
+		if len(vs.Validators) == 1 {
+			return fmt.Errorf("governance transaction %s removes the last validator", hash)
+		}
+		vs.Validators = slices.Delete(slices.Clone(vs.Validators), idx, idx+1)
+	default:
+		return fmt.Errorf("governance transaction %s has an unknown action %d", hash, action.Type)
+	}
+
+	vs.Sequence++
+	return nil
+}
+
+// Validators returns the public keys of the current validators in the order they produce blocks.
+// The list is empty if any node can produce blocks.
+func (c *Chain) Validators() [][]byte {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return slices.Clone(c.validators.Validators)
+}
+
+// NextProducer returns the public key of the validator allowed to produce a block following the chain tip at the given time.
+// Nil is returned if any node can produce the block.
+func (c *Chain) NextProducer(now time.Time) []byte {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	tip := c.tipNode()
+	return c.validators.producer(tip.height+1, c.producerRank(blockTimestamp(tip, now), tip))
+}
+
+// IsNextProducer checks whether the owner of the public key is allowed to produce a block following the chain tip
+// at the given time. In the authority mode it has to be the validator whose turn it is, in the proof-of-stake mode
+// the proposer drawn for the turn.
+func (c *Chain) IsNextProducer(pubKey []byte, now time.Time) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	tip := c.tipNode()
+	return c.isProducer(pubKey, blockTimestamp(tip, now), tip)
+}
+
+// checkProducer checks that the block on top of the parent block is signed by the validator whose turn it is
+// or by the drawn proposer. The validator and stake sets of a side branch are only known once the branch is
+// connected, so a block extending a side branch is only checked to be signed by one of the validators or stakers
+// at the tip; the turn is checked when the block is connected.
+//
+// The timestamp of the block decides the turn, so a producer taking over the turn of another one can't date
+// its block ahead of the local clock by more than maxFallbackTimeOffset.
+func (c *Chain) checkProducer(block *genproto.Block, parent *blockNode, now time.Time) error {
+	if !c.hasProducers() {
+		return nil
+	}
+
+	if parent != c.tipNode() {
+		if !c.isKnownProducer(block.PublicKey) {
+			return fmt.Errorf("block with hash %s is not signed by a validator or a staker", types.HashBlockString(block))
+		}
+	} else if !c.isProducer(block.PublicKey, block.Header.Timestamp, parent) {
+		return fmt.Errorf("block with hash %s is not signed by the scheduled producer", types.HashBlockString(block))
+	}
+
+	if c.producerRank(block.Header.Timestamp, parent) == 0 {
+		return nil
+	}
+
+	if maxTime := now.Add(maxFallbackTimeOffset).Unix(); block.Header.Timestamp > maxTime {
+		return fmt.Errorf("fallback block with hash %s: %w: timestamp %d, latest allowed %d",
+			types.HashBlockString(block), ErrBlockTooNew, block.Header.Timestamp, maxTime)
+	}
+
+	return nil
+}
+
+// producerRank returns the rank of the producer allowed to produce a block with the given timestamp on top of the parent block.
+// The scheduled producer has rank 0. Every ProducerTimeout seconds without a block the turn passes to the producer
+// of the next rank, so an offline producer doesn't halt the chain.
+func (c *Chain) producerRank(timestamp int64, parent *blockNode) int {
+	return int(max(0, timestamp-parent.header.Timestamp) / c.params.ProducerTimeout)
+}
+
+// hasProducers checks whether producing blocks is restricted to the validators or the stakers.
+// Otherwise any node can produce a block at any time.
+func (c *Chain) hasProducers() bool {
+	switch c.params.Consensus {
+	case ConsensusAuthority:
+		return len(c.validators.Validators) > 0
+	case ConsensusProofOfStake:
+		stakers, _ := c.stakes.eligible()
+		return len(stakers) > 0
+	default:
+		return false
+	}
+}
+
+// isKnownProducer checks whether the owner of the public key is one of the validators or the stakers at the chain tip.
+func (c *Chain) isKnownProducer(pubKey []byte) bool {
+	switch c.params.Consensus {
+	case ConsensusAuthority:
+		return c.validators.indexOf(pubKey) >= 0
+	case ConsensusProofOfStake:
+		if len(pubKey) != cryptography.PubKeyLen {
+			return false
+		}
+		stakers, _ := c.stakes.eligible()
+		_, ok := slices.BinarySearch(stakers, cryptography.NewPublicKeyFromBytes(pubKey).Address().String())
+		return ok
+	default:
+		return true
+	}
+}
+
+func (c *Chain) isProducer(pubKey []byte, timestamp int64, parent *blockNode) bool {
+	switch c.params.Consensus {
+	case ConsensusAuthority:
+		producer := c.validators.producer(parent.height+1, c.producerRank(timestamp, parent))
+		return producer == nil || bytes.Equal(pubKey, producer)
+	case ConsensusProofOfStake:
+		proposer := c.stakes.proposer(parent.seed, parent.height+1, c.producerRank(timestamp, parent))
+		if proposer == nil {
+			return true
+		}
+		return len(pubKey) == cryptography.PubKeyLen &&
+			bytes.Equal(cryptography.NewPublicKeyFromBytes(pubKey).Address().Bytes(), proposer)
+	default:
+		return true
+	}
+}
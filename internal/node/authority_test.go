@@ -0,0 +1,231 @@
+package node
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestValidators(count int) []cryptography.PrivateKey {
+	validators := make([]cryptography.PrivateKey, count)
+	for idx := range validators {
+		validators[idx] = cryptography.NewPrivateKey()
+	}
+
+	return validators
+}
+
+func newAuthorityChainParams(validators []cryptography.PrivateKey) *ChainParams {
+	params := DefaultChainParams()
+	for _, validator := range validators {
+		params.Validators = append(params.Validators, validator.Public().String())
+	}
+
+	return params
+}
+
+func newGovernanceTx(actionType genproto.GovernanceAction_Type, pubKey []byte, sequence uint64, signers ...cryptography.PrivateKey) *genproto.Transaction {
+	tx := types.NewGovernanceTransaction(&genproto.GovernanceAction{
+		Type:      actionType,
+		PublicKey: pubKey,
+		Sequence:  sequence,
+	})
+
+	for _, signer := range signers {
+		types.SignGovernanceTransaction(signer, tx, defaultChainID)
+	}
+
+	return tx
+}
+
+func TestValidatorSchedule(t *testing.T) {
+	var (
+		validators = newTestValidators(3)
+		chain      = newTestChain(t, newAuthorityChainParams(validators))
+	)
+
+	for height := 1; height <= 6; height++ {
+		// The block follows the previous one within the producer timeout
+		now := time.Unix(tipTimestamp(t, chain)+1, 0)
+
+		scheduled := validators[height%len(validators)]
+		require.Equal(t, scheduled.Public().Bytes(), chain.NextProducer(now))
+
+		// Other validators are not allowed to produce the block
+		other := validators[(height+1)%len(validators)]
+		block := chain.NewBlockTemplateAt(nil, other.Public().Address().Bytes(), now)
+		types.SignBlock(other, block)
+		require.NotNil(t, chain.AddBlock(block))
+
+		block = chain.NewBlockTemplateAt(nil, scheduled.Public().Address().Bytes(), now)
+		types.SignBlock(scheduled, block)
+		require.Nil(t, chain.AddBlock(block))
+	}
+
+	// Without validators any node can produce blocks
+	require.Nil(t, newMemoryChain(t).NextProducer(time.Now()))
+}
+
+func TestProducerFallback(t *testing.T) {
+	var (
+		validators = newTestValidators(3)
+		chain      = newTestChain(t, newAuthorityChainParams(validators))
+		timeout    = chain.Params().ProducerTimeout
+		timestamp  = tipTimestamp(t, chain)
+	)
+
+	// The turn passes to the next validator every producer timeout
+	for rank := 0; rank < 4; rank++ {
+		now := time.Unix(timestamp+int64(rank)*timeout, 0)
+		require.Equal(t, validators[(1+rank)%len(validators)].Public().Bytes(), chain.NextProducer(now))
+	}
+
+	// The scheduled validator is offline, its turn is over
+	now := time.Unix(timestamp+timeout, 0)
+	block := chain.NewBlockTemplateAt(nil, validators[1].Public().Address().Bytes(), now)
+	types.SignBlock(validators[1], block)
+	require.NotNil(t, chain.AddBlock(block))
+
+	block = newTestBlockAt(t, chain, validators, now)
+	require.Equal(t, validators[2].Public().Bytes(), block.PublicKey)
+	require.Nil(t, chain.AddBlock(block))
+
+	// The schedule continues from the height of the fallback block
+	require.Equal(t, validators[2].Public().Bytes(), chain.NextProducer(now.Add(time.Second)))
+
+	// A fallback validator can't take over the turn by dating its block ahead
+	block = newTestBlockAt(t, chain, validators, time.Now().Add(time.Minute))
+	require.ErrorIs(t, chain.AddBlock(block), ErrBlockTooNew)
+}
+
+func TestSideBranchProducer(t *testing.T) {
+	var (
+		validators = newTestValidators(3)
+		chain      = newTestChain(t, newAuthorityChainParams(validators))
+	)
+
+	block1 := newTestBlock(t, chain, validators)
+	require.Nil(t, chain.AddBlock(block1))
+	require.Nil(t, chain.AddBlock(newTestBlock(t, chain, validators)))
+
+	// Side branch blocks of other nodes are neither stored nor indexed
+	block := createRandomSignedBlockOnTop(block1, cryptography.NewPrivateKey())
+	require.NotNil(t, chain.AddBlock(block))
+	require.False(t, chain.HasBlock(types.HashBlockBytes(block)))
+
+	// The turn of side branch blocks is checked when they are connected
+	block = createRandomSignedBlockOnTop(block1, validators[0])
+	require.Nil(t, chain.AddBlock(block))
+	require.True(t, chain.HasBlock(types.HashBlockBytes(block)))
+}
+
+func TestGovernanceTransactions(t *testing.T) {
+	var (
+		validators   = newTestValidators(3)
+		chain        = newTestChain(t, newAuthorityChainParams(validators))
+		newValidator = cryptography.NewPrivateKey()
+	)
+
+	// More than half of the validators have to sign
+	tx := newGovernanceTx(genproto.GovernanceAction_ADD_VALIDATOR, newValidator.Public().Bytes(), 0, validators[0])
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	tx = newGovernanceTx(genproto.GovernanceAction_ADD_VALIDATOR, newValidator.Public().Bytes(), 0, validators[0], newValidator)
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	// Approvals for another network with the same validators are not valid
+	tx = types.NewGovernanceTransaction(&genproto.GovernanceAction{
+		Type:      genproto.GovernanceAction_ADD_VALIDATOR,
+		PublicKey: newValidator.Public().Bytes(),
+	})
+	types.SignGovernanceTransaction(validators[0], tx, "other-network")
+	types.SignGovernanceTransaction(validators[2], tx, "other-network")
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	addTx := newGovernanceTx(genproto.GovernanceAction_ADD_VALIDATOR, newValidator.Public().Bytes(), 0, validators[0], validators[2])
+	require.Nil(t, chain.ValidateTransaction(addTx))
+
+	removeTx := newGovernanceTx(genproto.GovernanceAction_REMOVE_VALIDATOR, validators[1].Public().Bytes(), 1, validators[0], validators[1], validators[2])
+	require.NotNil(t, chain.ValidateTransaction(removeTx))
+
+	// The template orders the governance transactions by their sequence
+	block := newTestBlock(t, chain, validators, removeTx, addTx)
+	require.Equal(t, []*genproto.Transaction{addTx, removeTx}, block.Transactions[1:])
+	require.Nil(t, chain.AddBlock(block))
+
+	expected := [][]byte{validators[0].Public().Bytes(), validators[2].Public().Bytes(), newValidator.Public().Bytes()}
+	require.Equal(t, expected, chain.Validators())
+	require.Equal(t, newValidator.Public().Bytes(), chain.NextProducer(time.Now()))
+
+	// Applied actions can't be replayed
+	require.NotNil(t, chain.ValidateTransaction(addTx))
+
+	// The block at height 2 is produced by the new validator
+	validators = append(validators, newValidator)
+	block = newTestBlock(t, chain, validators)
+	require.Equal(t, newValidator.Public().Bytes(), block.PublicKey)
+	require.Nil(t, chain.AddBlock(block))
+
+	// Disconnecting the blocks restores the validator set
+	_, err := chain.DisconnectTip()
+	require.Nil(t, err)
+	_, err = chain.DisconnectTip()
+	require.Nil(t, err)
+
+	require.Equal(t, newAuthorityChainParams(validators[:3]).Validators, hexKeys(chain.Validators()))
+	require.Nil(t, chain.ValidateTransaction(addTx))
+
+	// Governance transactions are not allowed without a validator set
+	require.NotNil(t, newMemoryChain(t).ValidateTransaction(addTx))
+}
+
+func TestValidatorSetRestartFromDisk(t *testing.T) {
+	var (
+		path         = filepath.Join(t.TempDir(), "chain.db")
+		store        = openTestDiskStore(t, path)
+		validators   = newTestValidators(2)
+		params       = newAuthorityChainParams(validators)
+		newValidator = cryptography.NewPrivateKey()
+	)
+
+	chain, err := NewChain(params, store)
+	require.Nil(t, err)
+
+	tx := newGovernanceTx(genproto.GovernanceAction_ADD_VALIDATOR, newValidator.Public().Bytes(), 0, validators...)
+	require.Nil(t, chain.AddBlock(newTestBlock(t, chain, validators, tx)))
+	require.Nil(t, store.Close())
+
+	store = openTestDiskStore(t, path)
+	defer store.Close()
+
+	chain, err = NewChain(params, store)
+	require.Nil(t, err)
+	require.Len(t, chain.Validators(), 3)
+
+	// The undo record restores the validator set of the loaded chain
+	_, err = chain.DisconnectTip()
+	require.Nil(t, err)
+	require.Len(t, chain.Validators(), 2)
+}
+
+// tipTimestamp returns the timestamp of the chain tip block.
+func tipTimestamp(t *testing.T, chain *Chain) int64 {
+	block, err := chain.GetBlockByHeight(chain.Height())
+	require.Nil(t, err)
+
+	return block.Header.Timestamp
+}
+
+func hexKeys(keys [][]byte) []string {
+	encoded := make([]string, len(keys))
+	for idx, key := range keys {
+		encoded[idx] = cryptography.NewPublicKeyFromBytes(key).String()
+	}
+
+	return encoded
+}
@@ -0,0 +1,119 @@
+package node
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"slices"
+
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+)
+
+// blockNode is an entry of the block index. It represents a known block header
+// that is either part of the main chain or of a side branch.
+type blockNode struct {
+	hash   string
+	header *genproto.BlockHeader
+	parent *blockNode
+	height int
+	// chainWork is the total work of the chain up to and including this block
+	chainWork *big.Int
+	// seed is the proposer draw seed of the following block. It mixes the seed secrets revealed
+	// by the block and its ancestors, starting with the genesis block hash (proof-of-stake only).
+	seed []byte
+	// invalid is set when the block failed validation while connecting it to the main chain
+	invalid bool
+}
+
+// ancestor returns the ancestor of the node at the given height.
+func (n *blockNode) ancestor(height int) *blockNode {
+	if height < 0 || height > n.height {
+		return nil
+	}
+
+	node := n
+	for node.height > height {
+		node = node.parent
+	}
+
+	return node
+}
+
+// medianTime returns the median timestamp of the node and its ancestors, up to medianTimeBlocks blocks.
+// Timestamps of new blocks must be after the median time of their parent.
+func (n *blockNode) medianTime() int64 {
+	timestamps := make([]int64, 0, medianTimeBlocks)
+	for node := n; node != nil && len(timestamps) < medianTimeBlocks; node = node.parent {
+		timestamps = append(timestamps, node.header.Timestamp)
+	}
+
+	slices.Sort(timestamps)
+	return timestamps[len(timestamps)/2]
+}
+
+// BlockIndex is a tree of all known block headers. It keeps the headers of side branches,
+// so the chain can switch to a competing branch once it accumulates more work.
+type BlockIndex struct {
+	nodes map[string]*blockNode
+}
+
+func NewBlockIndex() *BlockIndex {
+	return &BlockIndex{
+		nodes: make(map[string]*blockNode),
+	}
+}
+
+// Add inserts the header as a child of the parent node. The parent is nil for the genesis block.
+func (bi *BlockIndex) Add(header *genproto.BlockHeader, parent *blockNode) *blockNode {
+	node := &blockNode{
+		hash:      hex.EncodeToString(types.HashBlockHeader(header)),
+		header:    header,
+		parent:    parent,
+		chainWork: calculateBlockWork(header),
+	}
+
+	if parent != nil {
+		node.height = parent.height + 1
+		node.chainWork.Add(node.chainWork, parent.chainWork)
+
+		seed := sha256.Sum256(append(slices.Clone(parent.seed), header.SeedReveal...))
+		node.seed = seed[:]
+	} else {
+		node.seed = types.HashBlockHeader(header)
+	}
+
+	bi.nodes[node.hash] = node
+	return node
+}
+
+func (bi *BlockIndex) Get(hash string) (*blockNode, bool) {
+	node, ok := bi.nodes[hash]
+	return node, ok
+}
+
+// findFork returns the most recent common ancestor of the given nodes.
+func findFork(a, b *blockNode) *blockNode {
+	if a.height > b.height {
+		a = a.ancestor(b.height)
+	} else {
+		b = b.ancestor(a.height)
+	}
+
+	for a != b {
+		a = a.parent
+		b = b.parent
+	}
+
+	return a
+}
+
+// calculateBlockWork returns the amount of work the block adds to the chain.
+// Blocks without a difficulty target all count the same, so the chain with the most work is the longest one.
+func calculateBlockWork(header *genproto.BlockHeader) *big.Int {
+	if header.Bits == 0 {
+		return big.NewInt(1)
+	}
+
+	return types.CalculateWork(header.Bits)
+}
@@ -0,0 +1,174 @@
+package node
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// NewBlockTemplate builds an unsigned block on top of the current chain tip from the given transactions.
+// Transactions that are not valid on top of the tip are left out. The block starts with a coinbase transaction
+// that pays the block subsidy and the fees of the included transactions to the given address.
+// Governance transactions go first, in the order of their sequence. Transactions that would make the block
+// exceed the block limits of the chain parameters are left out as well.
+func (c *Chain) NewBlockTemplate(txList []*genproto.Transaction, coinbaseAddress []byte) *genproto.Block {
+	return c.NewBlockTemplateAt(txList, coinbaseAddress, time.Now())
+}
+
+// NewBlockTemplateAt builds the block template like NewBlockTemplate, produced at the given time.
+// The time decides whose turn it is to produce the block, see IsNextProducer.
+func (c *Chain) NewBlockTemplateAt(txList []*genproto.Transaction, coinbaseAddress []byte, now time.Time) *genproto.Block {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	height := c.blockHeaders.Height() + 1
+	prevHeader := c.blockHeaders.Get(c.blockHeaders.Height())
+	tip := c.tipNode()
+
+	block := &genproto.Block{
+		Header: &genproto.BlockHeader{
+			Version:   blockVersion,
+			Height:    int32(height),
+			PrevHash:  types.HashBlockHeader(prevHeader),
+			Timestamp: blockTimestamp(tip, now),
+			Bits:      c.requiredBits(tip),
+		},
+		// The coinbase transaction is added once the fees are known
+		Transactions: []*genproto.Transaction{nil},
+	}
+
+	budget := c.newBlockBudget(block, coinbaseAddress)
+
+	// Governance transactions are applied to a copy of the validator set in the sequence order,
+	// so the following ones see their changes. The same goes for slashing transactions and the stake set.
+	validators := c.validators.clone()
+	stakes := c.stakes.clone()
+	// Transactions are validated against a view of the UTXO set, so the template can include a transaction
+	// spending the outputs of a previous one and never includes two transactions spending the same output
+	view := newUTXOView(c.utxoStore)
+	included := make(map[string]struct{}, len(txList))
+
+	governanceTxs := slices.DeleteFunc(slices.Clone(txList), func(tx *genproto.Transaction) bool {
+		return !types.IsGovernanceTransaction(tx)
+	})
+	slices.SortFunc(governanceTxs, func(a, b *genproto.Transaction) int {
+		return cmp.Compare(a.Governance.Sequence, b.Governance.Sequence)
+	})
+
+	for _, tx := range governanceTxs {
+		if budget.fits(tx) && validators.apply(tx, c.params.ChainID) == nil {
+			block.Transactions = append(block.Transactions, tx)
+			budget.add(tx)
+		}
+	}
+
+	// reward is the block subsidy and the fees of the included transactions paid by the coinbase transaction
+	reward := c.params.BlockSubsidy(height)
+	// Transactions that are not valid are retried in the next pass over the list, since they may spend
+	// the outputs of transactions later in the list. The passes stop once no more transactions are included.
+	for pending := txList; len(pending) > 0; {
+		rest := make([]*genproto.Transaction, 0, len(pending))
+		for _, tx := range pending {
+			if types.IsGovernanceTransaction(tx) {
+				continue
+			}
+
+			hash := types.HashTransactionString(tx)
+			if _, ok := included[hash]; ok || !budget.fits(tx) {
+				continue
+			}
+
+			if types.IsSlashingTransaction(tx) {
+				if c.params.Consensus == ConsensusProofOfStake && stakes.slash(tx) == nil {
+					block.Transactions = append(block.Transactions, tx)
+					budget.add(tx)
+				}
+				continue
+			}
+
+			fee, err := c.validateTransaction(tx, height, view)
+			if err != nil {
+				rest = append(rest, tx)
+				continue
+			}
+
+			totalReward, err := addAmounts(reward, fee)
+			if err != nil || view.apply(tx, height, false) != nil {
+				continue
+			}
+
+			block.Transactions = append(block.Transactions, tx)
+			included[hash] = struct{}{}
+			budget.add(tx)
+			reward = totalReward
+		}
+
+		if len(rest) == len(pending) {
+			break
+		}
+		pending = rest
+	}
+
+	block.Transactions[0] = types.NewCoinbaseTransaction(int32(height), coinbaseAddress, reward)
+
+	return block
+}
+
+// blockTimestamp returns the timestamp of a block produced on top of the parent block at the given time.
+// The timestamp has to be after the median time even if the local clock is behind.
+func blockTimestamp(parent *blockNode, now time.Time) int64 {
+	return max(now.Unix(), parent.medianTime()+1)
+}
+
+// blockBudget tracks the block limits left while transactions are added to a block template.
+type blockBudget struct {
+	size   int
+	txs    int
+	sigOps int
+}
+
+// newBlockBudget returns the budget of the block with the header only. The size of the coinbase transaction
+// and of the block signature is reserved up front, since they are added after the transactions.
+func (c *Chain) newBlockBudget(block *genproto.Block, coinbaseAddress []byte) *blockBudget {
+	// The root hash is not calculated yet and the nonce is only found by mining
+	header := proto.Clone(block.Header).(*genproto.BlockHeader)
+	header.RootHash = make([]byte, sha256.Size)
+	header.Nonce = math.MaxUint64
+
+	reserved := &genproto.Block{
+		Header:       header,
+		PublicKey:    make([]byte, cryptography.PubKeyLen),
+		Signature:    make([]byte, cryptography.SigLen),
+		Transactions: []*genproto.Transaction{types.NewCoinbaseTransaction(block.Header.Height, coinbaseAddress, math.MaxInt64)},
+	}
+
+	return &blockBudget{
+		size:   c.params.MaxBlockSize - proto.Size(reserved),
+		txs:    c.params.MaxBlockTransactions - 1,
+		sigOps: c.params.MaxBlockSigOps,
+	}
+}
+
+// fits checks whether the transaction can be added to the block without exceeding the limits.
+func (b *blockBudget) fits(tx *genproto.Transaction) bool {
+	return b.txs > 0 && blockTxSize(tx) <= b.size && types.CountSignatureChecks(tx) <= b.sigOps
+}
+
+func (b *blockBudget) add(tx *genproto.Transaction) {
+	b.size -= blockTxSize(tx)
+	b.txs--
+	b.sigOps -= types.CountSignatureChecks(tx)
+}
+
+// blockTxSize returns the number of bytes the transaction adds to the serialized block.
+func blockTxSize(tx *genproto.Transaction) int {
+	return protowire.SizeTag(4) + protowire.SizeBytes(proto.Size(tx))
+}
@@ -3,91 +3,610 @@ package node
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"sync"
 	"time"
 
-	"github.com/oleglegun/blockchain-btc/internal/cryptography"
 	"github.com/oleglegun/blockchain-btc/internal/genproto"
 	"github.com/oleglegun/blockchain-btc/internal/types"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
-	genesisBlockSeed    = "b69d0c49b336d58aca501f6a0ba60c933b5904bea73a6c288639b9c3c830627f"
-	genesisBlockVersion = 1
-	genesisBlockHeight  = 0
-	genesisBlockAmount  = 1e6
+	blockVersion = 1
+	// medianTimeBlocks is the number of previous blocks the median time is calculated from
+	medianTimeBlocks = 11
+	// maxBlockTimeOffset is how far the timestamp of a block may be ahead of the local clock
+	maxBlockTimeOffset = 2 * time.Hour
+	// maxFallbackTimeOffset is how far the timestamp of a block produced in the turn of another producer
+	// may be ahead of the local clock
+	maxFallbackTimeOffset = 2 * time.Second
+)
+
+var (
+	// ErrBlockExists is returned when adding a block that is already known to the chain.
+	ErrBlockExists = errors.New("block already exists")
+	// ErrOrphanBlock is returned when adding a block whose parent block is unknown.
+	ErrOrphanBlock = errors.New("parent block is unknown")
+	// ErrGenesisMismatch is returned when the stored chain has a different genesis block than the chain parameters.
+	ErrGenesisMismatch = errors.New("genesis block doesn't match the chain parameters")
+	// ErrFinalizedBlock is returned when a block or a chain change conflicts with the finalized block.
+	ErrFinalizedBlock = errors.New("conflicts with the finalized block")
+	// ErrBadBlockHeight is returned when the height of a block doesn't follow the height of its parent block.
+	ErrBadBlockHeight = errors.New("block height doesn't follow the parent block")
+	// ErrBlockTooOld is returned when the timestamp of a block is not after the median time of the previous blocks.
+	ErrBlockTooOld = errors.New("block timestamp is not after the median time of the previous blocks")
+	// ErrBlockTooNew is returned when the timestamp of a block is too far ahead of the local clock.
+	ErrBlockTooNew = errors.New("block timestamp is too far in the future")
+	// ErrUnsupportedBlockVersion is returned when a block has a version the chain doesn't support.
+	ErrUnsupportedBlockVersion = errors.New("unsupported block version")
+	// ErrTransactionLocked is returned when the lock time or a relative lock of an input of a transaction is not reached yet.
+	ErrTransactionLocked = errors.New("transaction is locked")
 )
 
 type Chain struct {
+	lock sync.RWMutex
+
+	params      *ChainParams
+	genesisHash []byte
+
+	store        ChainStore
 	txStore      TxStore
 	blockStore   BlockStore
 	utxoStore    UTXOStore
 	blockHeaders *BlockHeaderList
+	// blockIndex contains the headers of all known blocks, including side branches
+	blockIndex *BlockIndex
+	// validators is the validator set at the chain tip
+	validators *ValidatorSet
+	// stakes is the stake set at the chain tip
+	stakes *StakeSet
+	// dataIndex contains the data outputs of the main chain
+	dataIndex *DataIndex
+	// finalized is the last finalized block. It and its ancestors are never removed from the main chain.
+	finalized *blockNode
+	// orphanedTxHandler receives the transactions of disconnected blocks that are not part of the new main chain
+	orphanedTxHandler func([]*genproto.Transaction)
 }
 
-func NewChain(bs BlockStore, txs TxStore, utxos UTXOStore) *Chain {
+// NewChain creates a chain with the given parameters on top of the given store. If the store already contains a chain,
+// its main chain is loaded starting from the stored tip and its genesis block is checked against the parameters.
+// Otherwise the genesis block is created from the parameters.
+func NewChain(params *ChainParams, store ChainStore) (*Chain, error) {
+	genesisBlock := createGenesisBlock(params)
+
 	chain := &Chain{
-		txStore:      txs,
-		utxoStore:    utxos,
-		blockStore:   bs,
+		params:       params,
+		genesisHash:  types.HashBlockBytes(genesisBlock),
+		store:        store,
+		txStore:      store.TxStore(),
+		utxoStore:    store.UTXOStore(),
+		blockStore:   store.BlockStore(),
 		blockHeaders: NewBlockHeaderList(),
+		blockIndex:   NewBlockIndex(),
+		validators:   newValidatorSet(params),
+		stakes:       newStakeSet(),
+		dataIndex:    newDataIndex(),
+	}
+
+	tipHash, err := chain.blockStore.GetTip()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain tip: %w", err)
+	}
+
+	if tipHash != "" {
+		if err := chain.loadMainChain(tipHash); err != nil {
+			return nil, fmt.Errorf("failed to load chain: %w", err)
+		}
+
+		if !bytes.Equal(types.HashBlockHeader(chain.blockHeaders.Get(0)), chain.genesisHash) {
+			return nil, ErrGenesisMismatch
+		}
+
+		if err := chain.loadFinalized(); err != nil {
+			return nil, fmt.Errorf("failed to load finalized block: %w", err)
+		}
+
+		return chain, nil
+	}
+
+	chain.finalized = chain.blockIndex.Add(genesisBlock.Header, nil)
+	if err := chain.connectBlock(genesisBlock); err != nil {
+		return nil, fmt.Errorf("failed to add genesis block: %w", err)
+	}
+
+	return chain, nil
+}
+
+// loadMainChain rebuilds the block headers of the main chain ending with the given tip from the block store.
+// The UTXO set is already up to date with the tip, the validator and stake sets and the data index are rebuilt by replaying
+// the blocks of the main chain. Side branches are not restored.
+func (c *Chain) loadMainChain(tipHash string) error {
+	headers := make([]*genproto.BlockHeader, 0)
+
+	for hash := tipHash; ; {
+		block, err := c.blockStore.Get(hash)
+		if err != nil {
+			return err
+		}
+		headers = append(headers, block.Header)
+
+		// Only the genesis block has no previous block
+		if len(block.Header.PrevHash) == 0 {
+			break
+		}
+		hash = hex.EncodeToString(block.Header.PrevHash)
+	}
+
+	var parent *blockNode
+	for i := len(headers) - 1; i >= 0; i-- {
+		parent = c.blockIndex.Add(headers[i], parent)
+		c.blockHeaders.Add(headers[i])
+
+		block, err := c.blockStore.Get(parent.hash)
+		if err != nil {
+			return err
+		}
+
+		if err := c.replayBlockState(block); err != nil {
+			return fmt.Errorf("failed to replay block %s: %w", parent.hash, err)
+		}
+	}
+
+	return nil
+}
+
+// replayBlockState applies the changes of a connected block to the validator and stake sets and the data index.
+// The seed commitment of the producer is replayed as well.
+// The UTXOs spent by the block are looked up in the UTXO set, where they are kept as spent.
+func (c *Chain) replayBlockState(block *genproto.Block) error {
+	if c.params.Consensus == ConsensusProofOfStake && block.Header.Height > 0 {
+		c.stakes.commit(blockProducerAddress(block), block.Header)
+	}
+
+	for _, tx := range block.Transactions {
+		c.dataIndex.add(tx)
+
+		switch {
+		case types.IsGovernanceTransaction(tx):
+			if err := c.validators.apply(tx, c.params.ChainID); err != nil {
+				return err
+			}
+		case types.IsSlashingTransaction(tx):
+			if err := c.stakes.slash(tx); err != nil {
+				return err
+			}
+		}
+
+		for _, txOutput := range tx.Outputs {
+			if txOutput.Type == genproto.OutputType_STAKE {
+				c.stakes.add(txOutput.Address, txOutput.Amount)
+			}
+		}
+
+		for _, txInput := range tx.Inputs {
+			utxo, err := c.utxoStore.Get(getUTXOKey(hex.EncodeToString(txInput.PrevTxHash), int(txInput.PrevTxOutIndex)))
+			if err != nil {
+				return err
+			}
+
+			if utxo.Type == genproto.OutputType_STAKE {
+				c.stakes.add(utxo.Address, -utxo.Amount)
+			}
+		}
 	}
 
-	chain.addBlock(createGenesisBlock())
-	return chain
+	return nil
+}
+
+// Params returns the parameters of the chain.
+func (c *Chain) Params() *ChainParams {
+	return c.params
 }
 
+// GenesisHash returns the hash of the genesis block.
+func (c *Chain) GenesisHash() []byte {
+	return c.genesisHash
+}
+
+// SetOrphanedTxHandler sets the handler that receives the transactions which were removed from the main chain
+// during a chain reorganization, so they can be returned to the mempool.
+func (c *Chain) SetOrphanedTxHandler(handler func([]*genproto.Transaction)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.orphanedTxHandler = handler
+}
+
+// AddBlock adds the block to the chain. A block that extends the main chain is validated and connected.
+// A block that extends a side branch is stored, and if the branch ends up with more work than the main chain,
+// the chain is reorganized to the branch. Blocks that don't descend from the finalized block are rejected.
 func (c *Chain) AddBlock(block *genproto.Block) error {
-	if err := c.ValidateBlock(block); err != nil {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	hash := types.HashBlockString(block)
+
+	if _, ok := c.blockIndex.Get(hash); ok {
+		return fmt.Errorf("block with hash %s: %w", hash, ErrBlockExists)
+	}
+
+	parent, ok := c.blockIndex.Get(hex.EncodeToString(block.Header.PrevHash))
+	if !ok {
+		return fmt.Errorf("block with hash %s: %w", hash, ErrOrphanBlock)
+	}
+
+	if parent.invalid {
+		return fmt.Errorf("block with hash %s extends an invalid block", hash)
+	}
+
+	if parent.ancestor(c.finalized.height) != c.finalized {
+		return fmt.Errorf("block with hash %s: %w", hash, ErrFinalizedBlock)
+	}
+
+	tip := c.tipNode()
+
+	if parent == tip {
+		if err := c.validateBlock(block); err != nil {
+			return err
+		}
+
+		c.blockIndex.Add(block.Header, parent)
+		return c.connectBlock(block)
+	}
+
+	if err := c.verifyBlock(block, parent); err != nil {
 		return err
 	}
 
-	return c.addBlock(block)
+	node := c.blockIndex.Add(block.Header, parent)
+	if err := c.blockStore.Put(block); err != nil {
+		return fmt.Errorf("failed to put block into store: %w", err)
+	}
+
+	if node.chainWork.Cmp(tip.chainWork) <= 0 {
+		return nil
+	}
+
+	return c.reorganize(node)
 }
 
-// We cannot validate the genesis block, that is why AddBlock is split in 2 funcs.
-func (c *Chain) addBlock(block *genproto.Block) error {
-	c.blockHeaders.Add(block.Header)
+// DisconnectTip removes the tip block from the main chain and rolls the UTXO set back using the undo record of the block.
+// The disconnected block is kept in the block index as a side branch block and its transactions are passed
+// to the orphaned transactions handler. The genesis block and finalized blocks can't be disconnected.
+func (c *Chain) DisconnectTip() (*genproto.Block, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
-	for _, tx := range block.Transactions {
-		if err := c.txStore.Put(tx); err != nil {
-			return fmt.Errorf("failed to put transaction into store: %w", err)
+	tip := c.tipNode()
+	if tip.parent == nil {
+		return nil, fmt.Errorf("genesis block can't be disconnected")
+	}
+
+	if tip == c.finalized {
+		return nil, fmt.Errorf("block with hash %s: %w", tip.hash, ErrFinalizedBlock)
+	}
+
+	disconnected, err := c.disconnectBlocks(tip.parent)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.orphanedTxHandler != nil {
+		c.orphanedTxHandler(getOrphanedTransactions(disconnected, nil))
+	}
+
+	return disconnected[0], nil
+}
+
+// connectBlock appends the block to the main chain, applies its transactions to the UTXO set and stores
+// the undo record of the changes. The block is not validated, that is why the genesis block can be
+// connected with it as well.
+//
+// All the changes are staged first and only written to the stores once the whole block was applied, so a block
+// failing in the middle, e.g. on a missing UTXO, leaves the stores and the chain state untouched.
+func (c *Chain) connectBlock(block *genproto.Block) error {
+	height := c.blockHeaders.Height() + 1
+	hash := types.HashBlockString(block)
+
+	var (
+		view       = newUTXOView(c.utxoStore)
+		validators = c.validators
+		stakes     = c.stakes.clone()
+		undo       = &BlockUndo{
+			SpentUTXOs:   make([]*UTXO, 0),
+			CreatedUTXOs: make([]string, 0),
 		}
+	)
 
-		hash := types.HashTransactionString(tx)
+	for txIdx, tx := range block.Transactions {
+		if types.IsGovernanceTransaction(tx) {
+			if undo.ValidatorSet == nil {
+				undo.ValidatorSet = c.validators
+				validators = c.validators.clone()
+			}
 
-		for idx, txOutput := range tx.Outputs {
-			utxo := NewUTXO(hash, idx, txOutput.Amount)
+			if err := validators.apply(tx, c.params.ChainID); err != nil {
+				return fmt.Errorf("failed to apply governance transaction: %w", err)
+			}
+			continue
+		}
 
-			if err := c.utxoStore.Put(utxo); err != nil {
-				return fmt.Errorf("failed to put utxo into store: %w", err)
+		if types.IsSlashingTransaction(tx) {
+			if err := stakes.slash(tx); err != nil {
+				return fmt.Errorf("failed to apply slashing transaction: %w", err)
 			}
+			continue
 		}
 
 		for _, txInput := range tx.Inputs {
 			key := getUTXOKey(hex.EncodeToString(txInput.PrevTxHash), int(txInput.PrevTxOutIndex))
-			utxo, err := c.utxoStore.Get(key)
+			utxo, err := view.Get(key)
 			if err != nil {
 				return fmt.Errorf("failed to get utxo: %w", err)
 			}
+
 			// Not double spending
-			utxo.IsSpent = true
-			if err := c.utxoStore.Put(utxo); err != nil {
-				return fmt.Errorf("failed to put spent utxo into store: %w", err)
+			if utxo.IsSpent {
+				return fmt.Errorf("utxo %s is already spent", key)
+			}
+
+			spentUTXO := *utxo
+			undo.SpentUTXOs = append(undo.SpentUTXOs, &spentUTXO)
+
+			if utxo.Type == genproto.OutputType_STAKE {
+				stakes.add(utxo.Address, -utxo.Amount)
+			}
+		}
+
+		txHash := types.HashTransactionString(tx)
+		for idx, txOutput := range tx.Outputs {
+			if txOutput.Type == genproto.OutputType_DATA {
+				continue
+			}
+			if txOutput.Type == genproto.OutputType_STAKE {
+				stakes.add(txOutput.Address, txOutput.Amount)
+			}
+			undo.CreatedUTXOs = append(undo.CreatedUTXOs, getUTXOKey(txHash, idx))
+		}
+
+		// Outputs of the genesis transaction are spendable right away
+		isCoinbase := txIdx == 0 && height > 0 && types.IsCoinbaseTransaction(tx)
+		if err := view.apply(tx, height, isCoinbase); err != nil {
+			return err
+		}
+	}
+
+	// The genesis block doesn't take part in the proposer draw
+	if c.params.Consensus == ConsensusProofOfStake && height > 0 {
+		undo.SeedCommitment = stakes.commit(blockProducerAddress(block), block.Header)
+	}
+
+	if err := c.commitBlockChanges(hash, block, undo, view); err != nil {
+		return err
+	}
+
+	c.blockHeaders.Add(block.Header)
+	c.validators = validators
+	c.stakes = stakes
+	for _, tx := range block.Transactions {
+		c.dataIndex.add(tx)
+	}
+
+	return nil
+}
+
+// commitBlockChanges writes the staged changes of a connected or disconnected block to the stores and makes
+// the block with the given hash the stored tip, all in a single commit. The block is only stored when it is connected.
+func (c *Chain) commitBlockChanges(tipHash string, block *genproto.Block, undo *BlockUndo, view *utxoView) error {
+	err := c.store.Commit(&BlockChanges{
+		Block: block,
+		Undo:  undo,
+		UTXOs: view.batch(),
+		Tip:   tipHash,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit block changes into store: %w", err)
+	}
+
+	return nil
+}
+
+// disconnectBlock removes the tip block from the main chain and reverts its changes to the UTXO set.
+// The spent UTXOs are restored before the created ones are removed, so UTXOs that were both created
+// and spent within the block don't remain in the set. Like in connectBlock, the changes are staged
+// and only written to the stores once all of them succeeded.
+func (c *Chain) disconnectBlock(block *genproto.Block) error {
+	undo, err := c.blockStore.GetUndo(types.HashBlockString(block))
+	if err != nil {
+		return fmt.Errorf("failed to get block undo record: %w", err)
+	}
+
+	view := newUTXOView(c.utxoStore)
+	stakes := c.stakes.clone()
+
+	for _, utxo := range undo.SpentUTXOs {
+		restoredUTXO := *utxo
+		view.put(&restoredUTXO)
+
+		if utxo.Type == genproto.OutputType_STAKE {
+			stakes.add(utxo.Address, utxo.Amount)
+		}
+	}
+
+	for _, key := range undo.CreatedUTXOs {
+		utxo, err := view.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to get created utxo: %w", err)
+		}
+
+		if utxo.Type == genproto.OutputType_STAKE {
+			stakes.add(utxo.Address, -utxo.Amount)
+		}
+
+		view.delete(key)
+	}
+
+	for _, tx := range block.Transactions {
+		if types.IsSlashingTransaction(tx) {
+			stakes.unslash(tx)
+		}
+	}
+
+	if c.params.Consensus == ConsensusProofOfStake {
+		stakes.uncommit(blockProducerAddress(block), undo.SeedCommitment)
+	}
+
+	if err := c.commitBlockChanges(hex.EncodeToString(block.Header.PrevHash), nil, nil, view); err != nil {
+		return err
+	}
+
+	if undo.ValidatorSet != nil {
+		c.validators = undo.ValidatorSet
+	}
+	c.stakes = stakes
+	c.blockHeaders.Remove()
+	for _, tx := range block.Transactions {
+		c.dataIndex.remove(tx)
+	}
+
+	return nil
+}
+
+// reorganize switches the main chain to the branch ending with the given node. The blocks of the main chain are
+// disconnected back to the common ancestor, then the blocks of the new branch are validated and connected.
+// If a block of the new branch is invalid, the original main chain is restored.
+func (c *Chain) reorganize(newTip *blockNode) error {
+	oldTip := c.tipNode()
+	fork := findFork(oldTip, newTip)
+
+	disconnected, err := c.disconnectBlocks(fork)
+	if err != nil {
+		return err
+	}
+
+	connectNodes := make([]*blockNode, 0, newTip.height-fork.height)
+	for node := newTip; node != fork; node = node.parent {
+		connectNodes = append(connectNodes, node)
+	}
+	slices.Reverse(connectNodes)
+
+	connected, err := c.connectBlocks(connectNodes)
+	if err != nil {
+		// Restore the original main chain
+		if _, restoreErr := c.disconnectBlocks(fork); restoreErr != nil {
+			return fmt.Errorf("failed to restore the main chain: %w", restoreErr)
+		}
+
+		slices.Reverse(disconnected)
+		for _, block := range disconnected {
+			if restoreErr := c.connectBlock(block); restoreErr != nil {
+				return fmt.Errorf("failed to restore the main chain: %w", restoreErr)
+			}
+		}
+
+		return fmt.Errorf("failed to reorganize the chain: %w", err)
+	}
+
+	if c.orphanedTxHandler != nil {
+		c.orphanedTxHandler(getOrphanedTransactions(disconnected, connected))
+	}
+
+	return nil
+}
+
+// disconnectBlocks disconnects main chain blocks until the fork node becomes the tip.
+// The disconnected blocks are returned starting from the old tip.
+func (c *Chain) disconnectBlocks(fork *blockNode) ([]*genproto.Block, error) {
+	disconnected := make([]*genproto.Block, 0)
+
+	for node := c.tipNode(); node != fork; node = node.parent {
+		block, err := c.blockStore.Get(node.hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block to disconnect: %w", err)
+		}
+
+		if err := c.disconnectBlock(block); err != nil {
+			return nil, fmt.Errorf("failed to disconnect block %s: %w", node.hash, err)
+		}
+
+		disconnected = append(disconnected, block)
+	}
+
+	return disconnected, nil
+}
+
+// connectBlocks validates and connects the blocks of the given nodes in order.
+// The first block that fails validation is marked as invalid along with all the following blocks.
+func (c *Chain) connectBlocks(nodes []*blockNode) ([]*genproto.Block, error) {
+	connected := make([]*genproto.Block, 0, len(nodes))
+
+	for idx, node := range nodes {
+		block, err := c.blockStore.Get(node.hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block to connect: %w", err)
+		}
+
+		if err := c.validateBlock(block); err != nil {
+			for _, invalidNode := range nodes[idx:] {
+				invalidNode.invalid = true
 			}
+			return nil, err
+		}
+
+		if err := c.connectBlock(block); err != nil {
+			return nil, err
 		}
 
+		connected = append(connected, block)
 	}
 
-	return c.blockStore.Put(block)
+	return connected, nil
+}
+
+// getOrphanedTransactions returns the transactions of the disconnected blocks that are not included in the connected blocks.
+// Coinbase transactions are never returned, since they are only valid in their own block.
+func getOrphanedTransactions(disconnected, connected []*genproto.Block) []*genproto.Transaction {
+	connectedTxs := make(map[string]struct{})
+	for _, block := range connected {
+		for _, tx := range block.Transactions {
+			connectedTxs[types.HashTransactionString(tx)] = struct{}{}
+		}
+	}
+
+	orphaned := make([]*genproto.Transaction, 0)
+	for _, block := range disconnected {
+		for _, tx := range block.Transactions[1:] {
+			if _, ok := connectedTxs[types.HashTransactionString(tx)]; !ok {
+				orphaned = append(orphaned, tx)
+			}
+		}
+	}
+
+	return orphaned
+}
+
+func (c *Chain) tipNode() *blockNode {
+	node, _ := c.blockIndex.Get(hex.EncodeToString(types.HashBlockHeader(c.blockHeaders.Get(c.blockHeaders.Height()))))
+	return node
 }
 
 func (c *Chain) ValidateBlock(block *genproto.Block) error {
-	if !types.VerifyBlock(block) {
-		return fmt.Errorf("block with hash %s has an invalid signature", types.HashBlockString(block))
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.validateBlock(block)
+}
+
+func (c *Chain) validateBlock(block *genproto.Block) error {
+	if err := c.verifyBlock(block, c.tipNode()); err != nil {
+		return err
 	}
 
-	currentBlock, err := c.GetBlockByHeight(c.Height())
+	currentBlock, err := c.getBlockByHeight(c.blockHeaders.Height())
 	if err != nil {
 		return err
 	}
@@ -98,55 +617,366 @@ func (c *Chain) ValidateBlock(block *genproto.Block) error {
 		return fmt.Errorf("block with hash %s is not a successor of the current block", types.HashBlockString(block))
 	}
 
+	if len(block.Transactions) == 0 || !types.IsCoinbaseTransaction(block.Transactions[0]) {
+		return fmt.Errorf("block with hash %s doesn't start with a coinbase transaction", types.HashBlockString(block))
+	}
+
+	height := c.blockHeaders.Height() + 1
+
+	if err := c.checkStakeSeed(block); err != nil {
+		return err
+	}
+
+	// Governance and slashing transactions are applied to copies of the validator and stake sets
+	// and the other transactions to a view of the UTXO set, so the following ones see their changes.
+	// A transaction can spend the outputs of the previous transactions of the block, but no output twice.
+	validators := c.validators.clone()
+	stakes := c.stakes.clone()
+	view := newUTXOView(c.utxoStore)
+
+	if err := view.apply(block.Transactions[0], height, true); err != nil {
+		return err
+	}
+
+	txHashes := make(map[string]struct{}, len(block.Transactions))
 	for _, tx := range block.Transactions {
-		if err := c.ValidateTransaction(tx); err != nil {
+		hash := types.HashTransactionString(tx)
+		if _, ok := txHashes[hash]; ok {
+			return fmt.Errorf("block with hash %s contains transaction %s more than once", types.HashBlockString(block), hash)
+		}
+		txHashes[hash] = struct{}{}
+	}
+
+	var fees int64
+	for _, tx := range block.Transactions[1:] {
+		if types.IsGovernanceTransaction(tx) {
+			if err := validators.apply(tx, c.params.ChainID); err != nil {
+				return fmt.Errorf("failed to validate governance transaction: %w", err)
+			}
+			continue
+		}
+
+		if types.IsSlashingTransaction(tx) {
+			if err := stakes.slash(tx); err != nil {
+				return fmt.Errorf("failed to validate slashing transaction: %w", err)
+			}
+			continue
+		}
+
+		fee, err := c.validateTransaction(tx, height, view)
+		if err != nil {
 			return fmt.Errorf("failed to validate transaction: %w", err)
 		}
+
+		fees, err = addAmounts(fees, fee)
+		if err != nil {
+			return fmt.Errorf("failed to sum block fees: %w", err)
+		}
+
+		if err := view.apply(tx, height, false); err != nil {
+			return err
+		}
+	}
+
+	if err := c.validateCoinbaseTransaction(block.Transactions[0], height, fees); err != nil {
+		return fmt.Errorf("failed to validate coinbase transaction: %w", err)
+	}
+
+	return nil
+}
+
+// verifyBlock performs the checks that don't depend on the UTXO set, so they can be done for side branch blocks as well.
+// Side branch blocks are checked before they are stored, so blocks of nodes that are not allowed to produce them
+// don't take up the block store.
+func (c *Chain) verifyBlock(block *genproto.Block, parent *blockNode) error {
+	if !types.VerifyBlock(block) {
+		return fmt.Errorf("block with hash %s has an invalid signature", types.HashBlockString(block))
+	}
+
+	now := time.Now()
+
+	if err := checkBlockHeader(block.Header, parent, now); err != nil {
+		return fmt.Errorf("block with hash %s: %w", types.HashBlockString(block), err)
+	}
+
+	if err := c.checkProducer(block, parent, now); err != nil {
+		return err
+	}
+
+	if err := c.checkBlockLimits(block); err != nil {
+		return err
+	}
+
+	return c.checkProofOfWork(block.Header, parent)
+}
+
+// checkBlockHeader checks the version, the height and the timestamp of the header following the parent block.
+// The timestamp must be after the median time of the previous blocks and at most maxBlockTimeOffset ahead of now.
+func checkBlockHeader(header *genproto.BlockHeader, parent *blockNode, now time.Time) error {
+	if header.Version < 1 || header.Version > blockVersion {
+		return fmt.Errorf("%w %d", ErrUnsupportedBlockVersion, header.Version)
+	}
+
+	if int(header.Height) != parent.height+1 {
+		return fmt.Errorf("%w: height %d, expected %d", ErrBadBlockHeight, header.Height, parent.height+1)
+	}
+
+	if medianTime := parent.medianTime(); header.Timestamp <= medianTime {
+		return fmt.Errorf("%w: timestamp %d, median time %d", ErrBlockTooOld, header.Timestamp, medianTime)
+	}
+
+	if maxTime := now.Add(maxBlockTimeOffset).Unix(); header.Timestamp > maxTime {
+		return fmt.Errorf("%w: timestamp %d, latest allowed %d", ErrBlockTooNew, header.Timestamp, maxTime)
+	}
+
+	return nil
+}
+
+// checkBlockLimits checks the serialized size, the number of transactions and the number of signature checks
+// of the block against the limits of the chain parameters.
+func (c *Chain) checkBlockLimits(block *genproto.Block) error {
+	hash := types.HashBlockString(block)
+
+	if len(block.Transactions) > c.params.MaxBlockTransactions {
+		return fmt.Errorf("block with hash %s has %d transactions, the limit is %d", hash, len(block.Transactions), c.params.MaxBlockTransactions)
+	}
+
+	if size := proto.Size(block); size > c.params.MaxBlockSize {
+		return fmt.Errorf("block with hash %s has %d bytes, the limit is %d", hash, size, c.params.MaxBlockSize)
+	}
+
+	var sigOps int
+	for _, tx := range block.Transactions {
+		sigOps += types.CountSignatureChecks(tx)
+	}
+
+	if sigOps > c.params.MaxBlockSigOps {
+		return fmt.Errorf("block with hash %s requires %d signature checks, the limit is %d", hash, sigOps, c.params.MaxBlockSigOps)
+	}
+
+	return nil
+}
+
+// validateCoinbaseTransaction checks that the coinbase transaction of the block at the given height
+// doesn't pay more than the block subsidy and the fees of the other block transactions.
+func (c *Chain) validateCoinbaseTransaction(tx *genproto.Transaction, height int, fees int64) error {
+	if int(tx.CoinbaseHeight) != height {
+		return fmt.Errorf("coinbase transaction with hash %s has height %d, expected %d", types.HashTransactionString(tx), tx.CoinbaseHeight, height)
+	}
+
+	for _, output := range tx.Outputs {
+		if output.Type != genproto.OutputType_TRANSFER {
+			return fmt.Errorf("coinbase transaction with hash %s has an output of type %s", types.HashTransactionString(tx), output.Type)
+		}
+	}
+
+	if err := checkLockScripts(tx); err != nil {
+		return err
+	}
+
+	if err := checkDataOutputs(tx); err != nil {
+		return err
+	}
+
+	outputSum, err := c.sumTotalOutputAmount(tx)
+	if err != nil {
+		return fmt.Errorf("failed to sum total output amount: %w", err)
+	}
+
+	reward, err := addAmounts(c.params.BlockSubsidy(height), fees)
+	if err != nil {
+		return fmt.Errorf("failed to sum block reward: %w", err)
+	}
+
+	if outputSum > reward {
+		return fmt.Errorf("coinbase transaction with hash %s pays more than the block subsidy and fees", types.HashTransactionString(tx))
 	}
 
 	return nil
 }
 
+// ValidateTransaction validates the transaction as if it was included in the block following the current chain tip.
+// Coinbase transactions are only valid as a part of a block.
 func (c *Chain) ValidateTransaction(tx *genproto.Transaction) error {
-	if !types.VerifyTransaction(tx) {
-		return fmt.Errorf("transaction with hash %s is invalid", types.HashTransactionString(tx))
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	_, err := c.validateTransaction(tx, c.blockHeaders.Height()+1, newUTXOView(c.utxoStore))
+	return err
+}
+
+// ValidatePoolTransaction validates the transaction like ValidateTransaction, but on top of the pending transactions
+// of the pool, so it can spend the outputs of unconfirmed transactions. Outputs spent by a pending transaction
+// can't be spent again. The pending transactions themselves are not validated again.
+func (c *Chain) ValidatePoolTransaction(tx *genproto.Transaction, pool PendingUTXOs) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	height := c.blockHeaders.Height() + 1
+	_, err := c.validateTransaction(tx, height, newPoolUTXOView(c.utxoStore, pool, height))
+	return err
+}
+
+// TransactionFee validates the transaction as if it was included in the block following the current chain tip
+// and returns its fee, which is the difference between the input and output amounts.
+// The fee is paid to the producer of the block that includes the transaction.
+func (c *Chain) TransactionFee(tx *genproto.Transaction) (int64, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.validateTransaction(tx, c.blockHeaders.Height()+1, newUTXOView(c.utxoStore))
+}
+
+// validateTransaction validates a non-coinbase transaction included in the block at the given height and returns its fee.
+// The spent outputs are looked up in the view. Governance transactions are checked against the current validator set
+// and pay no fee.
+func (c *Chain) validateTransaction(tx *genproto.Transaction, height int, view *utxoView) (int64, error) {
+	if types.IsGovernanceTransaction(tx) {
+		return 0, c.validators.clone().apply(tx, c.params.ChainID)
+	}
+
+	if types.IsSlashingTransaction(tx) {
+		if c.params.Consensus != ConsensusProofOfStake {
+			return 0, fmt.Errorf("transaction with hash %s is a slashing transaction outside of the proof-of-stake mode", types.HashTransactionString(tx))
+		}
+		return 0, c.stakes.clone().slash(tx)
+	}
+
+	if types.IsCoinbaseTransaction(tx) || tx.CoinbaseHeight != 0 {
+		return 0, fmt.Errorf("transaction with hash %s is a coinbase transaction", types.HashTransactionString(tx))
 	}
 
-	inputSum, err := c.sumTotalInputAmount(tx)
+	inputSum, inputs, err := c.sumTotalInputAmount(tx, height, view)
 	if err != nil {
-		return fmt.Errorf("failed to sum total input amount: %w", err)
+		return 0, fmt.Errorf("failed to sum total input amount: %w", err)
+	}
+
+	if err := c.checkTransactionLocks(tx, inputs, height); err != nil {
+		return 0, err
+	}
+
+	spentAmounts := make([]int64, len(inputs))
+	for idx, utxo := range inputs {
+		spentAmounts[idx] = utxo.Amount
+	}
+
+	for idx, utxo := range inputs {
+		if err := c.verifyTransactionInput(tx, idx, utxo, spentAmounts); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := c.validateOutputTypes(tx, inputs); err != nil {
+		return 0, err
+	}
+
+	if err := checkLockScripts(tx); err != nil {
+		return 0, err
+	}
+
+	if err := checkDataOutputs(tx); err != nil {
+		return 0, err
 	}
 
 	outputSum, err := c.sumTotalOutputAmount(tx)
 	if err != nil {
-		return fmt.Errorf("failed to sum total output amount: %w", err)
+		return 0, fmt.Errorf("failed to sum total output amount: %w", err)
 	}
 
 	if inputSum < outputSum {
-		return fmt.Errorf("transaction with hash %s has insufficient funds", types.HashTransactionString(tx))
+		return 0, fmt.Errorf("transaction with hash %s has insufficient funds", types.HashTransactionString(tx))
 	}
 
-	return nil
+	fee := inputSum - outputSum
+	if minFee := c.params.MinTransactionFee(tx); fee < minFee {
+		return 0, fmt.Errorf("transaction with hash %s pays fee %d, minimum fee is %d", types.HashTransactionString(tx), fee, minFee)
+	}
+
+	return fee, nil
 }
 
-func (c *Chain) sumTotalInputAmount(tx *genproto.Transaction) (int64, error) {
+// sumTotalInputAmount returns the total amount of the UTXOs spent by the transaction along with the UTXOs.
+func (c *Chain) sumTotalInputAmount(tx *genproto.Transaction, height int, view *utxoView) (int64, []*UTXO, error) {
 	var sumInputs int64
+	inputs := make([]*UTXO, 0, len(tx.Inputs))
+	keys := make(map[string]struct{}, len(tx.Inputs))
 
 	for _, input := range tx.Inputs {
-		key := fmt.Sprintf("%s:%d", hex.EncodeToString(input.PrevTxHash), input.PrevTxOutIndex)
-		utxo, err := c.utxoStore.Get(key)
+		key := getUTXOKey(hex.EncodeToString(input.PrevTxHash), int(input.PrevTxOutIndex))
+		utxo, err := view.Get(key)
 		if err != nil {
-			return 0, fmt.Errorf("failed to get utxo %s: %w", key, err)
+			return 0, nil, fmt.Errorf("failed to get utxo %s: %w", key, err)
 		}
 
-		if utxo.IsSpent {
-			return 0, fmt.Errorf("utxo %s is already spent", key)
+		if _, ok := keys[key]; ok || utxo.IsSpent {
+			return 0, nil, fmt.Errorf("utxo %s is already spent", key)
 		}
+		keys[key] = struct{}{}
 
-		sumInputs += utxo.Amount
+		if utxo.IsCoinbase && height-utxo.Height < c.params.CoinbaseMaturity {
+			return 0, nil, fmt.Errorf("coinbase utxo %s is spent before maturity", key)
+		}
+
+		if utxo.Type == genproto.OutputType_UNBONDING && height-utxo.Height < c.params.UnbondingPeriod {
+			return 0, nil, fmt.Errorf("unbonding utxo %s is spent before the end of the unbonding period", key)
+		}
+
+		if utxo.Type != genproto.OutputType_TRANSFER && c.stakes.isSlashed(utxo.Address) {
+			return 0, nil, fmt.Errorf("utxo %s belongs to a slashed validator", key)
+		}
+
+		sumInputs, err = addAmounts(sumInputs, utxo.Amount)
+		if err != nil {
+			return 0, nil, err
+		}
+		inputs = append(inputs, utxo)
 	}
 
-	return sumInputs, nil
+	return sumInputs, inputs, nil
+}
+
+// checkTransactionLocks checks that the lock time of the transaction included in the block at the given height
+// and the relative locks of its inputs spending the UTXOs are reached. Transactions are always validated
+// on top of the chain tip, so locks by time are compared with the median time of the tip.
+func (c *Chain) checkTransactionLocks(tx *genproto.Transaction, inputs []*UTXO, height int) error {
+	hash := types.HashTransactionString(tx)
+
+	if tx.LockTime != 0 {
+		if tx.LockTime < types.LockTimeThreshold {
+			if int(tx.LockTime) >= height {
+				return fmt.Errorf("%w: transaction with hash %s is locked until height %d", ErrTransactionLocked, hash, tx.LockTime)
+			}
+		} else if medianTime := c.tipNode().medianTime(); int64(tx.LockTime) >= medianTime {
+			return fmt.Errorf("%w: transaction with hash %s is locked until %d, median time is %d", ErrTransactionLocked, hash, tx.LockTime, medianTime)
+		}
+	}
+
+	for idx, input := range tx.Inputs {
+		value := int(input.Sequence & types.SequenceLockMask)
+		if input.Sequence&types.SequenceLockDisabled != 0 || value == 0 {
+			continue
+		}
+
+		utxo := inputs[idx]
+
+		if input.Sequence&types.SequenceLockTypeTime == 0 {
+			if height-utxo.Height < value {
+				return fmt.Errorf("%w: input %d of transaction with hash %s is locked for %d blocks after height %d",
+					ErrTransactionLocked, idx, hash, value, utxo.Height)
+			}
+			continue
+		}
+
+		// The age of the spent output is measured from the median time of the block before the one that created it
+		tip := c.tipNode()
+		spentTime := tip.ancestor(max(utxo.Height-1, 0)).medianTime()
+		if lockedUntil := spentTime + int64(value)<<types.SequenceLockGranularity; lockedUntil > tip.medianTime() {
+			return fmt.Errorf("%w: input %d of transaction with hash %s is locked until %d, median time is %d",
+				ErrTransactionLocked, idx, hash, lockedUntil, tip.medianTime())
+		}
+	}
+
+	return nil
 }
 
 func (c *Chain) sumTotalOutputAmount(tx *genproto.Transaction) (int64, error) {
@@ -156,13 +986,38 @@ func (c *Chain) sumTotalOutputAmount(tx *genproto.Transaction) (int64, error) {
 			return 0, fmt.Errorf("transaction with hash %s has negative output amount", types.HashTransactionString(tx))
 		}
 
-		sumOutputs += output.Amount
+		var err error
+		sumOutputs, err = addAmounts(sumOutputs, output.Amount)
+		if err != nil {
+			return 0, fmt.Errorf("transaction with hash %s: %w", types.HashTransactionString(tx), err)
+		}
 	}
 
 	return sumOutputs, nil
 }
 
+// addAmounts returns the sum of the amounts. A sum wrapping around the int64 range would create coins,
+// so negative amounts and sums overflowing int64 are rejected.
+func addAmounts(a, b int64) (int64, error) {
+	if a < 0 || b < 0 {
+		return 0, fmt.Errorf("negative amount")
+	}
+
+	if a > math.MaxInt64-b {
+		return 0, fmt.Errorf("sum of amounts overflows")
+	}
+
+	return a + b, nil
+}
+
 func (c *Chain) GetBlockByHash(hash []byte) (*genproto.Block, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.getBlockByHash(hash)
+}
+
+func (c *Chain) getBlockByHash(hash []byte) (*genproto.Block, error) {
 	hashString := hex.EncodeToString(hash)
 	block, err := c.blockStore.Get(hashString)
 	if err != nil {
@@ -171,21 +1026,85 @@ func (c *Chain) GetBlockByHash(hash []byte) (*genproto.Block, error) {
 	return block, nil
 }
 
+// HasBlock checks if the block with the given hash is known to the chain, either in the main chain or in a side branch.
+func (c *Chain) HasBlock(hash []byte) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.hasBlock(hash)
+}
+
+func (c *Chain) hasBlock(hash []byte) bool {
+	_, ok := c.blockIndex.Get(hex.EncodeToString(hash))
+	return ok
+}
+
 func (c *Chain) GetBlockByHeight(height int) (*genproto.Block, error) {
-	if height > c.Height() {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.getBlockByHeight(height)
+}
+
+func (c *Chain) getBlockByHeight(height int) (*genproto.Block, error) {
+	if height < 0 || height > c.blockHeaders.Height() {
 		return nil, fmt.Errorf("block with height %d doesn't exist", height)
 	}
 
 	blockHeader := c.blockHeaders.Get(height)
 	hash := types.HashBlockHeader(blockHeader)
-	block, err := c.GetBlockByHash(hash)
+	block, err := c.getBlockByHash(hash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get block at height %d: %w", height, err)
 	}
 	return block, nil
 }
 
+// GetBlockHeaders returns up to count consecutive main chain block headers starting at the given height.
+func (c *Chain) GetBlockHeaders(fromHeight, count int) ([]*genproto.BlockHeader, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if fromHeight < 0 || fromHeight > c.blockHeaders.Height() {
+		return nil, fmt.Errorf("block with height %d doesn't exist", fromHeight)
+	}
+
+	toHeight := min(fromHeight+count-1, c.blockHeaders.Height())
+
+	headers := make([]*genproto.BlockHeader, 0, toHeight-fromHeight+1)
+	for height := fromHeight; height <= toHeight; height++ {
+		headers = append(headers, c.blockHeaders.Get(height))
+	}
+
+	return headers, nil
+}
+
+// GetBlockHeight returns the height of the main chain block with the given hash.
+func (c *Chain) GetBlockHeight(hash []byte) (int, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	height, ok := c.blockHeaders.HeightOf(hash)
+	if !ok {
+		return 0, fmt.Errorf("block with hash %s is not in the main chain", hex.EncodeToString(hash))
+	}
+
+	return height, nil
+}
+
+// Tip returns the height and the hash of the block at the tip of the main chain.
+func (c *Chain) Tip() (int, []byte) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	height := c.blockHeaders.Height()
+	return height, types.HashBlockHeader(c.blockHeaders.Get(height))
+}
+
 func (c *Chain) Height() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
 	return c.blockHeaders.Height()
 }
 
@@ -195,18 +1114,35 @@ func (c *Chain) Height() int {
 
 type BlockHeaderList struct {
 	headerList []*genproto.BlockHeader
+	// heightMap maps block hashes to their heights in the list
+	heightMap map[string]int
 }
 
 func NewBlockHeaderList() *BlockHeaderList {
 	return &BlockHeaderList{
 		headerList: make([]*genproto.BlockHeader, 0),
+		heightMap:  make(map[string]int),
 	}
 }
 
 func (hs *BlockHeaderList) Add(h *genproto.BlockHeader) {
+	hs.heightMap[hex.EncodeToString(types.HashBlockHeader(h))] = len(hs.headerList)
 	hs.headerList = append(hs.headerList, h)
 }
 
+// HeightOf returns the height of the block header with the given hash.
+func (hs *BlockHeaderList) HeightOf(hash []byte) (int, bool) {
+	height, ok := hs.heightMap[hex.EncodeToString(hash)]
+	return height, ok
+}
+
+// Remove removes the last block header from the list.
+func (hs *BlockHeaderList) Remove() {
+	last := hs.headerList[len(hs.headerList)-1]
+	delete(hs.heightMap, hex.EncodeToString(types.HashBlockHeader(last)))
+	hs.headerList = hs.headerList[:len(hs.headerList)-1]
+}
+
 func (hs *BlockHeaderList) Get(height int) *genproto.BlockHeader {
 	return hs.headerList[height]
 }
@@ -215,31 +1151,3 @@ func (hs *BlockHeaderList) Height() int {
 	// blockchain always has a genesis block
 	return len(hs.headerList) - 1
 }
-
-func createGenesisBlock() *genproto.Block {
-	privKey := cryptography.NewPrivateKeyFromString(genesisBlockSeed)
-	block := &genproto.Block{
-		Header: &genproto.BlockHeader{
-			Version:   genesisBlockVersion,
-			Height:    genesisBlockHeight,
-			Timestamp: time.Now().Unix(),
-		},
-	}
-
-	tx := &genproto.Transaction{
-		Version: genesisBlockVersion,
-		Inputs:  []*genproto.TxInput{},
-		Outputs: []*genproto.TxOutput{
-			{
-				Amount:  genesisBlockAmount,
-				Address: privKey.Public().Address().Bytes(),
-			},
-		},
-	}
-
-	block.Transactions = append(block.Transactions, tx)
-
-	types.SignBlock(privKey, block)
-
-	return block
-}
@@ -1,21 +1,73 @@
 package node
 
 import (
+	"math"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/oleglegun/blockchain-btc/internal/cryptography"
 	"github.com/oleglegun/blockchain-btc/internal/genproto"
 	"github.com/oleglegun/blockchain-btc/internal/random"
 	"github.com/oleglegun/blockchain-btc/internal/types"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
 	genesisBlockTx0Hash = "bc88af88ffccbc54dbf64bef0b865568c974844352a8b989c1ebcd914defd27c"
 )
 
+// testTxFee is the fee paid by the test transactions. It covers the minimum fee of the default chain parameters.
+const testTxFee = 1000
+
+func newMemoryChain(t *testing.T) *Chain {
+	return newTestChain(t, DefaultChainParams())
+}
+
+// newTestChain creates a chain with the given parameters on top of memory stores.
+func newTestChain(t *testing.T, params *ChainParams) *Chain {
+	chain, err := NewChain(params, NewMemoryStore())
+	require.Nil(t, err)
+
+	return chain
+}
+
+// newTestBlock creates a block on top of the chain tip signed by the first of the producers allowed to produce it.
+func newTestBlock(t *testing.T, chain *Chain, producers []cryptography.PrivateKey, txs ...*genproto.Transaction) *genproto.Block {
+	return newTestBlockAt(t, chain, producers, time.Now(), txs...)
+}
+
+// newTestBlockAt creates a block produced at the given time on top of the chain tip. The block is signed by the first
+// of the producers whose turn it is: the scheduled validator, the drawn staker or any producer in the other modes.
+// Proof-of-work blocks are mined.
+func newTestBlockAt(t *testing.T, chain *Chain, producers []cryptography.PrivateKey, now time.Time, txs ...*genproto.Transaction) *genproto.Block {
+	for _, producer := range producers {
+		if !chain.IsNextProducer(producer.Public().Bytes(), now) {
+			continue
+		}
+
+		block := chain.NewBlockTemplateAt(txs, producer.Public().Address().Bytes(), now)
+		chain.SetStakeSeed(block, producer)
+
+		if chain.Params().Consensus == ConsensusProofOfWork {
+			rootHash, err := types.CalculateRootHash(block)
+			require.Nil(t, err)
+			block.Header.RootHash = rootHash
+
+			require.True(t, mineBlock(block.Header, 2, func() bool { return false }))
+		}
+
+		types.SignBlock(producer, block)
+		return block
+	}
+
+	require.FailNow(t, "none of the producers is allowed to produce the block")
+	return nil
+}
+
 func TestNewChain(t *testing.T) {
-	chain := NewChain(NewMemoryBlockStore(), NewMemoryTxStore(), NewMemoryUTXOStore())
+	chain := newMemoryChain(t)
 	require.NotNil(t, chain)
 	require.Equal(t, 0, chain.Height())
 
@@ -24,7 +76,7 @@ func TestNewChain(t *testing.T) {
 }
 
 func TestAddBlock(t *testing.T) {
-	chain := NewChain(NewMemoryBlockStore(), NewMemoryTxStore(), NewMemoryUTXOStore())
+	chain := newMemoryChain(t)
 
 	privKey := cryptography.NewPrivateKey()
 
@@ -48,7 +100,7 @@ func TestAddBlock(t *testing.T) {
 }
 
 func TestChainHeight(t *testing.T) {
-	chain := NewChain(NewMemoryBlockStore(), NewMemoryTxStore(), NewMemoryUTXOStore())
+	chain := newMemoryChain(t)
 
 	privKey := cryptography.NewPrivateKey()
 
@@ -70,7 +122,12 @@ func createRandomSignedBlock(chain *Chain, privKey cryptography.PrivateKey) (*ge
 		return nil, err
 	}
 
+	block.Header.Height = prevBlock.Header.Height + 1
 	block.Header.PrevHash = types.HashBlockBytes(prevBlock)
+	block.Header.Timestamp = max(block.Header.Timestamp, prevBlock.Header.Timestamp+1)
+	block.Transactions = []*genproto.Transaction{
+		createCoinbaseTx(chain.Params(), int(block.Header.Height), privKey),
+	}
 
 	sig := types.SignBlock(privKey, block)
 	block.Signature = sig.Bytes()
@@ -83,7 +140,7 @@ func TestAddBlockWithTransactions(t *testing.T) {
 	var (
 		senderPrivKey   = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
 		receiverAddress = cryptography.NewPrivateKey().Public().Address().Bytes()
-		chain           = NewChain(NewMemoryBlockStore(), NewMemoryTxStore(), NewMemoryUTXOStore())
+		chain           = newMemoryChain(t)
 	)
 	block, err := createRandomSignedBlock(chain, senderPrivKey)
 	require.Nil(t, err)
@@ -105,7 +162,7 @@ func TestAddBlockWithTransactions(t *testing.T) {
 			Address: receiverAddress,
 		},
 		{
-			Amount:  genesisBlockAmount - 100,
+			Amount:  genesisBlockAmount - 100 - testTxFee,
 			Address: senderPrivKey.Public().Address().Bytes(),
 		},
 	}
@@ -115,11 +172,8 @@ func TestAddBlockWithTransactions(t *testing.T) {
 		Outputs: outputs,
 	}
 
-	
-	sig := types.CalculateTransactionSignature(senderPrivKey, tx)
-	
-	tx.Inputs[0].Signature = sig.Bytes()
-	
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+
 	block.Transactions = append(block.Transactions, tx)
 	rootHash, err := types.CalculateRootHash(block)
 	require.Nil(t, err)
@@ -134,7 +188,7 @@ func TestAddBlockWithInsufficientFundsTx(t *testing.T) {
 	var (
 		senderPrivKey   = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
 		receiverAddress = cryptography.NewPrivateKey().Public().Address().Bytes()
-		chain           = NewChain(NewMemoryBlockStore(), NewMemoryTxStore(), NewMemoryUTXOStore())
+		chain           = newMemoryChain(t)
 	)
 	block, err := createRandomSignedBlock(chain, senderPrivKey)
 	require.Nil(t, err)
@@ -166,11 +220,748 @@ func TestAddBlockWithInsufficientFundsTx(t *testing.T) {
 		Outputs: outputs,
 	}
 
-	sig := types.CalculateTransactionSignature(senderPrivKey, tx)
-
-	tx.Inputs[0].Signature = sig.Bytes()
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
 
 	block.Transactions = append(block.Transactions, tx)
 	err = chain.AddBlock(block)
 	require.NotNil(t, err)
 }
+
+func TestAddExistingBlock(t *testing.T) {
+	chain := newMemoryChain(t)
+
+	block, err := createRandomSignedBlock(chain, cryptography.NewPrivateKey())
+	require.Nil(t, err)
+
+	require.False(t, chain.HasBlock(types.HashBlockBytes(block)))
+	require.Nil(t, chain.AddBlock(block))
+	require.True(t, chain.HasBlock(types.HashBlockBytes(block)))
+
+	err = chain.AddBlock(block)
+	require.ErrorIs(t, err, ErrBlockExists)
+}
+
+func createRandomSignedBlockOnTop(prevBlock *genproto.Block, privKey cryptography.PrivateKey, txs ...*genproto.Transaction) *genproto.Block {
+	block := random.RandomBlock()
+	block.Header.Height = prevBlock.Header.Height + 1
+	block.Header.PrevHash = types.HashBlockBytes(prevBlock)
+	block.Header.Timestamp = max(block.Header.Timestamp, prevBlock.Header.Timestamp+1)
+	block.Transactions = append([]*genproto.Transaction{
+		createCoinbaseTx(DefaultChainParams(), int(block.Header.Height), privKey),
+	}, txs...)
+
+	types.SignBlock(privKey, block)
+
+	return block
+}
+
+// createCoinbaseTx creates a coinbase transaction that pays the block subsidy to the address of the key.
+func createCoinbaseTx(params *ChainParams, height int, privKey cryptography.PrivateKey) *genproto.Transaction {
+	return types.NewCoinbaseTransaction(int32(height), privKey.Public().Address().Bytes(), params.BlockSubsidy(height))
+}
+
+func createGenesisSpendingTx(t *testing.T, chain *Chain) *genproto.Transaction {
+	senderPrivKey := cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+
+	genesisTx, err := chain.txStore.Get(genesisBlockTx0Hash)
+	require.Nil(t, err)
+
+	tx := &genproto.Transaction{
+		Inputs: []*genproto.TxInput{
+			{
+				PrevTxHash:     types.HashTransactionBytes(genesisTx),
+				PrevTxOutIndex: 0,
+				PublicKey:      senderPrivKey.Public().Bytes(),
+			},
+		},
+		Outputs: []*genproto.TxOutput{
+			{
+				Amount:  genesisBlockAmount - testTxFee,
+				Address: cryptography.NewPrivateKey().Public().Address().Bytes(),
+			},
+		},
+	}
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+
+	return tx
+}
+
+// newUnsignedSpendingTx creates a transaction spending the output of the previous transaction to a new address.
+// The input is unlocked by the caller.
+func newUnsignedSpendingTx(prevTx *genproto.Transaction, outIndex int) *genproto.Transaction {
+	return &genproto.Transaction{
+		Inputs: []*genproto.TxInput{
+			{
+				PrevTxHash:     types.HashTransactionBytes(prevTx),
+				PrevTxOutIndex: uint32(outIndex),
+			},
+		},
+		Outputs: []*genproto.TxOutput{
+			{
+				Amount:  prevTx.Outputs[outIndex].Amount - testTxFee,
+				Address: cryptography.NewPrivateKey().Public().Address().Bytes(),
+			},
+		},
+	}
+}
+
+// newSpendingTx spends the output of the previous transaction into a single output. The input is signed with the key.
+func newSpendingTx(prevTx *genproto.Transaction, outIndex int, privKey cryptography.PrivateKey, output *genproto.TxOutput) *genproto.Transaction {
+	tx := newUnsignedSpendingTx(prevTx, outIndex)
+	tx.Inputs[0].PublicKey = privKey.Public().Bytes()
+	tx.Outputs[0] = output
+	signTestTx(tx, privKey, prevTx.Outputs[outIndex].Amount)
+
+	return tx
+}
+
+// signTestTx signs all inputs of the transaction with the key, the spent amounts are given in the order of the inputs.
+func signTestTx(tx *genproto.Transaction, privKey cryptography.PrivateKey, spentAmounts ...int64) {
+	for idx := range tx.Inputs {
+		if err := types.SignTransactionInput(privKey, tx, idx, spentAmounts, defaultChainID, types.SigHashAll); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestChainReorganization(t *testing.T) {
+	var (
+		chain   = newMemoryChain(t)
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	var orphanedTxs []*genproto.Transaction
+	chain.SetOrphanedTxHandler(func(txs []*genproto.Transaction) {
+		orphanedTxs = append(orphanedTxs, txs...)
+	})
+
+	tx := createGenesisSpendingTx(t, chain)
+	txHash := types.HashTransactionString(tx)
+	genesisUTXOKey := getUTXOKey(genesisBlockTx0Hash, 0)
+
+	// Main chain: genesis -> a1 (spends the genesis output) -> a2
+	a1 := createRandomSignedBlockOnTop(genesisBlock, privKey, tx)
+	require.Nil(t, chain.AddBlock(a1))
+	a2 := createRandomSignedBlockOnTop(a1, privKey)
+	require.Nil(t, chain.AddBlock(a2))
+
+	utxo, err := chain.utxoStore.Get(genesisUTXOKey)
+	require.Nil(t, err)
+	require.True(t, utxo.IsSpent)
+
+	// Side branch: genesis -> b1 -> b2 has the same amount of work and is only stored
+	b1 := createRandomSignedBlockOnTop(genesisBlock, privKey)
+	require.Nil(t, chain.AddBlock(b1))
+	b2 := createRandomSignedBlockOnTop(b1, privKey)
+	require.Nil(t, chain.AddBlock(b2))
+
+	require.Equal(t, 2, chain.Height())
+	require.True(t, chain.HasBlock(types.HashBlockBytes(b2)))
+	_, tipHash := chain.Tip()
+	require.Equal(t, types.HashBlockBytes(a2), tipHash)
+
+	// b3 makes the side branch longer, so the chain switches to it
+	b3 := createRandomSignedBlockOnTop(b2, privKey)
+	require.Nil(t, chain.AddBlock(b3))
+
+	require.Equal(t, 3, chain.Height())
+	for height, block := range []*genproto.Block{b1, b2, b3} {
+		fetchedBlock, err := chain.GetBlockByHeight(height + 1)
+		require.Nil(t, err)
+		require.Equal(t, block, fetchedBlock)
+	}
+
+	utxo, err = chain.utxoStore.Get(genesisUTXOKey)
+	require.Nil(t, err)
+	require.False(t, utxo.IsSpent)
+
+	_, err = chain.utxoStore.Get(getUTXOKey(txHash, 0))
+	require.NotNil(t, err)
+
+	// The other transactions of the disconnected blocks are included in the new branch as well
+	require.Equal(t, []*genproto.Transaction{tx}, orphanedTxs)
+
+	// The orphaned transaction is valid on the new main chain
+	require.Nil(t, chain.ValidateTransaction(tx))
+}
+
+func TestChainReorganizationToInvalidBranch(t *testing.T) {
+	var (
+		chain   = newMemoryChain(t)
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	a1 := createRandomSignedBlockOnTop(genesisBlock, privKey)
+	require.Nil(t, chain.AddBlock(a1))
+
+	// b1 spends the genesis output twice
+	tx := createGenesisSpendingTx(t, chain)
+	b1 := createRandomSignedBlockOnTop(genesisBlock, privKey, tx)
+	require.Nil(t, chain.AddBlock(b1))
+	b2 := createRandomSignedBlockOnTop(b1, privKey, tx)
+	require.NotNil(t, chain.AddBlock(b2))
+
+	// The original main chain is restored
+	require.Equal(t, 1, chain.Height())
+	_, tipHash := chain.Tip()
+	require.Equal(t, types.HashBlockBytes(a1), tipHash)
+
+	utxo, err := chain.utxoStore.Get(getUTXOKey(genesisBlockTx0Hash, 0))
+	require.Nil(t, err)
+	require.False(t, utxo.IsSpent)
+
+	// Blocks extending the invalid branch are rejected
+	b3 := createRandomSignedBlockOnTop(b2, privKey)
+	require.NotNil(t, chain.AddBlock(b3))
+}
+
+func TestAddOrphanBlock(t *testing.T) {
+	chain := newMemoryChain(t)
+
+	block := random.RandomBlock()
+	types.SignBlock(cryptography.NewPrivateKey(), block)
+
+	require.ErrorIs(t, chain.AddBlock(block), ErrOrphanBlock)
+}
+
+func TestDisconnectTip(t *testing.T) {
+	var (
+		chain   = newMemoryChain(t)
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	_, err = chain.DisconnectTip()
+	require.NotNil(t, err)
+
+	tx := createGenesisSpendingTx(t, chain)
+	block := createRandomSignedBlockOnTop(genesisBlock, privKey, tx)
+	require.Nil(t, chain.AddBlock(block))
+
+	undo, err := chain.blockStore.GetUndo(types.HashBlockString(block))
+	require.Nil(t, err)
+	require.Len(t, undo.SpentUTXOs, 1)
+	require.False(t, undo.SpentUTXOs[0].IsSpent)
+	// The outputs of the coinbase and of the spending transaction
+	require.Len(t, undo.CreatedUTXOs, 2)
+
+	disconnected, err := chain.DisconnectTip()
+	require.Nil(t, err)
+	require.Equal(t, block, disconnected)
+	require.Equal(t, 0, chain.Height())
+
+	utxo, err := chain.utxoStore.Get(getUTXOKey(genesisBlockTx0Hash, 0))
+	require.Nil(t, err)
+	require.False(t, utxo.IsSpent)
+
+	for _, key := range undo.CreatedUTXOs {
+		_, err := chain.utxoStore.Get(key)
+		require.NotNil(t, err)
+	}
+
+	require.Nil(t, chain.ValidateTransaction(tx))
+}
+
+func TestAddBlockWithoutCoinbase(t *testing.T) {
+	var (
+		chain   = newMemoryChain(t)
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	block := createRandomSignedBlockOnTop(genesisBlock, privKey)
+	block.Transactions = block.Transactions[1:]
+	block.Transactions = append(block.Transactions, createGenesisSpendingTx(t, chain))
+	types.SignBlock(privKey, block)
+
+	require.NotNil(t, chain.AddBlock(block))
+
+	// A second coinbase transaction is rejected as well
+	block = createRandomSignedBlockOnTop(genesisBlock, privKey, createCoinbaseTx(chain.Params(), 1, cryptography.NewPrivateKey()))
+	require.NotNil(t, chain.AddBlock(block))
+}
+
+func TestAddBlockWithOversizedCoinbase(t *testing.T) {
+	var (
+		chain   = newMemoryChain(t)
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	block := createRandomSignedBlockOnTop(genesisBlock, privKey)
+	block.Transactions[0].Outputs[0].Amount++
+	types.SignBlock(privKey, block)
+	require.NotNil(t, chain.AddBlock(block))
+
+	// The coinbase transaction must commit to the block height
+	block = createRandomSignedBlockOnTop(genesisBlock, privKey)
+	block.Transactions[0].CoinbaseHeight = 2
+	types.SignBlock(privKey, block)
+	require.NotNil(t, chain.AddBlock(block))
+
+	// The coinbase transaction may claim the fees of the block transactions
+	tx := createGenesisSpendingTx(t, chain)
+	block = createRandomSignedBlockOnTop(genesisBlock, privKey, tx)
+	block.Transactions[0].Outputs[0].Amount += testTxFee
+	types.SignBlock(privKey, block)
+	require.Nil(t, chain.AddBlock(block))
+}
+
+func TestCoinbaseMaturity(t *testing.T) {
+	params := DefaultChainParams()
+	params.CoinbaseMaturity = 2
+	params.MinFeeRate = 0
+
+	chain, err := NewChain(params, NewMemoryStore())
+	require.Nil(t, err)
+
+	privKey := cryptography.NewPrivateKey()
+
+	block, err := createRandomSignedBlock(chain, privKey)
+	require.Nil(t, err)
+	require.Nil(t, chain.AddBlock(block))
+
+	coinbaseTx := block.Transactions[0]
+	utxo, err := chain.utxoStore.Get(getUTXOKey(types.HashTransactionString(coinbaseTx), 0))
+	require.Nil(t, err)
+	require.True(t, utxo.IsCoinbase)
+	require.Equal(t, 1, utxo.Height)
+
+	tx := &genproto.Transaction{
+		Inputs: []*genproto.TxInput{
+			{
+				PrevTxHash:     types.HashTransactionBytes(coinbaseTx),
+				PrevTxOutIndex: 0,
+				PublicKey:      privKey.Public().Bytes(),
+			},
+		},
+		Outputs: []*genproto.TxOutput{
+			{
+				Amount:  coinbaseTx.Outputs[0].Amount,
+				Address: cryptography.NewPrivateKey().Public().Address().Bytes(),
+			},
+		},
+	}
+	signTestTx(tx, privKey, coinbaseTx.Outputs[0].Amount)
+
+	// The transaction would be included at height 2
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	block, err = createRandomSignedBlock(chain, privKey)
+	require.Nil(t, err)
+	require.Nil(t, chain.AddBlock(block))
+
+	require.Nil(t, chain.ValidateTransaction(tx))
+
+	// Coinbase transactions are only valid as the first transaction of a block
+	require.NotNil(t, chain.ValidateTransaction(createCoinbaseTx(params, 3, privKey)))
+}
+
+func TestTransactionFee(t *testing.T) {
+	chain := newMemoryChain(t)
+
+	tx := createGenesisSpendingTx(t, chain)
+	fee, err := chain.TransactionFee(tx)
+	require.Nil(t, err)
+	require.Equal(t, int64(testTxFee), fee)
+
+	// Transactions paying less than the minimum fee rate are rejected
+	senderPrivKey := cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+	tx.Outputs[0].Amount = genesisBlockAmount - chain.Params().MinTransactionFee(tx) + 1
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+
+	_, err = chain.TransactionFee(tx)
+	require.NotNil(t, err)
+	require.NotNil(t, chain.ValidateTransaction(tx))
+}
+
+func TestOverflowingOutputAmounts(t *testing.T) {
+	var (
+		chain         = newMemoryChain(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+	)
+
+	// The outputs would sum up to 0 in wrapping int64 arithmetic
+	tx := createGenesisSpendingTx(t, chain)
+	address := tx.Outputs[0].Address
+	tx.Outputs = []*genproto.TxOutput{
+		{Amount: math.MaxInt64, Address: address},
+		{Amount: math.MaxInt64, Address: address},
+		{Amount: 2, Address: address},
+	}
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	block := createRandomSignedBlockOnTop(genesisBlock, senderPrivKey, tx)
+	require.NotNil(t, chain.AddBlock(block))
+	require.Equal(t, 0, chain.Height())
+
+	_, err = addAmounts(math.MaxInt64, 1)
+	require.NotNil(t, err)
+	_, err = addAmounts(1, -1)
+	require.NotNil(t, err)
+}
+
+func TestTransactionSignedForAnotherChain(t *testing.T) {
+	chain := newMemoryChain(t)
+
+	tx := createGenesisSpendingTx(t, chain)
+	require.Nil(t, chain.ValidateTransaction(tx))
+
+	senderPrivKey := cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+	require.Nil(t, types.SignTransactionInput(senderPrivKey, tx, 0, []int64{genesisBlockAmount}, "other", types.SigHashAll))
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	// The signature commits to the spent amount
+	require.Nil(t, types.SignTransactionInput(senderPrivKey, tx, 0, []int64{genesisBlockAmount + 1}, defaultChainID, types.SigHashAll))
+	require.NotNil(t, chain.ValidateTransaction(tx))
+}
+
+func TestSpendingUTXOOfAnotherAddress(t *testing.T) {
+	var (
+		chain         = newMemoryChain(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+		thiefPrivKey  = cryptography.NewPrivateKey()
+		tx            = createGenesisSpendingTx(t, chain)
+	)
+
+	// The input is signed correctly, but by a key that doesn't own the genesis output
+	tx.Inputs[0].PublicKey = thiefPrivKey.Public().Bytes()
+	signTestTx(tx, thiefPrivKey, genesisBlockAmount)
+
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	block := createRandomSignedBlockOnTop(genesisBlock, senderPrivKey, tx)
+	require.NotNil(t, chain.AddBlock(block))
+	require.Equal(t, 0, chain.Height())
+}
+
+func TestTransactionLocks(t *testing.T) {
+	var (
+		chain         = newMemoryChain(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+		ownerPrivKey  = cryptography.NewPrivateKey()
+		now           = time.Now().Unix()
+	)
+
+	prevBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	addBlock := func(timestamp int64, txs ...*genproto.Transaction) {
+		block := createRandomSignedBlockOnTop(prevBlock, senderPrivKey, txs...)
+		block.Header.Timestamp = timestamp
+		types.SignBlock(senderPrivKey, block)
+		require.Nil(t, chain.AddBlock(block))
+		prevBlock = block
+	}
+
+	// The lock time by height is the last height the transaction can't be included at
+	lockTx := createGenesisSpendingTx(t, chain)
+	lockTx.LockTime = 1
+	lockTx.Outputs[0].Address = ownerPrivKey.Public().Address().Bytes()
+	lockTx.Outputs = append(lockTx.Outputs, &genproto.TxOutput{
+		Amount:  lockTx.Outputs[0].Amount / 2,
+		Address: ownerPrivKey.Public().Address().Bytes(),
+	})
+	lockTx.Outputs[0].Amount -= lockTx.Outputs[1].Amount
+	signTestTx(lockTx, senderPrivKey, genesisBlockAmount)
+	require.ErrorIs(t, chain.ValidateTransaction(lockTx), ErrTransactionLocked)
+
+	// The lock time by timestamp is compared with the median time of the previous blocks
+	timeLockTx := proto.Clone(lockTx).(*genproto.Transaction)
+	timeLockTx.LockTime = genesisBlockTimestamp
+	signTestTx(timeLockTx, senderPrivKey, genesisBlockAmount)
+	require.ErrorIs(t, chain.ValidateTransaction(timeLockTx), ErrTransactionLocked)
+
+	addBlock(now)
+	require.Nil(t, chain.ValidateTransaction(timeLockTx))
+	require.Nil(t, chain.ValidateTransaction(lockTx))
+	addBlock(now+1, lockTx)
+
+	spendTx := func(outIndex int, sequence uint32) *genproto.Transaction {
+		tx := newUnsignedSpendingTx(lockTx, outIndex)
+		tx.Inputs[0].PublicKey = ownerPrivKey.Public().Bytes()
+		tx.Inputs[0].Sequence = sequence
+		signTestTx(tx, ownerPrivKey, lockTx.Outputs[outIndex].Amount)
+		return tx
+	}
+
+	blocksSequence, err := types.SequenceLockBlocks(2)
+	require.Nil(t, err)
+	timeSequence, err := types.SequenceLockDuration(10 * time.Minute)
+	require.Nil(t, err)
+
+	blocksLockedTx := spendTx(0, blocksSequence)
+	timeLockedTx := spendTx(1, timeSequence)
+	require.ErrorIs(t, chain.ValidateTransaction(blocksLockedTx), ErrTransactionLocked)
+	require.ErrorIs(t, chain.ValidateTransaction(timeLockedTx), ErrTransactionLocked)
+
+	// Disabled relative locks are ignored
+	require.Nil(t, chain.ValidateTransaction(spendTx(1, types.SequenceLockDisabled|timeSequence)))
+
+	addBlock(now + 1100)
+	require.Nil(t, chain.ValidateTransaction(blocksLockedTx))
+	require.ErrorIs(t, chain.ValidateTransaction(timeLockedTx), ErrTransactionLocked)
+
+	// The median time passes the lock once most of the recent blocks are after it
+	addBlock(now + 1101)
+	addBlock(now + 1102)
+	require.Nil(t, chain.ValidateTransaction(timeLockedTx))
+
+	addBlock(now+1103, blocksLockedTx, timeLockedTx)
+}
+
+func TestBlockHeaderRules(t *testing.T) {
+	var (
+		chain   = newMemoryChain(t)
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	createBlock := func(modify func(header *genproto.BlockHeader)) *genproto.Block {
+		block, err := createRandomSignedBlock(chain, privKey)
+		require.Nil(t, err)
+
+		modify(block.Header)
+		types.SignBlock(privKey, block)
+
+		return block
+	}
+
+	block := createBlock(func(header *genproto.BlockHeader) { header.Height = 2 })
+	require.ErrorIs(t, chain.AddBlock(block), ErrBadBlockHeight)
+
+	block = createBlock(func(header *genproto.BlockHeader) { header.Version = blockVersion + 1 })
+	require.ErrorIs(t, chain.AddBlock(block), ErrUnsupportedBlockVersion)
+
+	block = createBlock(func(header *genproto.BlockHeader) { header.Timestamp = genesisBlockTimestamp })
+	require.ErrorIs(t, chain.AddBlock(block), ErrBlockTooOld)
+
+	block = createBlock(func(header *genproto.BlockHeader) {
+		header.Timestamp = time.Now().Add(maxBlockTimeOffset + time.Minute).Unix()
+	})
+	require.ErrorIs(t, chain.AddBlock(block), ErrBlockTooNew)
+
+	// The timestamp has to be after the median time of the last blocks, not after the parent timestamp
+	var timestamps []int64
+	for i := 0; i < medianTimeBlocks; i++ {
+		block := createBlock(func(header *genproto.BlockHeader) { header.Timestamp = genesisBlockTimestamp + int64(i+1)*10 })
+		require.Nil(t, chain.AddBlock(block))
+		timestamps = append(timestamps, block.Header.Timestamp)
+	}
+
+	medianTime := timestamps[len(timestamps)/2]
+	block = createBlock(func(header *genproto.BlockHeader) { header.Timestamp = medianTime })
+	require.ErrorIs(t, chain.AddBlock(block), ErrBlockTooOld)
+
+	block = createBlock(func(header *genproto.BlockHeader) { header.Timestamp = medianTime + 1 })
+	require.Nil(t, chain.AddBlock(block))
+
+	// The block template always meets the rules
+	block = chain.NewBlockTemplate(nil, privKey.Public().Address().Bytes())
+	types.SignBlock(privKey, block)
+	require.Nil(t, chain.AddBlock(block))
+}
+
+func TestBlockLimits(t *testing.T) {
+	var (
+		params        = DefaultChainParams()
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+		privKey       = cryptography.NewPrivateKey()
+	)
+
+	params.MaxBlockTransactions = 3
+	chain, err := NewChain(params, NewMemoryStore())
+	require.Nil(t, err)
+
+	// Split the genesis output, so there are several independent transactions
+	splitTx := createGenesisSpendingTx(t, chain)
+	splitTx.Outputs = nil
+	for i := 0; i < 3; i++ {
+		splitTx.Outputs = append(splitTx.Outputs, &genproto.TxOutput{
+			Amount:  (genesisBlockAmount - testTxFee) / 3,
+			Address: senderPrivKey.Public().Address().Bytes(),
+		})
+	}
+	signTestTx(splitTx, senderPrivKey, genesisBlockAmount)
+
+	block := chain.NewBlockTemplate([]*genproto.Transaction{splitTx}, privKey.Public().Address().Bytes())
+	types.SignBlock(privKey, block)
+	require.Nil(t, chain.AddBlock(block))
+
+	txs := make([]*genproto.Transaction, 3)
+	for i := range txs {
+		txs[i] = newSpendingTx(splitTx, i, senderPrivKey, &genproto.TxOutput{
+			Amount:  splitTx.Outputs[i].Amount - testTxFee,
+			Address: privKey.Public().Address().Bytes(),
+		})
+		require.Nil(t, chain.ValidateTransaction(txs[i]))
+	}
+
+	// The coinbase transaction counts towards the limit
+	block = chain.NewBlockTemplate(txs, privKey.Public().Address().Bytes())
+	require.Len(t, block.Transactions, 3)
+
+	block.Transactions = append(block.Transactions, txs[2])
+	types.SignBlock(privKey, block)
+	require.NotNil(t, chain.AddBlock(block))
+
+	// The template leaves out the transactions exceeding the signature check limit
+	params.MaxBlockTransactions = defaultMaxBlockTxs
+	params.MaxBlockSigOps = 2
+
+	block = chain.NewBlockTemplate(txs, privKey.Public().Address().Bytes())
+	require.Len(t, block.Transactions, 3)
+
+	block.Transactions = append(block.Transactions, txs[2])
+	types.SignBlock(privKey, block)
+	require.NotNil(t, chain.AddBlock(block))
+
+	// The template leaves out the transactions exceeding the size limit, including the signature of the block
+	params.MaxBlockSigOps = defaultMaxBlockSigOps
+	block = chain.NewBlockTemplate(txs[:2], privKey.Public().Address().Bytes())
+	types.SignBlock(privKey, block)
+	// The template reserves space for the largest coinbase amount and nonce
+	params.MaxBlockSize = proto.Size(block) + 32
+
+	block = chain.NewBlockTemplate(txs, privKey.Public().Address().Bytes())
+	require.Len(t, block.Transactions, 3)
+
+	block.Transactions = append(block.Transactions, txs[2])
+	types.SignBlock(privKey, block)
+	require.NotNil(t, chain.AddBlock(block))
+
+	block = chain.NewBlockTemplate(txs, privKey.Public().Address().Bytes())
+	types.SignBlock(privKey, block)
+	require.Nil(t, chain.AddBlock(block))
+}
+
+func TestIntraBlockSpends(t *testing.T) {
+	var (
+		chain         = newMemoryChain(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+		privKey       = cryptography.NewPrivateKey()
+	)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	tx := createGenesisSpendingTx(t, chain)
+	tx.Outputs[0].Address = privKey.Public().Address().Bytes()
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+
+	conflictingTx := createGenesisSpendingTx(t, chain)
+
+	chainedTx := newSpendingTx(tx, 0, privKey, &genproto.TxOutput{
+		Amount:  tx.Outputs[0].Amount - testTxFee,
+		Address: cryptography.NewPrivateKey().Public().Address().Bytes(),
+	})
+
+	// Chained spends are only valid once the spent output is in the block
+	require.NotNil(t, chain.ValidateTransaction(chainedTx))
+
+	// Two transactions spending the same output
+	block := createRandomSignedBlockOnTop(genesisBlock, privKey, tx, conflictingTx)
+	require.NotNil(t, chain.AddBlock(block))
+
+	// The same transaction twice
+	block = createRandomSignedBlockOnTop(genesisBlock, privKey, tx, tx)
+	require.NotNil(t, chain.AddBlock(block))
+
+	// A transaction spending the same output twice
+	doubleInputTx := createGenesisSpendingTx(t, chain)
+	doubleInputTx.Inputs = append(doubleInputTx.Inputs, proto.Clone(doubleInputTx.Inputs[0]).(*genproto.TxInput))
+	doubleInputTx.Outputs[0].Amount = 2*genesisBlockAmount - testTxFee
+	signTestTx(doubleInputTx, senderPrivKey, genesisBlockAmount, genesisBlockAmount)
+
+	block = createRandomSignedBlockOnTop(genesisBlock, privKey, doubleInputTx)
+	require.NotNil(t, chain.AddBlock(block))
+
+	// The spending transaction has to come after the spent one
+	block = createRandomSignedBlockOnTop(genesisBlock, privKey, chainedTx, tx)
+	require.NotNil(t, chain.AddBlock(block))
+
+	// The template leaves out the conflicting transaction and keeps the chained spend
+	block = chain.NewBlockTemplate([]*genproto.Transaction{tx, conflictingTx, chainedTx, tx}, privKey.Public().Address().Bytes())
+	require.Equal(t, []*genproto.Transaction{tx, chainedTx}, block.Transactions[1:])
+
+	types.SignBlock(privKey, block)
+	require.Nil(t, chain.AddBlock(block))
+
+	utxo, err := chain.utxoStore.Get(getUTXOKey(types.HashTransactionString(tx), 0))
+	require.Nil(t, err)
+	require.True(t, utxo.IsSpent)
+
+	// Disconnecting the block removes the outputs created and spent within the block
+	_, err = chain.DisconnectTip()
+	require.Nil(t, err)
+
+	_, err = chain.utxoStore.Get(getUTXOKey(types.HashTransactionString(tx), 0))
+	require.NotNil(t, err)
+}
+
+func TestConnectInvalidBlockLeavesStateUntouched(t *testing.T) {
+	var (
+		path      = filepath.Join(t.TempDir(), "chain.db")
+		diskStore = openTestDiskStore(t, path)
+		privKey   = cryptography.NewPrivateKey()
+	)
+	defer diskStore.Close()
+
+	memoryChain := newMemoryChain(t)
+	diskChain, err := NewChain(DefaultChainParams(), diskStore)
+	require.Nil(t, err)
+
+	for _, chain := range []*Chain{memoryChain, diskChain} {
+		genesisBlock, err := chain.GetBlockByHeight(0)
+		require.Nil(t, err)
+
+		// The first transaction is valid, the second one spends an unknown output
+		tx := createGenesisSpendingTx(t, chain)
+		invalidTx := &genproto.Transaction{
+			Inputs: []*genproto.TxInput{
+				{PrevTxHash: types.HashTransactionBytes(genesisBlock.Transactions[0]), PrevTxOutIndex: 5},
+			},
+			Outputs: []*genproto.TxOutput{{Amount: 1}},
+		}
+		block := createRandomSignedBlockOnTop(genesisBlock, privKey, tx, invalidTx)
+
+		require.NotNil(t, chain.connectBlock(block))
+		require.Equal(t, 0, chain.Height())
+
+		tip, err := chain.blockStore.GetTip()
+		require.Nil(t, err)
+		require.Equal(t, types.HashBlockString(genesisBlock), tip)
+
+		utxo, err := chain.utxoStore.Get(getUTXOKey(genesisBlockTx0Hash, 0))
+		require.Nil(t, err)
+		require.False(t, utxo.IsSpent)
+
+		_, err = chain.utxoStore.Get(getUTXOKey(types.HashTransactionString(tx), 0))
+		require.NotNil(t, err)
+
+		_, err = chain.txStore.Get(types.HashTransactionString(tx))
+		require.NotNil(t, err)
+
+		_, err = chain.blockStore.Get(types.HashBlockString(block))
+		require.NotNil(t, err)
+
+		require.Nil(t, chain.ValidateTransaction(tx))
+	}
+}
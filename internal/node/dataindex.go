@@ -0,0 +1,77 @@
+package node
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+)
+
+// DataIndex contains the payloads of the data outputs of the main chain transactions by their transaction hashes.
+// It is rebuilt from the blocks of the main chain when the chain is loaded.
+type DataIndex struct {
+	payloads map[string][][]byte
+}
+
+func newDataIndex() *DataIndex {
+	return &DataIndex{
+		payloads: make(map[string][][]byte),
+	}
+}
+
+// add indexes the data outputs of the transaction of a connected block.
+func (di *DataIndex) add(tx *genproto.Transaction) {
+	if payloads := types.DataPayloads(tx); len(payloads) > 0 {
+		di.payloads[types.HashTransactionString(tx)] = payloads
+	}
+}
+
+// remove drops the data outputs of the transaction of a disconnected block from the index.
+func (di *DataIndex) remove(tx *genproto.Transaction) {
+	delete(di.payloads, types.HashTransactionString(tx))
+}
+
+// GetDataPayloads returns the payloads of the data outputs of the main chain transaction with the given hash.
+func (c *Chain) GetDataPayloads(txHash []byte) ([][]byte, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	payloads, ok := c.dataIndex.payloads[hex.EncodeToString(txHash)]
+	if !ok {
+		return nil, fmt.Errorf("transaction [%x] with data outputs is not found in the main chain", txHash)
+	}
+
+	return payloads, nil
+}
+
+// checkDataOutputs checks that the transaction has at most one data output, which carries a payload of up to
+// types.MaxDataSize bytes and nothing else, and that the other outputs carry no payload.
+func checkDataOutputs(tx *genproto.Transaction) error {
+	hash := types.HashTransactionString(tx)
+	dataOutputs := 0
+
+	for idx, output := range tx.Outputs {
+		if output.Type != genproto.OutputType_DATA {
+			if len(output.Data) != 0 {
+				return fmt.Errorf("output %d of transaction with hash %s has data, but is not a data output", idx, hash)
+			}
+			continue
+		}
+
+		if dataOutputs++; dataOutputs > types.MaxDataOutputs {
+			return fmt.Errorf("transaction with hash %s has more than %d data outputs", hash, types.MaxDataOutputs)
+		}
+
+		if output.Amount != 0 || len(output.Address) != 0 || len(output.LockScript) != 0 {
+			return fmt.Errorf("data output %d of transaction with hash %s has an amount, an address or a lock script", idx, hash)
+		}
+
+		if len(output.Data) == 0 || len(output.Data) > types.MaxDataSize {
+			return fmt.Errorf("data output %d of transaction with hash %s has %d bytes, expected from 1 to %d",
+				idx, hash, len(output.Data), types.MaxDataSize)
+		}
+	}
+
+	return nil
+}
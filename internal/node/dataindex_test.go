@@ -0,0 +1,108 @@
+package node
+
+import (
+	"crypto/sha256"
+	"path/filepath"
+	"testing"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+// createDataTx creates a transaction spending the genesis output with a data output carrying the data.
+func createDataTx(t *testing.T, chain *Chain, data []byte) *genproto.Transaction {
+	spendingTx := createGenesisSpendingTx(t, chain)
+
+	tx, err := types.NewDataTransaction(spendingTx.Inputs, spendingTx.Outputs, data)
+	require.Nil(t, err)
+	signTestTx(tx, cryptography.NewPrivateKeyFromString(genesisBlockSeed), genesisBlockAmount)
+
+	return tx
+}
+
+func TestDataOutput(t *testing.T) {
+	var (
+		path    = filepath.Join(t.TempDir(), "chain.db")
+		store   = openTestDiskStore(t, path)
+		privKey = cryptography.NewPrivateKey()
+		anchor  = sha256.Sum256([]byte("audit log"))
+	)
+
+	chain, err := NewChain(DefaultChainParams(), store)
+	require.Nil(t, err)
+
+	tx := createDataTx(t, chain, anchor[:])
+	txHash := types.HashTransactionBytes(tx)
+	require.Nil(t, chain.ValidateTransaction(tx))
+
+	_, err = chain.GetDataPayloads(txHash)
+	require.NotNil(t, err)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	block := createRandomSignedBlockOnTop(genesisBlock, privKey, tx)
+	require.Nil(t, chain.AddBlock(block))
+
+	payloads, err := chain.GetDataPayloads(txHash)
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{anchor[:]}, payloads)
+
+	// Data outputs are never added to the UTXO set, so they can't be spent
+	_, err = chain.utxoStore.Get(getUTXOKey(types.HashTransactionString(tx), 1))
+	require.NotNil(t, err)
+
+	spendingTx := newUnsignedSpendingTx(tx, 1)
+	spendingTx.Outputs[0].Amount = 0
+	require.NotNil(t, chain.ValidateTransaction(spendingTx))
+
+	// The index is rebuilt when the chain is loaded
+	require.Nil(t, store.Close())
+	store = openTestDiskStore(t, path)
+	defer store.Close()
+
+	chain, err = NewChain(DefaultChainParams(), store)
+	require.Nil(t, err)
+
+	payloads, err = chain.GetDataPayloads(txHash)
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{anchor[:]}, payloads)
+
+	_, err = chain.DisconnectTip()
+	require.Nil(t, err)
+	_, err = chain.GetDataPayloads(txHash)
+	require.NotNil(t, err)
+}
+
+func TestDataOutputRules(t *testing.T) {
+	var (
+		chain         = newMemoryChain(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+	)
+
+	for _, modify := range []func(output *genproto.TxOutput){
+		func(output *genproto.TxOutput) { output.Amount = 1 },
+		func(output *genproto.TxOutput) { output.Address = senderPrivKey.Public().Address().Bytes() },
+		func(output *genproto.TxOutput) { output.Data = nil },
+		func(output *genproto.TxOutput) { output.Data = make([]byte, types.MaxDataSize+1) },
+		func(output *genproto.TxOutput) { output.Type = genproto.OutputType_TRANSFER },
+	} {
+		tx := createDataTx(t, chain, []byte("data"))
+		modify(tx.Outputs[1])
+		signTestTx(tx, senderPrivKey, genesisBlockAmount)
+		require.NotNil(t, chain.ValidateTransaction(tx))
+	}
+
+	// A transaction carries one data output at most
+	tx := createDataTx(t, chain, []byte("data"))
+	dataOutput, err := types.NewDataOutput([]byte("more data"))
+	require.Nil(t, err)
+	tx.Outputs = append(tx.Outputs, dataOutput)
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	tx.Outputs = tx.Outputs[:len(tx.Outputs)-1]
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+	require.Nil(t, chain.ValidateTransaction(tx))
+}
@@ -0,0 +1,342 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	blocksBucket = []byte("blocks")
+	undoBucket   = []byte("undo")
+	txsBucket    = []byte("txs")
+	utxosBucket  = []byte("utxos")
+	metaBucket   = []byte("meta")
+
+	tipKey       = []byte("tip")
+	finalizedKey = []byte("finalized")
+)
+
+// DiskStore is a durable storage of blocks, transactions and UTXOs backed by a single embedded bbolt database file.
+// Every write is committed in its own database transaction, which is fsynced to disk before the write returns,
+// so the stored data survives crashes. The changes of a block are committed in a single database transaction.
+type DiskStore struct {
+	db *bolt.DB
+}
+
+// OpenDiskStore opens the database file at the given path, creating it if it doesn't exist.
+// The consistency of the database and of the stored chain tip is checked before the store is returned.
+func OpenDiskStore(path string) (*DiskStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{blocksBucket, undoBucket, txsBucket, utxosBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create database buckets: %w", err)
+	}
+
+	store := &DiskStore{db: db}
+
+	if err := store.recover(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("database %s failed the recovery check: %w", path, err)
+	}
+
+	return store, nil
+}
+
+// recover checks the integrity of the database pages and that the blocks of the stored chain tip are complete.
+func (s *DiskStore) recover() error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		var errs []error
+		for err := range tx.Check() {
+			errs = append(errs, err)
+		}
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+
+		tip := tx.Bucket(metaBucket).Get(tipKey)
+		if tip == nil {
+			return nil
+		}
+
+		if tx.Bucket(blocksBucket).Get(tip) == nil {
+			return fmt.Errorf("tip block [%s] is not found", tip)
+		}
+
+		if tx.Bucket(undoBucket).Get(tip) == nil {
+			return fmt.Errorf("undo record of tip block [%s] is not found", tip)
+		}
+
+		return nil
+	})
+}
+
+func (s *DiskStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *DiskStore) BlockStore() BlockStore {
+	return &DiskBlockStore{db: s.db}
+}
+
+func (s *DiskStore) TxStore() TxStore {
+	return &DiskTxStore{db: s.db}
+}
+
+func (s *DiskStore) UTXOStore() UTXOStore {
+	return &DiskUTXOStore{db: s.db}
+}
+
+// Commit writes the changes of the block in a single database transaction, so after a crash the database
+// contains either all of them or none.
+func (s *DiskStore) Commit(changes *BlockChanges) error {
+	var (
+		txs   = make(map[string][]byte)
+		utxos = make(map[string][]byte, len(changes.UTXOs.Put))
+		hash  []byte
+		block []byte
+		undo  []byte
+		err   error
+	)
+
+	if changes.Block != nil {
+		for _, tx := range changes.Block.Transactions {
+			if txs[types.HashTransactionString(tx)], err = proto.Marshal(tx); err != nil {
+				return err
+			}
+		}
+
+		hash = []byte(types.HashBlockString(changes.Block))
+		if block, err = proto.Marshal(changes.Block); err != nil {
+			return err
+		}
+		if undo, err = json.Marshal(changes.Undo); err != nil {
+			return err
+		}
+	}
+
+	for _, utxo := range changes.UTXOs.Put {
+		if utxos[getUTXOKey(utxo.Hash, utxo.OutIndex)], err = json.Marshal(utxo); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for key, value := range txs {
+			if err := tx.Bucket(txsBucket).Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+
+		if hash != nil {
+			if err := tx.Bucket(blocksBucket).Put(hash, block); err != nil {
+				return err
+			}
+			if err := tx.Bucket(undoBucket).Put(hash, undo); err != nil {
+				return err
+			}
+		}
+
+		for key, value := range utxos {
+			if err := tx.Bucket(utxosBucket).Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+
+		for _, key := range changes.UTXOs.Delete {
+			if err := tx.Bucket(utxosBucket).Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+
+		return tx.Bucket(metaBucket).Put(tipKey, []byte(changes.Tip))
+	})
+}
+
+func (s *DiskStore) get(bucket []byte, key string) ([]byte, error) {
+	var value []byte
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// The returned value is only valid during the transaction
+		if v := tx.Bucket(bucket).Get([]byte(key)); v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+
+	return value, err
+}
+
+func (s *DiskStore) put(bucket []byte, key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), value)
+	})
+}
+
+//-----------------------------------------------------------------------------
+//  DiskTxStore
+//-----------------------------------------------------------------------------
+
+type DiskTxStore DiskStore
+
+func (s *DiskTxStore) Get(hash string) (*genproto.Transaction, error) {
+	b, err := (*DiskStore)(s).get(txsBucket, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if b == nil {
+		return nil, fmt.Errorf("transaction [%s] is not found", hash)
+	}
+
+	tx := new(genproto.Transaction)
+	if err := proto.Unmarshal(b, tx); err != nil {
+		return nil, fmt.Errorf("failed to decode transaction [%s]: %w", hash, err)
+	}
+
+	return tx, nil
+}
+
+func (s *DiskTxStore) Put(tx *genproto.Transaction) error {
+	b, err := proto.Marshal(tx)
+	if err != nil {
+		return err
+	}
+
+	return (*DiskStore)(s).put(txsBucket, types.HashTransactionString(tx), b)
+}
+
+//-----------------------------------------------------------------------------
+//  DiskUTXOStore
+//-----------------------------------------------------------------------------
+
+type DiskUTXOStore DiskStore
+
+func (s *DiskUTXOStore) Get(hash string) (*UTXO, error) {
+	b, err := (*DiskStore)(s).get(utxosBucket, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if b == nil {
+		return nil, fmt.Errorf("utxo [%s] is not found", hash)
+	}
+
+	utxo := new(UTXO)
+	if err := json.Unmarshal(b, utxo); err != nil {
+		return nil, fmt.Errorf("failed to decode utxo [%s]: %w", hash, err)
+	}
+
+	return utxo, nil
+}
+
+func (s *DiskUTXOStore) Put(utxo *UTXO) error {
+	b, err := json.Marshal(utxo)
+	if err != nil {
+		return err
+	}
+
+	return (*DiskStore)(s).put(utxosBucket, getUTXOKey(utxo.Hash, utxo.OutIndex), b)
+}
+
+func (s *DiskUTXOStore) Delete(hash string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(utxosBucket).Delete([]byte(hash))
+	})
+}
+
+//-----------------------------------------------------------------------------
+//  DiskBlockStore
+//-----------------------------------------------------------------------------
+
+type DiskBlockStore DiskStore
+
+func (s *DiskBlockStore) Put(block *genproto.Block) error {
+	b, err := proto.Marshal(block)
+	if err != nil {
+		return err
+	}
+
+	return (*DiskStore)(s).put(blocksBucket, types.HashBlockString(block), b)
+}
+
+func (s *DiskBlockStore) Get(hash string) (*genproto.Block, error) {
+	b, err := (*DiskStore)(s).get(blocksBucket, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if b == nil {
+		return nil, fmt.Errorf("block [%s] is not found", hash)
+	}
+
+	block := new(genproto.Block)
+	if err := proto.Unmarshal(b, block); err != nil {
+		return nil, fmt.Errorf("failed to decode block [%s]: %w", hash, err)
+	}
+
+	return block, nil
+}
+
+func (s *DiskBlockStore) PutUndo(hash string, undo *BlockUndo) error {
+	b, err := json.Marshal(undo)
+	if err != nil {
+		return err
+	}
+
+	return (*DiskStore)(s).put(undoBucket, hash, b)
+}
+
+func (s *DiskBlockStore) GetUndo(hash string) (*BlockUndo, error) {
+	b, err := (*DiskStore)(s).get(undoBucket, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if b == nil {
+		return nil, fmt.Errorf("undo record of block [%s] is not found", hash)
+	}
+
+	undo := new(BlockUndo)
+	if err := json.Unmarshal(b, undo); err != nil {
+		return nil, fmt.Errorf("failed to decode undo record of block [%s]: %w", hash, err)
+	}
+
+	return undo, nil
+}
+
+func (s *DiskBlockStore) PutTip(hash string) error {
+	return (*DiskStore)(s).put(metaBucket, string(tipKey), []byte(hash))
+}
+
+func (s *DiskBlockStore) GetTip() (string, error) {
+	b, err := (*DiskStore)(s).get(metaBucket, string(tipKey))
+	return string(b), err
+}
+
+func (s *DiskBlockStore) PutFinalized(hash string) error {
+	return (*DiskStore)(s).put(metaBucket, string(finalizedKey), []byte(hash))
+}
+
+func (s *DiskBlockStore) GetFinalized() (string, error) {
+	b, err := (*DiskStore)(s).get(metaBucket, string(finalizedKey))
+	return string(b), err
+}
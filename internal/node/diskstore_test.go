@@ -0,0 +1,204 @@
+package node
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/random"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestDiskStore(t *testing.T, path string) *DiskStore {
+	store, err := OpenDiskStore(path)
+	require.Nil(t, err)
+
+	return store
+}
+
+func TestDiskStore(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "chain.db")
+		store = openTestDiskStore(t, path)
+		block = random.RandomBlock()
+		tx    = block.Transactions[0]
+		utxo  = NewUTXO(types.HashTransactionString(tx), 0, 10)
+		undo  = &BlockUndo{SpentUTXOs: []*UTXO{utxo}, CreatedUTXOs: []string{"key"}}
+		hash  = types.HashBlockString(block)
+	)
+
+	require.Nil(t, store.BlockStore().Put(block))
+	require.Nil(t, store.BlockStore().PutUndo(hash, undo))
+	require.Nil(t, store.BlockStore().PutTip(hash))
+	require.Nil(t, store.TxStore().Put(tx))
+	require.Nil(t, store.UTXOStore().Put(utxo))
+	require.Nil(t, store.Close())
+
+	// Reopen the database to check the data is persisted
+	store = openTestDiskStore(t, path)
+	defer store.Close()
+
+	fetchedBlock, err := store.BlockStore().Get(hash)
+	require.Nil(t, err)
+	require.Equal(t, types.HashBlockBytes(block), types.HashBlockBytes(fetchedBlock))
+
+	fetchedUndo, err := store.BlockStore().GetUndo(hash)
+	require.Nil(t, err)
+	require.Equal(t, undo, fetchedUndo)
+
+	tip, err := store.BlockStore().GetTip()
+	require.Nil(t, err)
+	require.Equal(t, hash, tip)
+
+	fetchedTx, err := store.TxStore().Get(types.HashTransactionString(tx))
+	require.Nil(t, err)
+	require.Equal(t, types.HashTransactionString(tx), types.HashTransactionString(fetchedTx))
+
+	key := getUTXOKey(utxo.Hash, utxo.OutIndex)
+	fetchedUTXO, err := store.UTXOStore().Get(key)
+	require.Nil(t, err)
+	require.Equal(t, utxo, fetchedUTXO)
+
+	require.Nil(t, store.UTXOStore().Delete(key))
+	_, err = store.UTXOStore().Get(key)
+	require.NotNil(t, err)
+
+	_, err = store.BlockStore().Get("unknown")
+	require.NotNil(t, err)
+}
+
+func TestDiskStoreRecoveryCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chain.db")
+	store := openTestDiskStore(t, path)
+
+	// Tip without the block
+	require.Nil(t, store.BlockStore().PutTip("missing"))
+	require.Nil(t, store.Close())
+
+	_, err := OpenDiskStore(path)
+	require.NotNil(t, err)
+}
+
+func TestChainRestartFromDisk(t *testing.T) {
+	var (
+		path    = filepath.Join(t.TempDir(), "chain.db")
+		store   = openTestDiskStore(t, path)
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	chain, err := NewChain(DefaultChainParams(), store)
+	require.Nil(t, err)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	tx := createGenesisSpendingTx(t, chain)
+	block := createRandomSignedBlockOnTop(genesisBlock, privKey, tx)
+	require.Nil(t, chain.AddBlock(block))
+	require.Nil(t, store.Close())
+
+	store = openTestDiskStore(t, path)
+	defer store.Close()
+
+	chain, err = NewChain(DefaultChainParams(), store)
+	require.Nil(t, err)
+	require.Equal(t, 1, chain.Height())
+
+	_, tipHash := chain.Tip()
+	require.Equal(t, types.HashBlockBytes(block), tipHash)
+
+	utxo, err := chain.utxoStore.Get(getUTXOKey(genesisBlockTx0Hash, 0))
+	require.Nil(t, err)
+	require.True(t, utxo.IsSpent)
+
+	// The loaded chain can be extended and rolled back
+	nextBlock := createRandomSignedBlockOnTop(block, privKey)
+	require.Nil(t, chain.AddBlock(nextBlock))
+
+	_, err = chain.DisconnectTip()
+	require.Nil(t, err)
+	_, err = chain.DisconnectTip()
+	require.Nil(t, err)
+
+	utxo, err = chain.utxoStore.Get(getUTXOKey(genesisBlockTx0Hash, 0))
+	require.Nil(t, err)
+	require.False(t, utxo.IsSpent)
+}
+
+func TestDiskStoreCommit(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "chain.db")
+		store = openTestDiskStore(t, path)
+		block = random.RandomBlock()
+		hash  = types.HashBlockString(block)
+		tx    = block.Transactions[0]
+		undo  = &BlockUndo{SpentUTXOs: []*UTXO{}, CreatedUTXOs: []string{}}
+		utxo1 = NewUTXO(types.HashTransactionString(tx), 0, 10)
+		utxo2 = NewUTXO(types.HashTransactionString(tx), 1, 20)
+	)
+	defer store.Close()
+
+	// Connect
+	require.Nil(t, store.Commit(&BlockChanges{
+		Block: block,
+		Undo:  undo,
+		UTXOs: &UTXOBatch{Put: []*UTXO{utxo1, utxo2}},
+		Tip:   hash,
+	}))
+
+	_, err := store.BlockStore().Get(hash)
+	require.Nil(t, err)
+
+	fetchedUndo, err := store.BlockStore().GetUndo(hash)
+	require.Nil(t, err)
+	require.Equal(t, undo, fetchedUndo)
+
+	_, err = store.TxStore().Get(types.HashTransactionString(tx))
+	require.Nil(t, err)
+
+	tip, err := store.BlockStore().GetTip()
+	require.Nil(t, err)
+	require.Equal(t, hash, tip)
+
+	// Disconnect
+	utxo1.IsSpent = true
+	key2 := getUTXOKey(utxo2.Hash, utxo2.OutIndex)
+	require.Nil(t, store.Commit(&BlockChanges{
+		UTXOs: &UTXOBatch{Put: []*UTXO{utxo1}, Delete: []string{key2}},
+		Tip:   "prev",
+	}))
+
+	fetchedUTXO, err := store.UTXOStore().Get(getUTXOKey(utxo1.Hash, utxo1.OutIndex))
+	require.Nil(t, err)
+	require.True(t, fetchedUTXO.IsSpent)
+
+	_, err = store.UTXOStore().Get(key2)
+	require.NotNil(t, err)
+
+	tip, err = store.BlockStore().GetTip()
+	require.Nil(t, err)
+	require.Equal(t, "prev", tip)
+
+	// A failed commit writes nothing
+	nextBlock := random.RandomBlock()
+	tooLongKey := NewUTXO(strings.Repeat("0", bolt.MaxKeySize), 0, 1)
+	require.NotNil(t, store.Commit(&BlockChanges{
+		Block: nextBlock,
+		Undo:  undo,
+		UTXOs: &UTXOBatch{Put: []*UTXO{tooLongKey}, Delete: []string{getUTXOKey(utxo1.Hash, utxo1.OutIndex)}},
+		Tip:   types.HashBlockString(nextBlock),
+	}))
+
+	_, err = store.BlockStore().Get(types.HashBlockString(nextBlock))
+	require.NotNil(t, err)
+
+	_, err = store.UTXOStore().Get(getUTXOKey(utxo1.Hash, utxo1.OutIndex))
+	require.Nil(t, err)
+
+	tip, err = store.BlockStore().GetTip()
+	require.Nil(t, err)
+	require.Equal(t, "prev", tip)
+}
@@ -0,0 +1,441 @@
+package node
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+)
+
+//-----------------------------------------------------------------------------
+//  Chain finalization
+//-----------------------------------------------------------------------------
+
+// FinalizedHeight returns the height of the last finalized block. The main chain is never reorganized below it.
+func (c *Chain) FinalizedHeight() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.finalized.height
+}
+
+// FinalizeBlock marks the main chain block with the given hash and all its ancestors as final.
+// Finalizing a block below the finalized height has no effect.
+func (c *Chain) FinalizeBlock(hash []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	node, ok := c.blockIndex.Get(hex.EncodeToString(hash))
+	if !ok || node.height > c.blockHeaders.Height() ||
+		!bytes.Equal(types.HashBlockHeader(c.blockHeaders.Get(node.height)), hash) {
+		return fmt.Errorf("block with hash %x is not a part of the main chain", hash)
+	}
+
+	if node.height <= c.finalized.height {
+		return nil
+	}
+
+	if err := c.blockStore.PutFinalized(node.hash); err != nil {
+		return fmt.Errorf("failed to put finalized block into store: %w", err)
+	}
+
+	c.finalized = node
+	return nil
+}
+
+// BlockHeight returns the height of the known block with the given hash, which is either a part of the main chain
+// or of a side branch. False is returned if the block is unknown.
+func (c *Chain) BlockHeight(hash []byte) (int, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	node, ok := c.blockIndex.Get(hex.EncodeToString(hash))
+	if !ok {
+		return 0, false
+	}
+
+	return node.height, true
+}
+
+// IsAncestor reports whether the block with the hash ancestorHash is the known block with the given hash
+// or one of its ancestors.
+func (c *Chain) IsAncestor(ancestorHash, hash []byte) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	ancestor, ok := c.blockIndex.Get(hex.EncodeToString(ancestorHash))
+	if !ok {
+		return false
+	}
+
+	node, ok := c.blockIndex.Get(hex.EncodeToString(hash))
+	return ok && node.ancestor(ancestor.height) == ancestor
+}
+
+// loadFinalized restores the finalized block of the loaded main chain.
+func (c *Chain) loadFinalized() error {
+	hash, err := c.blockStore.GetFinalized()
+	if err != nil {
+		return err
+	}
+
+	if hash == "" {
+		c.finalized, _ = c.blockIndex.Get(hex.EncodeToString(c.genesisHash))
+		return nil
+	}
+
+	node, ok := c.blockIndex.Get(hash)
+	if !ok {
+		return fmt.Errorf("finalized block [%s] is not a part of the main chain", hash)
+	}
+
+	c.finalized = node
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+//  Finality voting
+//-----------------------------------------------------------------------------
+
+const (
+	// maxVoteHeightAhead is how far above the chain tip votes are accepted
+	maxVoteHeightAhead = 100
+	// maxPendingVotes is the maximum number of kept votes for blocks that are not known yet
+	maxPendingVotes = 1000
+)
+
+// finalityVoting implements the two-phase voting of the validators on the blocks of the main chain.
+//
+// A validator prevotes for a block once it is connected to its main chain and precommits the block
+// once it sees prevotes of more than 2/3 of the validators for it. A block with precommits of more than
+// 2/3 of the validators is finalized. Every validator casts at most one vote of each type per height.
+//
+// A validator that precommitted a block is locked to it: it only votes for the block's descendants,
+// and the votes of other validators that break their lock are rejected. The lock is released once a block
+// on another branch above the locked one has prevotes of more than 2/3 of the validators, which can't happen
+// to a finalized block. So two conflicting blocks can't both be finalized unless more than 1/3
+// of the validators misbehave, even if the honest ones follow a reorganization in between.
+type finalityVoting struct {
+	lock    sync.Mutex
+	chain   *Chain
+	privKey *cryptography.PrivateKey
+
+	// votes maps a vote target to the public keys of the validators that voted for it
+	votes map[voteTarget]map[string]struct{}
+	// cast maps a voter slot to the block hash the validator voted for, so every validator votes once per slot
+	cast map[voterSlot]string
+	// locks maps the public key of a validator to the last block it precommitted
+	locks map[string]lockedBlock
+	// pending holds the votes for blocks that are not known yet by the block hash. They are counted
+	// once the block is connected.
+	pending      map[string]map[voterSlot]*genproto.Vote
+	pendingCount int
+}
+
+type voteTarget struct {
+	voteType  genproto.Vote_Type
+	height    int32
+	blockHash string
+}
+
+type voterSlot struct {
+	voteType  genproto.Vote_Type
+	height    int32
+	publicKey string
+}
+
+type lockedBlock struct {
+	height    int32
+	blockHash []byte
+}
+
+// newFinalityVoting creates the voting state of a node. Nodes without a private key only count the votes.
+func newFinalityVoting(chain *Chain, privKey *cryptography.PrivateKey) *finalityVoting {
+	return &finalityVoting{
+		chain:   chain,
+		privKey: privKey,
+		votes:   make(map[voteTarget]map[string]struct{}),
+		cast:    make(map[voterSlot]string),
+		locks:   make(map[string]lockedBlock),
+		pending: make(map[string]map[voterSlot]*genproto.Vote),
+	}
+}
+
+// blockConnected prevotes for the block if it is at the tip of the main chain and returns the votes
+// the node has cast, which have to be broadcast to the peers, along with the votes for the block
+// received before the block itself.
+func (f *finalityVoting) blockConnected(block *genproto.Block) []*genproto.Vote {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	height, tipHash := f.chain.Tip()
+	if !bytes.Equal(tipHash, types.HashBlockBytes(block)) || height <= f.chain.FinalizedHeight() {
+		return nil
+	}
+
+	var votes []*genproto.Vote
+	for _, vote := range f.takePending(tipHash) {
+		if f.extendsLock(vote.PublicKey, vote.BlockHash) && f.addVote(vote) {
+			votes = append(append(votes, vote), f.react(vote)...)
+		}
+	}
+
+	votes = append(votes, f.castVote(genproto.Vote_PREVOTE, int32(height), tipHash)...)
+
+	// The votes of the other validators may have been received before the block itself
+	if f.hasQuorum(voteTarget{genproto.Vote_PREVOTE, int32(height), string(tipHash)}) {
+		votes = append(votes, f.castVote(genproto.Vote_PRECOMMIT, int32(height), tipHash)...)
+	}
+	f.tryFinalize(int32(height), tipHash)
+
+	return votes
+}
+
+// handleVote verifies and counts the vote of a validator. It returns the votes that have to be broadcast
+// to the peers: the received vote if it wasn't seen before and the votes the node has cast in reaction.
+// Votes for unknown blocks are kept until the block is connected.
+func (f *finalityVoting) handleVote(vote *genproto.Vote) ([]*genproto.Vote, error) {
+	if !types.VerifyVote(vote, f.chain.Params().ChainID) {
+		return nil, fmt.Errorf("vote has an invalid signature")
+	}
+
+	if !f.isValidator(vote.PublicKey) {
+		return nil, fmt.Errorf("vote is not cast by a validator")
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if int(vote.Height) <= f.chain.FinalizedHeight() {
+		return nil, nil
+	}
+
+	if tipHeight := f.chain.Height(); int(vote.Height) > tipHeight+maxVoteHeightAhead {
+		return nil, fmt.Errorf("vote for height %d is too far ahead of the chain height %d", vote.Height, tipHeight)
+	}
+
+	height, ok := f.chain.BlockHeight(vote.BlockHash)
+	if !ok {
+		f.addPending(vote)
+		return nil, nil
+	}
+
+	if height != int(vote.Height) {
+		return nil, fmt.Errorf("vote for height %d is cast for block [%x] at height %d", vote.Height, vote.BlockHash, height)
+	}
+
+	if !f.extendsLock(vote.PublicKey, vote.BlockHash) {
+		return nil, fmt.Errorf("vote for block [%x] conflicts with the block the validator precommitted", vote.BlockHash)
+	}
+
+	if !f.addVote(vote) {
+		return nil, nil
+	}
+
+	return append([]*genproto.Vote{vote}, f.react(vote)...), nil
+}
+
+// addVote counts the vote and locks the validator to the block it precommits. False is returned
+// if the validator has already voted in the slot.
+func (f *finalityVoting) addVote(vote *genproto.Vote) bool {
+	slot := voterSlot{vote.Type, vote.Height, string(vote.PublicKey)}
+	if _, ok := f.cast[slot]; ok {
+		return false
+	}
+	f.cast[slot] = string(vote.BlockHash)
+
+	target := voteTarget{vote.Type, vote.Height, string(vote.BlockHash)}
+	if f.votes[target] == nil {
+		f.votes[target] = make(map[string]struct{})
+	}
+	f.votes[target][string(vote.PublicKey)] = struct{}{}
+
+	if lock, ok := f.locks[string(vote.PublicKey)]; vote.Type == genproto.Vote_PRECOMMIT && (!ok || lock.height < vote.Height) {
+		f.locks[string(vote.PublicKey)] = lockedBlock{vote.Height, vote.BlockHash}
+	}
+
+	return true
+}
+
+// addPending keeps the vote for an unknown block, unless too many votes are kept already.
+func (f *finalityVoting) addPending(vote *genproto.Vote) {
+	if f.pendingCount >= maxPendingVotes {
+		return
+	}
+
+	blockHash := string(vote.BlockHash)
+	if f.pending[blockHash] == nil {
+		f.pending[blockHash] = make(map[voterSlot]*genproto.Vote)
+	}
+
+	slot := voterSlot{vote.Type, vote.Height, string(vote.PublicKey)}
+	if _, ok := f.pending[blockHash][slot]; !ok {
+		f.pending[blockHash][slot] = vote
+		f.pendingCount++
+	}
+}
+
+// takePending removes the kept votes for the block and returns the ones cast for its height, prevotes first.
+func (f *finalityVoting) takePending(blockHash []byte) []*genproto.Vote {
+	height, ok := f.chain.BlockHeight(blockHash)
+	if !ok {
+		return nil
+	}
+
+	pending := f.pending[string(blockHash)]
+	delete(f.pending, string(blockHash))
+	f.pendingCount -= len(pending)
+
+	votes := make([]*genproto.Vote, 0, len(pending))
+	for _, vote := range pending {
+		if int(vote.Height) == height {
+			votes = append(votes, vote)
+		}
+	}
+	slices.SortFunc(votes, func(a, b *genproto.Vote) int {
+		return cmp.Compare(a.Type, b.Type)
+	})
+
+	return votes
+}
+
+// extendsLock checks that the block is the block the validator is locked to or one of its descendants.
+func (f *finalityVoting) extendsLock(pubKey, blockHash []byte) bool {
+	lock, ok := f.locks[string(pubKey)]
+	return !ok || f.chain.IsAncestor(lock.blockHash, blockHash)
+}
+
+// unlock releases the locks to blocks below the given height that are not ancestors of the block.
+// It is called once the block has prevotes of more than 2/3 of the validators, so none of the released
+// blocks can be finalized anymore.
+func (f *finalityVoting) unlock(height int32, blockHash []byte) {
+	for pubKey, lock := range f.locks {
+		if lock.height < height && !f.chain.IsAncestor(lock.blockHash, blockHash) {
+			delete(f.locks, pubKey)
+		}
+	}
+}
+
+// react casts the votes that follow from the counted vote and finalizes the block once it has enough precommits.
+func (f *finalityVoting) react(vote *genproto.Vote) []*genproto.Vote {
+	target := voteTarget{vote.Type, vote.Height, string(vote.BlockHash)}
+	if !f.hasQuorum(target) {
+		return nil
+	}
+
+	if vote.Type == genproto.Vote_PREVOTE {
+		f.unlock(vote.Height, vote.BlockHash)
+
+		// Validators only precommit the blocks of their main chain
+		if !f.isOnMainChain(vote.Height, vote.BlockHash) {
+			return nil
+		}
+		return f.castVote(genproto.Vote_PRECOMMIT, vote.Height, vote.BlockHash)
+	}
+
+	f.tryFinalize(vote.Height, vote.BlockHash)
+	return nil
+}
+
+// castVote signs and counts the vote of the node and the votes that follow from it. Nothing is cast
+// if the node is not a validator, if it has already voted in the slot or if the block conflicts with its lock.
+func (f *finalityVoting) castVote(voteType genproto.Vote_Type, height int32, blockHash []byte) []*genproto.Vote {
+	if f.privKey == nil || !f.isValidator(f.privKey.Public().Bytes()) {
+		return nil
+	}
+
+	if !f.extendsLock(f.privKey.Public().Bytes(), blockHash) {
+		return nil
+	}
+
+	vote := &genproto.Vote{
+		Type:      voteType,
+		Height:    height,
+		BlockHash: blockHash,
+	}
+	types.SignVote(*f.privKey, vote, f.chain.Params().ChainID)
+
+	if !f.addVote(vote) {
+		return nil
+	}
+
+	return append([]*genproto.Vote{vote}, f.react(vote)...)
+}
+
+// tryFinalize finalizes the block if it has enough precommits and is already a part of the main chain.
+// The votes and locks for the finalized heights are not needed anymore.
+func (f *finalityVoting) tryFinalize(height int32, blockHash []byte) {
+	if !f.hasQuorum(voteTarget{genproto.Vote_PRECOMMIT, height, string(blockHash)}) {
+		return
+	}
+
+	if err := f.chain.FinalizeBlock(blockHash); err != nil {
+		return
+	}
+
+	for target := range f.votes {
+		if target.height <= height {
+			delete(f.votes, target)
+		}
+	}
+
+	for slot := range f.cast {
+		if slot.height <= height {
+			delete(f.cast, slot)
+		}
+	}
+
+	// Every following block descends from the finalized one
+	for pubKey, lock := range f.locks {
+		if lock.height <= height {
+			delete(f.locks, pubKey)
+		}
+	}
+
+	for blockHash, votes := range f.pending {
+		for slot := range votes {
+			if slot.height <= height {
+				delete(votes, slot)
+				f.pendingCount--
+			}
+		}
+		if len(votes) == 0 {
+			delete(f.pending, blockHash)
+		}
+	}
+}
+
+// hasQuorum checks whether more than 2/3 of the current validators voted for the target.
+func (f *finalityVoting) hasQuorum(target voteTarget) bool {
+	validators := f.chain.Validators()
+
+	count := 0
+	for _, validator := range validators {
+		if _, ok := f.votes[target][string(validator)]; ok {
+			count++
+		}
+	}
+
+	return len(validators) > 0 && count*3 > len(validators)*2
+}
+
+func (f *finalityVoting) isOnMainChain(height int32, blockHash []byte) bool {
+	block, err := f.chain.GetBlockByHeight(int(height))
+	return err == nil && bytes.Equal(types.HashBlockBytes(block), blockHash)
+}
+
+func (f *finalityVoting) isValidator(pubKey []byte) bool {
+	for _, validator := range f.chain.Validators() {
+		if bytes.Equal(validator, pubKey) {
+			return true
+		}
+	}
+
+	return false
+}
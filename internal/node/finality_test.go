@@ -0,0 +1,294 @@
+package node
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinalizeBlock(t *testing.T) {
+	var (
+		path    = filepath.Join(t.TempDir(), "chain.db")
+		store   = openTestDiskStore(t, path)
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	chain, err := NewChain(DefaultChainParams(), store)
+	require.Nil(t, err)
+	require.Equal(t, 0, chain.FinalizedHeight())
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	blocks := []*genproto.Block{genesisBlock}
+	for i := 1; i <= 3; i++ {
+		block := createRandomSignedBlockOnTop(blocks[i-1], privKey)
+		require.Nil(t, chain.AddBlock(block))
+		blocks = append(blocks, block)
+	}
+
+	require.Nil(t, chain.FinalizeBlock(types.HashBlockBytes(blocks[2])))
+	require.Equal(t, 2, chain.FinalizedHeight())
+
+	// Finalizing an ancestor has no effect
+	require.Nil(t, chain.FinalizeBlock(types.HashBlockBytes(blocks[1])))
+	require.Equal(t, 2, chain.FinalizedHeight())
+
+	// Side branch blocks can't be finalized
+	sideBlock := createRandomSignedBlockOnTop(blocks[2], cryptography.NewPrivateKey())
+	require.Nil(t, chain.AddBlock(sideBlock))
+	require.NotNil(t, chain.FinalizeBlock(types.HashBlockBytes(sideBlock)))
+
+	// Branches forking below the finalized block are rejected, no matter how long they are
+	forkBlock := createRandomSignedBlockOnTop(blocks[1], cryptography.NewPrivateKey())
+	require.ErrorIs(t, chain.AddBlock(forkBlock), ErrFinalizedBlock)
+
+	_, err = chain.DisconnectTip()
+	require.Nil(t, err)
+	_, err = chain.DisconnectTip()
+	require.ErrorIs(t, err, ErrFinalizedBlock)
+	require.Equal(t, 2, chain.Height())
+
+	// The finalized block survives restarts
+	require.Nil(t, store.Close())
+	store = openTestDiskStore(t, path)
+	defer store.Close()
+
+	chain, err = NewChain(DefaultChainParams(), store)
+	require.Nil(t, err)
+	require.Equal(t, 2, chain.FinalizedHeight())
+}
+
+func TestFinalityVoting(t *testing.T) {
+	var (
+		validators = newTestValidators(4)
+		params     = newAuthorityChainParams(validators)
+		voting     = make([]*finalityVoting, len(validators))
+	)
+
+	for idx := range validators {
+		chain, err := NewChain(params, NewMemoryStore())
+		require.Nil(t, err)
+		voting[idx] = newFinalityVoting(chain, &validators[idx])
+	}
+
+	// Votes are delivered to all validators until no new votes are cast
+	deliver := func(votes []*genproto.Vote, recipients []*finalityVoting) {
+		for len(votes) > 0 {
+			vote := votes[0]
+			votes = votes[1:]
+
+			for _, v := range recipients {
+				newVotes, err := v.handleVote(vote)
+				require.Nil(t, err)
+				votes = append(votes, newVotes...)
+			}
+		}
+	}
+
+	block := newTestBlock(t, voting[0].chain, validators)
+	for _, v := range voting {
+		require.Nil(t, v.chain.AddBlock(block))
+	}
+
+	// Two prevotes of four validators are not enough to precommit
+	var votes []*genproto.Vote
+	for _, v := range voting[:2] {
+		votes = append(votes, v.blockConnected(block)...)
+	}
+	deliver(votes, voting)
+
+	for _, v := range voting {
+		require.Equal(t, 0, v.chain.FinalizedHeight())
+	}
+
+	// The third prevote makes a quorum, so all validators precommit and finalize the block
+	deliver(voting[2].blockConnected(block), voting)
+
+	for _, v := range voting {
+		require.Equal(t, 1, v.chain.FinalizedHeight())
+	}
+
+	// The fourth validator doesn't cast a vote for the finalized height
+	require.Empty(t, voting[3].blockConnected(block))
+}
+
+func TestHandleInvalidVote(t *testing.T) {
+	var (
+		validators = newTestValidators(2)
+		chain      = newTestChain(t, newAuthorityChainParams(validators))
+		voting     = newFinalityVoting(chain, nil)
+	)
+
+	block := newTestBlock(t, chain, validators)
+	require.Nil(t, chain.AddBlock(block))
+
+	// Nodes without a validator key don't vote
+	require.Empty(t, voting.blockConnected(block))
+
+	vote := &genproto.Vote{
+		Type:      genproto.Vote_PREVOTE,
+		Height:    1,
+		BlockHash: types.HashBlockBytes(block),
+	}
+
+	types.SignVote(cryptography.NewPrivateKey(), vote, defaultChainID)
+	_, err := voting.handleVote(vote)
+	require.NotNil(t, err)
+
+	// Votes signed for another network are rejected
+	types.SignVote(validators[0], vote, "another-network")
+	_, err = voting.handleVote(vote)
+	require.NotNil(t, err)
+
+	types.SignVote(validators[0], vote, defaultChainID)
+	vote.Height = 2
+	_, err = voting.handleVote(vote)
+	require.NotNil(t, err)
+
+	// The height of the vote must be the height of the block
+	types.SignVote(validators[0], vote, defaultChainID)
+	_, err = voting.handleVote(vote)
+	require.NotNil(t, err)
+
+	// Votes far ahead of the chain are rejected
+	vote.Height = 2 + maxVoteHeightAhead
+	types.SignVote(validators[0], vote, defaultChainID)
+	_, err = voting.handleVote(vote)
+	require.NotNil(t, err)
+
+	// A valid vote is relayed only once
+	vote.Height = 1
+	types.SignVote(validators[0], vote, defaultChainID)
+	votes, err := voting.handleVote(vote)
+	require.Nil(t, err)
+	require.Equal(t, []*genproto.Vote{vote}, votes)
+
+	votes, err = voting.handleVote(vote)
+	require.Nil(t, err)
+	require.Empty(t, votes)
+}
+
+// newTestVote creates a vote of the validator for the block.
+func newTestVote(validator cryptography.PrivateKey, voteType genproto.Vote_Type, block *genproto.Block) *genproto.Vote {
+	vote := &genproto.Vote{
+		Type:      voteType,
+		Height:    block.Header.Height,
+		BlockHash: types.HashBlockBytes(block),
+	}
+	types.SignVote(validator, vote, defaultChainID)
+
+	return vote
+}
+
+// newLockedVoting returns the voting of the first validator, which is locked to the block at height 1
+// of its main chain, along with that block and the blocks of a longer branch the chain has switched to.
+func newLockedVoting(t *testing.T, validators []cryptography.PrivateKey) (*finalityVoting, *genproto.Block, []*genproto.Block) {
+	var (
+		params    = newAuthorityChainParams(validators)
+		voting    = newFinalityVoting(newTestChain(t, params), &validators[0])
+		sideChain = newTestChain(t, params)
+		now       = time.Now()
+	)
+
+	lockedBlock := newTestBlockAt(t, voting.chain, validators, now)
+	require.Nil(t, voting.chain.AddBlock(lockedBlock))
+	require.NotEmpty(t, voting.blockConnected(lockedBlock))
+
+	// The prevotes of two more validators make a quorum, so the validator precommits the block
+	for _, validator := range validators[1:3] {
+		votes, err := voting.handleVote(newTestVote(validator, genproto.Vote_PREVOTE, lockedBlock))
+		require.Nil(t, err)
+		require.NotEmpty(t, votes)
+	}
+	require.Equal(t, 0, voting.chain.FinalizedHeight())
+
+	var branch []*genproto.Block
+	for i := range 2 {
+		block := newTestBlockAt(t, sideChain, validators, now.Add(time.Duration(i+1)*time.Second))
+		require.Nil(t, sideChain.AddBlock(block))
+		require.Nil(t, voting.chain.AddBlock(block))
+		branch = append(branch, block)
+	}
+
+	height, tipHash := voting.chain.Tip()
+	require.Equal(t, 2, height)
+	require.Equal(t, types.HashBlockBytes(branch[1]), tipHash)
+
+	return voting, lockedBlock, branch
+}
+
+func TestFinalityLock(t *testing.T) {
+	validators := newTestValidators(4)
+	voting, lockedBlock, branch := newLockedVoting(t, validators)
+
+	// The validator doesn't vote for a block that doesn't descend from the block it precommitted
+	require.Empty(t, voting.blockConnected(branch[1]))
+
+	// Neither do the other validators
+	_, err := voting.handleVote(newTestVote(validators[3], genproto.Vote_PRECOMMIT, lockedBlock))
+	require.Nil(t, err)
+	_, err = voting.handleVote(newTestVote(validators[3], genproto.Vote_PREVOTE, branch[1]))
+	require.NotNil(t, err)
+
+	// Votes of unlocked validators are counted
+	votes, err := voting.handleVote(newTestVote(validators[1], genproto.Vote_PREVOTE, branch[1]))
+	require.Nil(t, err)
+	require.NotEmpty(t, votes)
+}
+
+func TestFinalityUnlock(t *testing.T) {
+	validators := newTestValidators(4)
+	voting, _, branch := newLockedVoting(t, validators)
+
+	// Prevotes of more than 2/3 of the validators for a block on another branch release the lock,
+	// so the validator precommits that block
+	var votes []*genproto.Vote
+	for _, validator := range validators[1:] {
+		newVotes, err := voting.handleVote(newTestVote(validator, genproto.Vote_PREVOTE, branch[1]))
+		require.Nil(t, err)
+		votes = append(votes, newVotes...)
+	}
+
+	precommit := votes[len(votes)-1]
+	require.Equal(t, genproto.Vote_PRECOMMIT, precommit.Type)
+	require.Equal(t, validators[0].Public().Bytes(), precommit.PublicKey)
+	require.Equal(t, types.HashBlockBytes(branch[1]), precommit.BlockHash)
+
+	for _, validator := range validators[1:3] {
+		_, err := voting.handleVote(newTestVote(validator, genproto.Vote_PRECOMMIT, branch[1]))
+		require.Nil(t, err)
+	}
+	require.Equal(t, 2, voting.chain.FinalizedHeight())
+}
+
+func TestPendingVotes(t *testing.T) {
+	var (
+		validators = newTestValidators(4)
+		params     = newAuthorityChainParams(validators)
+		voting     = newFinalityVoting(newTestChain(t, params), &validators[0])
+		peerChain  = newTestChain(t, params)
+	)
+
+	block := newTestBlock(t, peerChain, validators)
+
+	// Votes for an unknown block are kept, but not relayed until the block is connected
+	for _, validator := range validators[1:3] {
+		votes, err := voting.handleVote(newTestVote(validator, genproto.Vote_PREVOTE, block))
+		require.Nil(t, err)
+		require.Empty(t, votes)
+	}
+	require.Equal(t, 2, voting.pendingCount)
+
+	// With the kept prevotes the own prevote makes a quorum, so the validator precommits the block
+	require.Nil(t, voting.chain.AddBlock(block))
+	votes := voting.blockConnected(block)
+	require.Len(t, votes, 4)
+	require.Equal(t, genproto.Vote_PRECOMMIT, votes[3].Type)
+	require.Equal(t, 0, voting.pendingCount)
+}
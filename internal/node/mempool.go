@@ -1,6 +1,7 @@
 package node
 
 import (
+	"encoding/hex"
 	"sync"
 	"time"
 
@@ -8,17 +9,25 @@ import (
 	"github.com/oleglegun/blockchain-btc/internal/types"
 )
 
+// Mempool holds the pending transactions along with the changes they make to the UTXO set, so new transactions
+// can spend the outputs of pending ones. Pending transactions never spend the same output.
 type Mempool struct {
 	sync.RWMutex
 	txMap map[string]*genproto.Transaction
 	// txTimestampMap contains all transactions' timestamps (including cleared)
 	txTimestampMap map[string]time.Time
+	// outputs contains the outputs of the pending transactions by their UTXO keys
+	outputs map[string]*UTXO
+	// spent maps the UTXO keys spent by the pending transactions to the hashes of the spending transactions
+	spent map[string]string
 }
 
 func NewMempool() *Mempool {
 	return &Mempool{
 		txMap:          make(map[string]*genproto.Transaction),
 		txTimestampMap: make(map[string]time.Time),
+		outputs:        make(map[string]*UTXO),
+		spent:          make(map[string]string),
 	}
 }
 
@@ -39,10 +48,27 @@ func (p *Mempool) Clear() []*genproto.Transaction {
 		idx++
 	}
 	p.txMap = make(map[string]*genproto.Transaction)
+	p.outputs = make(map[string]*UTXO)
+	p.spent = make(map[string]string)
 
 	return txList
 }
 
+// Pending returns the transactions of the mempool without removing them.
+func (p *Mempool) Pending() []*genproto.Transaction {
+	p.RLock()
+	defer p.RUnlock()
+
+	txList := make([]*genproto.Transaction, 0, len(p.txMap))
+	for _, tx := range p.txMap {
+		txList = append(txList, tx)
+	}
+
+	return txList
+}
+
+// ClearProcessed forgets the hashes of the processed transactions older than the threshold, so they can be
+// accepted again. Pending transactions are never cleared, they stay until a block includes them.
 func (p *Mempool) ClearProcessed(threshold time.Duration) []string {
 	p.Lock()
 	defer p.Unlock()
@@ -52,8 +78,11 @@ func (p *Mempool) ClearProcessed(threshold time.Duration) []string {
 	clearedTxHashList := make([]string, 0)
 
 	for hash, timestamp := range p.txTimestampMap {
+		if _, pending := p.txMap[hash]; pending {
+			continue
+		}
+
 		if now.Sub(timestamp) > threshold {
-			delete(p.txMap, hash)
 			delete(p.txTimestampMap, hash)
 			clearedTxHashList = append(clearedTxHashList, hash)
 		}
@@ -74,6 +103,8 @@ func (p *Mempool) Has(tx *genproto.Transaction) bool {
 	return ok
 }
 
+// Add puts the transaction into the mempool. False is returned if the transaction was processed before
+// or spends an output that a pending transaction spends already.
 func (p *Mempool) Add(tx *genproto.Transaction) bool {
 	hash := types.HashTransactionString(tx)
 
@@ -84,7 +115,137 @@ func (p *Mempool) Add(tx *genproto.Transaction) bool {
 		return false
 	}
 
-	p.txMap[hash] = tx
+	if !p.addPending(hash, tx) {
+		return false
+	}
 	p.txTimestampMap[hash] = time.Now()
 	return true
 }
+
+// Remove deletes the given transactions (e.g. included in a block) from the mempool.
+// The transactions are marked as processed, so they are not accepted again. Pending transactions
+// spending the same outputs as the given ones are evicted along with the transactions spending their outputs.
+func (p *Mempool) Remove(txList []*genproto.Transaction) {
+	p.Lock()
+	defer p.Unlock()
+
+	now := time.Now()
+
+	for _, tx := range txList {
+		hash := types.HashTransactionString(tx)
+
+		if _, ok := p.txMap[hash]; ok {
+			p.removePending(hash)
+		} else {
+			for _, input := range tx.Inputs {
+				if spendingHash, ok := p.spent[inputUTXOKey(input)]; ok {
+					p.evict(spendingHash)
+				}
+			}
+		}
+
+		if _, exists := p.txTimestampMap[hash]; !exists {
+			p.txTimestampMap[hash] = now
+		}
+	}
+}
+
+// Restore puts the given transactions back into the mempool, even if they were processed before.
+// It is used for the transactions of blocks that were removed from the main chain. Transactions spending
+// an output that a pending transaction spends already are left out.
+func (p *Mempool) Restore(txList []*genproto.Transaction) {
+	p.Lock()
+	defer p.Unlock()
+
+	now := time.Now()
+
+	for _, tx := range txList {
+		hash := types.HashTransactionString(tx)
+		if _, ok := p.txMap[hash]; ok {
+			continue
+		}
+
+		if p.addPending(hash, tx) {
+			p.txTimestampMap[hash] = now
+		}
+	}
+}
+
+// PendingOutput returns the output with the given key created by a pending transaction. The height
+// of the returned UTXO is not set, since the transaction is not included in a block yet.
+func (p *Mempool) PendingOutput(key string) (*UTXO, bool) {
+	p.RLock()
+	defer p.RUnlock()
+
+	utxo, ok := p.outputs[key]
+	return utxo, ok
+}
+
+// IsPendingSpent reports whether a pending transaction spends the output with the given key.
+func (p *Mempool) IsPendingSpent(key string) bool {
+	p.RLock()
+	defer p.RUnlock()
+
+	_, ok := p.spent[key]
+	return ok
+}
+
+// addPending adds the transaction with its outputs and spent outputs to the pending ones, unless it spends
+// an output that a pending transaction spends already. The lock must be held by the caller.
+func (p *Mempool) addPending(hash string, tx *genproto.Transaction) bool {
+	for _, input := range tx.Inputs {
+		if _, ok := p.spent[inputUTXOKey(input)]; ok {
+			return false
+		}
+	}
+
+	p.txMap[hash] = tx
+	for _, input := range tx.Inputs {
+		p.spent[inputUTXOKey(input)] = hash
+	}
+	for idx, output := range tx.Outputs {
+		if output.Type != genproto.OutputType_DATA {
+			p.outputs[getUTXOKey(hash, idx)] = newOutputUTXO(hash, idx, output, 0, false)
+		}
+	}
+
+	return true
+}
+
+// removePending removes the pending transaction with its outputs and spent outputs.
+// The lock must be held by the caller.
+func (p *Mempool) removePending(hash string) {
+	tx, ok := p.txMap[hash]
+	if !ok {
+		return
+	}
+
+	delete(p.txMap, hash)
+	for _, input := range tx.Inputs {
+		delete(p.spent, inputUTXOKey(input))
+	}
+	for idx := range tx.Outputs {
+		delete(p.outputs, getUTXOKey(hash, idx))
+	}
+}
+
+// evict removes the pending transaction along with the pending transactions spending its outputs,
+// which can't be included anymore. The lock must be held by the caller.
+func (p *Mempool) evict(hash string) {
+	tx, ok := p.txMap[hash]
+	if !ok {
+		return
+	}
+
+	p.removePending(hash)
+	for idx := range tx.Outputs {
+		if spendingHash, ok := p.spent[getUTXOKey(hash, idx)]; ok {
+			p.evict(spendingHash)
+		}
+	}
+}
+
+// inputUTXOKey returns the key of the UTXO spent by the input.
+func inputUTXOKey(input *genproto.TxInput) string {
+	return getUTXOKey(hex.EncodeToString(input.PrevTxHash), int(input.PrevTxOutIndex))
+}
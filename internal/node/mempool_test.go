@@ -0,0 +1,79 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/random"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMempoolClearProcessed(t *testing.T) {
+	var (
+		mempool    = NewMempool()
+		pendingTx  = random.RandomBlock().Transactions[0]
+		includedTx = random.RandomBlock().Transactions[0]
+	)
+	includedTx.Version = 2
+
+	require.True(t, mempool.Add(pendingTx))
+	require.True(t, mempool.Add(includedTx))
+	mempool.Remove([]*genproto.Transaction{includedTx})
+	time.Sleep(time.Millisecond)
+
+	// Only the processed transactions are forgotten, pending ones stay however old they are
+	cleared := mempool.ClearProcessed(0)
+	require.Equal(t, []string{types.HashTransactionString(includedTx)}, cleared)
+	require.Equal(t, []*genproto.Transaction{pendingTx}, mempool.Pending())
+	require.True(t, mempool.Has(pendingTx))
+
+	// A forgotten transaction can be accepted again
+	require.False(t, mempool.Has(includedTx))
+	require.True(t, mempool.Add(includedTx))
+	require.Equal(t, 2, mempool.Size())
+}
+
+func TestMempoolPendingUTXOs(t *testing.T) {
+	var (
+		mempool   = NewMempool()
+		privKey   = cryptography.NewPrivateKey()
+		output    = &genproto.TxOutput{Amount: 1, Address: privKey.Public().Address().Bytes()}
+		prevTx    = &genproto.Transaction{Version: 1, Outputs: []*genproto.TxOutput{{Amount: 10, Address: output.Address}}}
+		parentTx  = newSpendingTx(prevTx, 0, privKey, output)
+		childTx   = newSpendingTx(parentTx, 0, privKey, output)
+		conflicts = newSpendingTx(prevTx, 0, privKey, &genproto.TxOutput{Amount: 2, Address: output.Address})
+		parentKey = getUTXOKey(types.HashTransactionString(parentTx), 0)
+	)
+
+	require.True(t, mempool.Add(parentTx))
+	require.True(t, mempool.Add(childTx))
+
+	// The outputs of the pending transactions are seen along with the outputs they spend
+	utxo, ok := mempool.PendingOutput(parentKey)
+	require.True(t, ok)
+	require.Equal(t, output.Amount, utxo.Amount)
+	require.True(t, mempool.IsPendingSpent(parentKey))
+	require.True(t, mempool.IsPendingSpent(getUTXOKey(types.HashTransactionString(prevTx), 0)))
+
+	// Pending transactions never spend the same output
+	require.False(t, mempool.Add(conflicts))
+	require.False(t, mempool.Has(conflicts))
+
+	// A block including the parent confirms its outputs, the child stays pending
+	mempool.Remove([]*genproto.Transaction{parentTx})
+	_, ok = mempool.PendingOutput(parentKey)
+	require.False(t, ok)
+	require.True(t, mempool.IsPendingSpent(parentKey))
+	require.Equal(t, []*genproto.Transaction{childTx}, mempool.Pending())
+
+	// A block including a conflicting transaction evicts the pending one and the transactions spending its outputs
+	mempool.Restore([]*genproto.Transaction{parentTx})
+	require.Equal(t, 2, mempool.Size())
+	mempool.Remove([]*genproto.Transaction{conflicts})
+	require.Equal(t, 0, mempool.Size())
+	require.False(t, mempool.IsPendingSpent(parentKey))
+	require.True(t, mempool.Has(childTx))
+}
@@ -0,0 +1,119 @@
+package node
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// minerCheckInterval is the number of nonces a mining worker tries before it checks whether it should stop.
+const minerCheckInterval = 1 << 14
+
+// runMiner produces proof-of-work blocks from the mempool transactions in an endless loop.
+// Mining of a block is abandoned once the chain tip changes, e.g. when a peer relays a block first.
+func (n *Node) runMiner() {
+	n.log.Debug("running miner", "workers", n.MinerWorkers, "pubKey", n.PrivateKey.Public().String())
+
+	for {
+		block := n.mineNextBlock()
+		if block == nil {
+			continue
+		}
+
+		if err := n.broadcast(block); err != nil {
+			n.log.Error("failed to broadcast block", "error", err)
+		}
+	}
+}
+
+// mineNextBlock mines a block with the mempool transactions on top of the chain tip and adds it to the chain.
+// The transactions stay in the mempool until a connected block includes them, so the ones left out of the block
+// or of an abandoned block are mined later. It returns nil if the mining was abandoned or the block was rejected.
+func (n *Node) mineNextBlock() *genproto.Block {
+	n.mempool.ClearProcessed(time.Minute)
+
+	block := n.chain.NewBlockTemplate(n.mempool.Pending(), n.PrivateKey.Public().Address().Bytes())
+
+	rootHash, err := types.CalculateRootHash(block)
+	if err != nil {
+		n.log.Error("failed to calculate root hash", "error", err)
+		return nil
+	}
+	block.Header.RootHash = rootHash
+
+	n.log.Debug("mining new block", "height", block.Header.Height, "txs", len(block.Transactions), "bits", block.Header.Bits)
+
+	stale := func() bool {
+		_, tipHash := n.chain.Tip()
+		return !bytes.Equal(tipHash, block.Header.PrevHash)
+	}
+
+	if !mineBlock(block.Header, n.MinerWorkers, stale) {
+		return nil
+	}
+
+	types.SignBlock(*n.PrivateKey, block)
+
+	if err := n.chain.AddBlock(block); err != nil {
+		n.log.Error("failed to add block to the chain", "error", err)
+		return nil
+	}
+
+	n.log.Debug("mined new block", "height", block.Header.Height, "hash", types.HashBlockString(block), "nonce", block.Header.Nonce)
+	n.mempool.Remove(block.Transactions)
+
+	return block
+}
+
+// mineBlock searches for a nonce that makes the header hash meet the difficulty target of the header.
+// The nonce space is split between the given number of worker goroutines. The search stops early
+// when stale returns true, in which case false is returned and the header is left unchanged.
+func mineBlock(header *genproto.BlockHeader, workers int, stale func() bool) bool {
+	var (
+		wg    sync.WaitGroup
+		done  atomic.Bool
+		nonce = make(chan uint64, workers)
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func(start uint64) {
+			defer wg.Done()
+
+			candidate := proto.Clone(header).(*genproto.BlockHeader)
+			candidate.Nonce = start
+
+			for tries := 1; ; tries++ {
+				if types.VerifyProofOfWork(candidate) {
+					done.Store(true)
+					nonce <- candidate.Nonce
+					return
+				}
+
+				if tries%minerCheckInterval == 0 && (done.Load() || stale()) {
+					done.Store(true)
+					return
+				}
+
+				candidate.Nonce += uint64(workers)
+			}
+		}(uint64(i))
+	}
+
+	wg.Wait()
+	close(nonce)
+
+	found, ok := <-nonce
+	if !ok {
+		return false
+	}
+
+	header.Nonce = found
+	return true
+}
@@ -1,11 +1,14 @@
 package node
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"os"
+	"runtime"
 	"sync"
 	"time"
 
@@ -19,14 +22,17 @@ import (
 )
 
 const (
-	nodeVersion = "1.0"
-	blockTime   = time.Second * 5
+	nodeVersion    = "1.0"
+	statusInterval = time.Second * 10
 )
 
 type NodeConfig struct {
 	Version    string
 	ListenAddr string
+	// PrivateKey signs the produced blocks. Nodes without a key don't produce blocks.
 	PrivateKey *cryptography.PrivateKey
+	// MinerWorkers is the number of mining goroutines in the proof-of-work mode (number of CPUs if zero)
+	MinerWorkers int
 }
 
 type Node struct {
@@ -39,12 +45,17 @@ type Node struct {
 	peers     map[string]ConnectedPeer
 	mempool   *Mempool
 
-	chain *Chain
+	chain       *Chain
+	syncManager *syncManager
+	finality    *finalityVoting
 }
 
 type ConnectedPeer struct {
 	peerClient genproto.NodeClient
 	nodeInfo   *genproto.NodeInfo
+	// remoteAddr is the address of the connection the peer called the handshake from.
+	// It is empty for peers the node dialed itself.
+	remoteAddr string
 }
 
 func NewNode(config NodeConfig, chain *Chain) *Node {
@@ -53,18 +64,31 @@ func NewNode(config NodeConfig, chain *Chain) *Node {
 		AddSource: false,
 	})
 
+	log := slog.New(logHandler).With("node", config.ListenAddr)
+	mempool := NewMempool()
+
+	// Transactions of the blocks removed during a chain reorganization become pending again
+	chain.SetOrphanedTxHandler(mempool.Restore)
+
+	if config.MinerWorkers <= 0 {
+		config.MinerWorkers = runtime.NumCPU()
+	}
+
 	return &Node{
-		NodeConfig: config,
-		log:        slog.New(logHandler).With("node", config.ListenAddr),
-		peers:      make(map[string]ConnectedPeer),
-		mempool:    NewMempool(),
-		chain:      chain,
+		NodeConfig:  config,
+		log:         log,
+		peers:       make(map[string]ConnectedPeer),
+		mempool:     mempool,
+		chain:       chain,
+		syncManager: newSyncManager(chain, log),
+		finality:    newFinalityVoting(chain, config.PrivateKey),
 	}
 }
 
 // Start runs the node server, listening on the configured listen address and
 // registering the node gRPC service. It bootstraps the node by connecting to
-// the specified list of bootstrap nodes.
+// the specified list of bootstrap nodes and downloads the blocks it is missing
+// from them before taking part in relay and block production.
 func (n *Node) Start(bootstrapNodes []string) error {
 	grpcServer := grpc.NewServer()
 	tpcListener, err := net.Listen("tcp", n.ListenAddr)
@@ -76,14 +100,26 @@ func (n *Node) Start(bootstrapNodes []string) error {
 
 	n.log.Debug("running...")
 
-	if len(bootstrapNodes) > 0 {
-		n.log.Debug("discovered new peers", "peers", bootstrapNodes)
-		go n.bootstrapNetwork(bootstrapNodes)
-	}
+	go func() {
+		if len(bootstrapNodes) > 0 {
+			n.log.Debug("discovered new peers", "peers", bootstrapNodes)
+			n.bootstrapNetwork(bootstrapNodes)
+		}
 
-	if n.PrivateKey != nil {
-		go n.runValidatorLoop()
-	}
+		n.syncManager.Sync(n.getConnectedPeers())
+
+		go n.runStatusLoop()
+
+		if n.PrivateKey == nil {
+			return
+		}
+
+		if n.chain.Params().Consensus == ConsensusProofOfWork {
+			n.runMiner()
+		} else {
+			n.runValidatorLoop()
+		}
+	}()
 
 	return grpcServer.Serve(tpcListener)
 }
@@ -95,13 +131,14 @@ func (n *Node) Start(bootstrapNodes []string) error {
 // Handshake is called when a new peer node connects to the current node.
 // It exchanges node information with the peer node and adds the peer to the list of connected peers.
 //
+// Peers with a different node version, genesis block or chain ID are refused.
 // If there is an error creating the client connection to the peer node, the function will return an error.
 func (n *Node) Handshake(ctx context.Context, peerNodeInfo *genproto.NodeInfo) (*genproto.NodeInfo, error) {
 	thisNodeInfo := n.getNodeInfo()
 
-	if thisNodeInfo.Version != peerNodeInfo.Version {
-		n.log.Debug("incompatible node versions")
-		return nil, fmt.Errorf("incompatible node versions")
+	if err := checkPeerCompatibility(thisNodeInfo, peerNodeInfo); err != nil {
+		n.log.Debug("refused peer", "peer", peerNodeInfo.ListenAddr, "error", err)
+		return nil, err
 	}
 
 	peerClient, err := newNodeClient(peerNodeInfo.ListenAddr)
@@ -109,18 +146,51 @@ func (n *Node) Handshake(ctx context.Context, peerNodeInfo *genproto.NodeInfo) (
 		return nil, err
 	}
 
-	n.addPeer(peerClient, peerNodeInfo)
+	var remoteAddr string
+	if peer, ok := peer.FromContext(ctx); ok {
+		remoteAddr = peer.Addr.String()
+	}
+
+	n.addPeer(peerClient, peerNodeInfo, remoteAddr)
 
 	return thisNodeInfo, nil
 }
 
+// Status is called periodically by connected peers to exchange up-to-date node information.
+// The information only updates the peer that called the handshake over the same connection,
+// regardless of the listen address it claims.
+func (n *Node) Status(ctx context.Context, peerNodeInfo *genproto.NodeInfo) (*genproto.NodeInfo, error) {
+	if peer, ok := peer.FromContext(ctx); ok {
+		if listenAddr, ok := n.getPeerByRemoteAddr(peer.Addr.String()); ok {
+			n.updatePeerInfo(listenAddr, peerNodeInfo)
+		}
+	}
+
+	return n.getNodeInfo(), nil
+}
+
 func (n *Node) HandleTransaction(ctx context.Context, tx *genproto.Transaction) (*emptypb.Empty, error) {
+	if !n.syncManager.IsSynced() {
+		return &emptypb.Empty{}, nil
+	}
+
 	peer, ok := peer.FromContext(ctx)
 	if !ok {
 		n.log.Error("cannot get peer from the context")
 
 	}
 
+	if n.mempool.Has(tx) {
+		return &emptypb.Empty{}, nil
+	}
+
+	// Only transactions that are valid in the next block after the pending ones are admitted, so transactions
+	// may spend the outputs of unconfirmed ones, but locked transactions and double spends are rejected
+	if err := n.chain.ValidatePoolTransaction(tx, n.mempool); err != nil {
+		n.log.Debug("rejected tx", "tx", types.HashTransactionString(tx), "error", err)
+		return nil, err
+	}
+
 	if n.mempool.Add(tx) {
 		txHash := types.HashTransactionString(tx)
 		n.log.Debug("received tx", "from", peer.Addr, "tx", txHash)
@@ -135,6 +205,127 @@ func (n *Node) HandleTransaction(ctx context.Context, tx *genproto.Transaction)
 	return &emptypb.Empty{}, nil
 }
 
+// HandleBlock is called when a peer node relays a new block.
+// The block is validated and appended to the chain, its transactions are removed from the mempool
+// and it is relayed further to all known peers.
+//
+// Blocks that are already part of the chain are ignored and not relayed again, which prevents relay loops.
+// Blocks received while the node is syncing are ignored as well. A block that is ahead of the chain tip
+// or has an unknown parent triggers a new sync with the known peers.
+func (n *Node) HandleBlock(ctx context.Context, block *genproto.Block) (*emptypb.Empty, error) {
+	// Malformed blocks are rejected before their header is used
+	if block.Header == nil || len(block.Transactions) == 0 {
+		return nil, fmt.Errorf("block has no header or transactions")
+	}
+
+	if !n.syncManager.IsSynced() {
+		return &emptypb.Empty{}, nil
+	}
+
+	blockHash := types.HashBlockString(block)
+
+	if int(block.Header.Height) > n.chain.Height()+1 {
+		n.log.Debug("received block ahead of the chain", "height", block.Header.Height, "block", blockHash)
+		go n.syncManager.Sync(n.getConnectedPeers())
+		return &emptypb.Empty{}, nil
+	}
+
+	if err := n.chain.AddBlock(block); err != nil {
+		if errors.Is(err, ErrBlockExists) {
+			return &emptypb.Empty{}, nil
+		}
+
+		if errors.Is(err, ErrOrphanBlock) {
+			n.log.Debug("received block with unknown parent", "block", blockHash)
+			go n.syncManager.Sync(n.getConnectedPeers())
+			return &emptypb.Empty{}, nil
+		}
+
+		n.log.Debug("rejected block", "block", blockHash, "error", err)
+		return nil, err
+	}
+
+	n.mempool.Remove(block.Transactions)
+
+	if peer, ok := peer.FromContext(ctx); ok {
+		n.log.Debug("received block", "from", peer.Addr, "height", block.Header.Height, "block", blockHash)
+	}
+
+	votes := n.finality.blockConnected(block)
+
+	go func() {
+		if err := n.broadcast(block); err != nil {
+			n.log.Error("failed to broadcast block", "error", err)
+		}
+		n.broadcastVotes(votes)
+	}()
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleVote is called when a peer relays a finality vote of a validator.
+// New valid votes are counted and relayed further along with the votes the node casts in reaction.
+func (n *Node) HandleVote(ctx context.Context, vote *genproto.Vote) (*emptypb.Empty, error) {
+	if !n.syncManager.IsSynced() {
+		return &emptypb.Empty{}, nil
+	}
+
+	votes, err := n.finality.handleVote(vote)
+	if err != nil {
+		n.log.Debug("rejected vote", "height", vote.Height, "error", err)
+		return nil, err
+	}
+
+	go n.broadcastVotes(votes)
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetBlockHeaders returns consecutive main chain block headers starting at the requested block.
+func (n *Node) GetBlockHeaders(ctx context.Context, req *genproto.BlockRangeRequest) (*genproto.BlockHeaders, error) {
+	fromHeight, err := n.getRangeStartHeight(req)
+	if err != nil {
+		return nil, err
+	}
+
+	count := min(int(req.Count), maxHeadersPerRequest)
+	if fromHeight > n.chain.Height() || count <= 0 {
+		return &genproto.BlockHeaders{}, nil
+	}
+
+	headers, err := n.chain.GetBlockHeaders(fromHeight, count)
+	if err != nil {
+		return nil, err
+	}
+
+	return &genproto.BlockHeaders{Headers: headers}, nil
+}
+
+// GetBlocks returns consecutive main chain blocks starting at the requested block.
+func (n *Node) GetBlocks(ctx context.Context, req *genproto.BlockRangeRequest) (*genproto.Blocks, error) {
+	fromHeight, err := n.getRangeStartHeight(req)
+	if err != nil {
+		return nil, err
+	}
+
+	count := min(int(req.Count), maxBlocksPerRequest)
+	if fromHeight > n.chain.Height() || count <= 0 {
+		return &genproto.Blocks{}, nil
+	}
+
+	blocks := make([]*genproto.Block, 0, count)
+
+	for height := fromHeight; height < fromHeight+count && height <= n.chain.Height(); height++ {
+		block, err := n.chain.GetBlockByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	return &genproto.Blocks{Blocks: blocks}, nil
+}
+
 //-----------------------------------------------------------------------------
 //  Other methods
 //-----------------------------------------------------------------------------
@@ -149,17 +340,18 @@ func (n *Node) bootstrapNetwork(listenSocketAddrs []string) error {
 			continue
 		}
 
-		n.addPeer(peerClient, peerNodeInfo)
+		n.addPeer(peerClient, peerNodeInfo, "")
 	}
 
 	return nil
 }
 
-func (n *Node) addPeer(peerClient genproto.NodeClient, peerNodeInfo *genproto.NodeInfo) {
+func (n *Node) addPeer(peerClient genproto.NodeClient, peerNodeInfo *genproto.NodeInfo, remoteAddr string) {
 	n.peersLock.Lock()
 	n.peers[peerNodeInfo.ListenAddr] = ConnectedPeer{
 		peerClient: peerClient,
 		nodeInfo:   peerNodeInfo,
+		remoteAddr: remoteAddr,
 	}
 	n.log.Debug("connected nodes", "count", len(n.peers))
 
@@ -183,24 +375,130 @@ func (n *Node) removePeer(peerListenAddr string) {
 	delete(n.peers, peerListenAddr)
 }
 
+// updatePeerInfo replaces the node information of the peer connected with the given listen address.
+// The listen address identifies the peer, so information claiming another address is ignored.
+func (n *Node) updatePeerInfo(listenAddr string, peerNodeInfo *genproto.NodeInfo) {
+	n.peersLock.Lock()
+	defer n.peersLock.Unlock()
+
+	peer, ok := n.peers[listenAddr]
+	if !ok || peerNodeInfo.ListenAddr != listenAddr {
+		return
+	}
+
+	peer.nodeInfo = peerNodeInfo
+	n.peers[listenAddr] = peer
+}
+
+// getPeerByRemoteAddr returns the listen address of the peer that called the handshake
+// from the connection with the given remote address.
+func (n *Node) getPeerByRemoteAddr(remoteAddr string) (string, bool) {
+	n.peersLock.RLock()
+	defer n.peersLock.RUnlock()
+
+	for listenAddr, peer := range n.peers {
+		if peer.remoteAddr != "" && peer.remoteAddr == remoteAddr {
+			return listenAddr, true
+		}
+	}
+
+	return "", false
+}
+
+// runStatusLoop periodically exchanges node information with all connected peers, so the heights
+// of the peers are kept current. Peers that don't respond are removed. If any peer is ahead of
+// the local chain, a sync is started.
+func (n *Node) runStatusLoop() {
+	ticker := time.NewTicker(statusInterval)
+
+	for {
+		<-ticker.C
+
+		var (
+			nodeInfo = n.getNodeInfo()
+			isBehind = false
+		)
+
+		for _, peer := range n.getConnectedPeers() {
+			ctx, cancel := context.WithTimeout(context.Background(), statusInterval)
+			peerNodeInfo, err := peer.peerClient.Status(ctx, nodeInfo)
+			cancel()
+
+			if err != nil {
+				n.log.Error("failed to get peer status", "peer", peer.nodeInfo.ListenAddr, "error", err)
+				n.removePeer(peer.nodeInfo.ListenAddr)
+				continue
+			}
+
+			n.updatePeerInfo(peer.nodeInfo.ListenAddr, peerNodeInfo)
+
+			if peerNodeInfo.Height > nodeInfo.Height {
+				isBehind = true
+			}
+		}
+
+		if isBehind {
+			go n.syncManager.Sync(n.getConnectedPeers())
+		}
+	}
+}
+
 func (n *Node) runValidatorLoop() {
-	ticker := time.NewTicker(blockTime)
+	ticker := time.NewTicker(time.Duration(n.chain.Params().TargetBlockTime) * time.Second)
 	n.log.Debug("running validation loop", "pubKey", n.PrivateKey.Public().String())
 
 	for {
 		<-ticker.C
-		txList := n.mempool.Clear()
+
+		// Validators produce blocks in turns, stakers only when they are drawn. The block is created
+		// at the same time the turn is checked for, so it is valid even if the turn passes in between.
+		now := time.Now()
+		if !n.chain.IsNextProducer(n.PrivateKey.Public().Bytes(), now) {
+			continue
+		}
+
+		// The transactions stay in the mempool until the block is connected, so transactions spending
+		// their outputs are admitted meanwhile and the ones left out of the block are included later
 		n.mempool.ClearProcessed(time.Minute)
+		txList := n.mempool.Pending()
 		n.log.Debug("creating new block", "txs", len(txList))
 
-		// Construct a new block
-		// Add transactions from the mempool to the block
-		// Sign the block with the private key
-		// Add the block to the chain
-		// Broadcast the block to all known peers
+		block := n.createBlock(txList, now)
+
+		if err := n.chain.AddBlock(block); err != nil {
+			n.log.Error("failed to add block to the chain", "error", err)
+			continue
+		}
+
+		n.log.Debug("added new block", "height", block.Header.Height, "hash", types.HashBlockString(block), "txs", len(block.Transactions))
+		n.mempool.Remove(block.Transactions)
+
+		votes := n.finality.blockConnected(block)
+
+		if err := n.broadcast(block); err != nil {
+			n.log.Error("failed to broadcast block", "error", err)
+		}
+		n.broadcastVotes(votes)
 	}
 }
 
+// createBlock constructs a new block produced at the given time on top of the current chain tip from the given
+// transactions and signs it with the node private key. Transactions that fail validation or don't fit are left out.
+// The block subsidy and the transaction fees are paid to the node address.
+func (n *Node) createBlock(txList []*genproto.Transaction, now time.Time) *genproto.Block {
+	block := n.chain.NewBlockTemplateAt(txList, n.PrivateKey.Public().Address().Bytes(), now)
+
+	if leftOut := len(txList) - len(block.Transactions) + 1; leftOut > 0 {
+		n.log.Debug("left out transactions", "count", leftOut)
+	}
+
+	// SignBlock calculates the Merkle root of the transactions before signing
+	n.chain.SetStakeSeed(block, *n.PrivateKey)
+	types.SignBlock(*n.PrivateKey, block)
+
+	return block
+}
+
 // broadcast broadcasts message to all known peers
 func (n *Node) broadcast(msg any) error {
 	n.peersLock.RLock()
@@ -222,6 +520,28 @@ func (n *Node) broadcast(msg any) error {
 				}
 			}(peer)
 		}
+	case *genproto.Block:
+		for _, peer := range n.peers {
+			wg.Add(1)
+			go func(peer ConnectedPeer) {
+				defer wg.Done()
+				_, err := peer.peerClient.HandleBlock(ctx, v)
+				if err != nil {
+					n.log.Error("failed to broadcast block to peer", "peer", peer.nodeInfo.ListenAddr, "error", err)
+				}
+			}(peer)
+		}
+	case *genproto.Vote:
+		for _, peer := range n.peers {
+			wg.Add(1)
+			go func(peer ConnectedPeer) {
+				defer wg.Done()
+				_, err := peer.peerClient.HandleVote(ctx, v)
+				if err != nil {
+					n.log.Error("failed to broadcast vote to peer", "peer", peer.nodeInfo.ListenAddr, "error", err)
+				}
+			}(peer)
+		}
 	default:
 		n.log.Error("unsupported message type for broadcast", "type", fmt.Sprintf("%T", msg))
 		return fmt.Errorf("unsupported message type: %T", msg)
@@ -231,6 +551,14 @@ func (n *Node) broadcast(msg any) error {
 	return nil
 }
 
+func (n *Node) broadcastVotes(votes []*genproto.Vote) {
+	for _, vote := range votes {
+		if err := n.broadcast(vote); err != nil {
+			n.log.Error("failed to broadcast vote", "error", err)
+		}
+	}
+}
+
 func (n *Node) getAbsentPeerList(peerAddrList []string) []string {
 	absentPeerAddresses := []string{}
 
@@ -258,13 +586,48 @@ func (n *Node) getAbsentPeerList(peerAddrList []string) []string {
 	return absentPeerAddresses
 }
 
+// getRangeStartHeight returns the height of the first block of the requested range.
+func (n *Node) getRangeStartHeight(req *genproto.BlockRangeRequest) (int, error) {
+	if len(req.FromHash) > 0 {
+		return n.chain.GetBlockHeight(req.FromHash)
+	}
+
+	if req.FromHeight < 0 {
+		return 0, fmt.Errorf("invalid block height %d", req.FromHeight)
+	}
+
+	return int(req.FromHeight), nil
+}
+
 func (n *Node) getNodeInfo() *genproto.NodeInfo {
+	height, bestHash := n.chain.Tip()
+
 	return &genproto.NodeInfo{
-		Version:    n.Version,
-		Height:     0,
-		ListenAddr: n.ListenAddr,
-		PeerList:   n.getPeerList(),
+		Version:     n.Version,
+		Height:      int32(height),
+		ListenAddr:  n.ListenAddr,
+		PeerList:    n.getPeerList(),
+		BestHash:    bestHash,
+		GenesisHash: n.chain.GenesisHash(),
+		ChainId:     n.chain.Params().ChainID,
+	}
+}
+
+// checkPeerCompatibility checks that the peer runs the same node version on the same chain.
+func checkPeerCompatibility(thisNodeInfo, peerNodeInfo *genproto.NodeInfo) error {
+	if thisNodeInfo.Version != peerNodeInfo.Version {
+		return fmt.Errorf("incompatible node versions")
+	}
+
+	if !bytes.Equal(thisNodeInfo.GenesisHash, peerNodeInfo.GenesisHash) {
+		return fmt.Errorf("different genesis blocks")
+	}
+
+	if thisNodeInfo.ChainId != peerNodeInfo.ChainId {
+		return fmt.Errorf("different chain IDs")
 	}
+
+	return nil
 }
 
 func (n *Node) dialPeerNode(peerListenAddr string) (genproto.NodeClient, *genproto.NodeInfo, error) {
@@ -273,12 +636,19 @@ func (n *Node) dialPeerNode(peerListenAddr string) (genproto.NodeClient, *genpro
 		return nil, nil, err
 	}
 
-	peerNodeInfo, err := peerClient.Handshake(context.TODO(), n.getNodeInfo())
+	thisNodeInfo := n.getNodeInfo()
+
+	peerNodeInfo, err := peerClient.Handshake(context.TODO(), thisNodeInfo)
 	if err != nil {
 		n.log.Debug("handshake error", "error", err)
 		return nil, nil, err
 	}
 
+	if err := checkPeerCompatibility(thisNodeInfo, peerNodeInfo); err != nil {
+		n.log.Debug("refused peer", "peer", peerListenAddr, "error", err)
+		return nil, nil, err
+	}
+
 	return peerClient, peerNodeInfo, nil
 }
 
@@ -294,6 +664,18 @@ func (n *Node) getPeerList() []string {
 	return peerList
 }
 
+func (n *Node) getConnectedPeers() []ConnectedPeer {
+	n.peersLock.RLock()
+	defer n.peersLock.RUnlock()
+
+	peers := make([]ConnectedPeer, 0, len(n.peers))
+	for _, peer := range n.peers {
+		peers = append(peers, peer)
+	}
+
+	return peers
+}
+
 func newNodeClient(listenSocketAddr string) (genproto.NodeClient, error) {
 	clientConn, err := grpc.NewClient("dns:///"+listenSocketAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
@@ -0,0 +1,239 @@
+package node
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/peer"
+)
+
+func newTestValidatorNode(t *testing.T) *Node {
+	privKey := cryptography.NewPrivateKey()
+	chain := newMemoryChain(t)
+
+	return NewNode(NodeConfig{
+		Version:    nodeVersion,
+		ListenAddr: ":0",
+		PrivateKey: &privKey,
+	}, chain)
+}
+
+func TestCreateBlock(t *testing.T) {
+	n := newTestValidatorNode(t)
+
+	genesisTx, err := n.chain.txStore.Get(genesisBlockTx0Hash)
+	require.Nil(t, err)
+
+	senderPrivKey := cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+	validTx := &genproto.Transaction{
+		Inputs: []*genproto.TxInput{
+			{
+				PrevTxHash:     types.HashTransactionBytes(genesisTx),
+				PrevTxOutIndex: 0,
+				PublicKey:      senderPrivKey.Public().Bytes(),
+			},
+		},
+		Outputs: []*genproto.TxOutput{
+			{
+				Amount:  genesisBlockAmount - testTxFee,
+				Address: cryptography.NewPrivateKey().Public().Address().Bytes(),
+			},
+		},
+	}
+	signTestTx(validTx, senderPrivKey, genesisBlockAmount)
+
+	// Unsigned transaction spending a nonexistent output
+	invalidTx := &genproto.Transaction{
+		Inputs: []*genproto.TxInput{
+			{
+				PrevTxHash: genesisTx.Outputs[0].Address,
+				PublicKey:  senderPrivKey.Public().Bytes(),
+			},
+		},
+	}
+
+	block := n.createBlock([]*genproto.Transaction{validTx, invalidTx}, time.Now())
+	require.NotNil(t, block)
+	require.Equal(t, int32(1), block.Header.Height)
+	require.Len(t, block.Transactions, 2)
+	require.Equal(t, validTx, block.Transactions[1])
+	require.True(t, types.VerifyBlock(block))
+
+	coinbaseTx := block.Transactions[0]
+	require.True(t, types.IsCoinbaseTransaction(coinbaseTx))
+	require.Equal(t, n.PrivateKey.Public().Address().Bytes(), coinbaseTx.Outputs[0].Address)
+	require.Equal(t, n.chain.Params().BlockSubsidy(1)+testTxFee, coinbaseTx.Outputs[0].Amount)
+
+	require.Nil(t, n.chain.AddBlock(block))
+	require.Equal(t, 1, n.chain.Height())
+}
+
+func TestCreateBlockWithoutValidTransactions(t *testing.T) {
+	n := newTestValidatorNode(t)
+
+	block := n.createBlock(nil, time.Now())
+	require.Len(t, block.Transactions, 1)
+
+	// A block with only the coinbase transaction is valid
+	require.Nil(t, n.chain.AddBlock(block))
+}
+
+func TestHandleBlock(t *testing.T) {
+	n := newTestValidatorNode(t)
+	n.syncManager.Sync(nil)
+
+	senderPrivKey := cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+	genesisTx, err := n.chain.txStore.Get(genesisBlockTx0Hash)
+	require.Nil(t, err)
+
+	tx := &genproto.Transaction{
+		Inputs: []*genproto.TxInput{
+			{
+				PrevTxHash:     types.HashTransactionBytes(genesisTx),
+				PrevTxOutIndex: 0,
+				PublicKey:      senderPrivKey.Public().Bytes(),
+			},
+		},
+		Outputs: []*genproto.TxOutput{
+			{
+				Amount:  genesisBlockAmount - testTxFee,
+				Address: cryptography.NewPrivateKey().Public().Address().Bytes(),
+			},
+		},
+	}
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+
+	require.True(t, n.mempool.Add(tx))
+
+	block := n.createBlock([]*genproto.Transaction{tx}, time.Now())
+
+	_, err = n.HandleBlock(context.Background(), block)
+	require.Nil(t, err)
+	require.Equal(t, 1, n.chain.Height())
+	require.Equal(t, 0, n.mempool.Size())
+	require.False(t, n.mempool.Add(tx))
+
+	// Relayed duplicates are ignored
+	_, err = n.HandleBlock(context.Background(), block)
+	require.Nil(t, err)
+	require.Equal(t, 1, n.chain.Height())
+
+	_, err = n.HandleBlock(context.Background(), &genproto.Block{Transactions: block.Transactions})
+	require.NotNil(t, err)
+	_, err = n.HandleBlock(context.Background(), &genproto.Block{Header: block.Header})
+	require.NotNil(t, err)
+}
+
+func TestHandleTransaction(t *testing.T) {
+	var (
+		n             = newTestValidatorNode(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+		ctx           = peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{}})
+	)
+	n.syncManager.Sync(nil)
+
+	// Transactions locked past the next block are not admitted to the mempool
+	tx := createGenesisSpendingTx(t, n.chain)
+	tx.LockTime = 1
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+
+	_, err := n.HandleTransaction(ctx, tx)
+	require.ErrorIs(t, err, ErrTransactionLocked)
+	require.Equal(t, 0, n.mempool.Size())
+
+	tx.Outputs[0].Amount = genesisBlockAmount + 1
+	_, err = n.HandleTransaction(ctx, tx)
+	require.NotNil(t, err)
+	require.Equal(t, 0, n.mempool.Size())
+
+	tx = createGenesisSpendingTx(t, n.chain)
+	_, err = n.HandleTransaction(ctx, tx)
+	require.Nil(t, err)
+	require.Equal(t, 1, n.mempool.Size())
+}
+
+func TestHandleUnconfirmedTransactionChain(t *testing.T) {
+	var (
+		n             = newTestValidatorNode(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+		ownerPrivKey  = cryptography.NewPrivateKey()
+		ctx           = peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{}})
+	)
+	n.syncManager.Sync(nil)
+
+	genesisTx, err := n.chain.txStore.Get(genesisBlockTx0Hash)
+	require.Nil(t, err)
+
+	parentTx := newSpendingTx(genesisTx, 0, senderPrivKey, &genproto.TxOutput{
+		Amount:  genesisBlockAmount - testTxFee,
+		Address: ownerPrivKey.Public().Address().Bytes(),
+	})
+	childTx := newSpendingTx(parentTx, 0, ownerPrivKey, &genproto.TxOutput{
+		Amount:  parentTx.Outputs[0].Amount - testTxFee,
+		Address: cryptography.NewPrivateKey().Public().Address().Bytes(),
+	})
+
+	// The child spends an output that is unknown until the parent is in the mempool
+	_, err = n.HandleTransaction(ctx, childTx)
+	require.NotNil(t, err)
+
+	_, err = n.HandleTransaction(ctx, parentTx)
+	require.Nil(t, err)
+	_, err = n.HandleTransaction(ctx, childTx)
+	require.Nil(t, err)
+	require.Equal(t, 2, n.mempool.Size())
+
+	// Outputs spent by a pending transaction can't be spent again
+	doubleSpendTx := newSpendingTx(genesisTx, 0, senderPrivKey, &genproto.TxOutput{
+		Amount:  genesisBlockAmount - 2*testTxFee,
+		Address: ownerPrivKey.Public().Address().Bytes(),
+	})
+	_, err = n.HandleTransaction(ctx, doubleSpendTx)
+	require.NotNil(t, err)
+	require.Equal(t, 2, n.mempool.Size())
+
+	// The block includes the parent before the child in whatever order they are pending
+	block := n.createBlock([]*genproto.Transaction{childTx, parentTx}, time.Now())
+	require.Equal(t, []*genproto.Transaction{parentTx, childTx}, block.Transactions[1:])
+	require.Nil(t, n.chain.AddBlock(block))
+}
+
+func TestStatusUpdatesPeerInfo(t *testing.T) {
+	var (
+		n        = newTestValidatorNode(t)
+		peerNode = newTestValidatorNode(t)
+	)
+
+	localPeer := newLocalPeer(peerNode)
+	localPeer.remoteAddr = "127.0.0.1:50001"
+	n.peers[peerNode.ListenAddr] = localPeer
+	peerCtx := func(remoteAddr string) context.Context {
+		addr, err := net.ResolveTCPAddr("tcp", remoteAddr)
+		require.Nil(t, err)
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+	}
+
+	block, err := createRandomSignedBlock(peerNode.chain, cryptography.NewPrivateKey())
+	require.Nil(t, err)
+	require.Nil(t, peerNode.chain.AddBlock(block))
+
+	peerNodeInfo := peerNode.getNodeInfo()
+	require.Equal(t, int32(1), peerNodeInfo.Height)
+	require.Equal(t, types.HashBlockBytes(block), peerNodeInfo.BestHash)
+
+	// Other connections can't update the peer by claiming its listen address
+	nodeInfo, err := n.Status(peerCtx("127.0.0.1:50002"), peerNodeInfo)
+	require.Nil(t, err)
+	require.Equal(t, int32(0), nodeInfo.Height)
+	require.Equal(t, int32(0), n.getConnectedPeers()[0].nodeInfo.Height)
+
+	_, err = n.Status(peerCtx(localPeer.remoteAddr), peerNodeInfo)
+	require.Nil(t, err)
+	require.Equal(t, peerNodeInfo, n.getConnectedPeers()[0].nodeInfo)
+}
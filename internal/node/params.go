@@ -0,0 +1,300 @@
+package node
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// genesisBlockSeed is the seed of the key that signs the genesis block.
+	// Ed25519 signatures are deterministic, so every node builds the same genesis block.
+	genesisBlockSeed      = "b69d0c49b336d58aca501f6a0ba60c933b5904bea73a6c288639b9c3c830627f"
+	genesisBlockVersion   = 1
+	genesisBlockHeight    = 0
+	genesisBlockAmount    = 1e6
+	genesisBlockTimestamp = 1725148800 // 2024-09-01T00:00:00Z
+
+	defaultInitialSubsidy   = 50
+	defaultHalvingInterval  = 210000
+	defaultCoinbaseMaturity = 100
+	defaultMinFeeRate       = 1
+	defaultPowLimitBits     = 0x1f00ffff
+	defaultTargetBlockTime  = 5
+	defaultProducerTimeout  = 2 * defaultTargetBlockTime
+	defaultRetargetInterval = 20
+	defaultUnbondingPeriod  = 100
+	defaultMaxBlockSize     = 1 << 20
+	defaultMaxBlockTxs      = 10000
+	defaultMaxBlockSigOps   = 20000
+	defaultChainID          = "blockchain-btc-local"
+)
+
+// ConsensusMode defines how the block producers are chosen.
+type ConsensusMode string
+
+const (
+	// ConsensusAuthority lets the nodes configured with a private key produce blocks on a fixed schedule.
+	// With a validator set only the validators produce blocks, in turns
+	ConsensusAuthority ConsensusMode = "authority"
+	// ConsensusProofOfWork lets any node produce a block by finding a block hash that meets the difficulty target
+	ConsensusProofOfWork ConsensusMode = "pow"
+	// ConsensusProofOfStake lets the stakers produce blocks, the proposer of every block is drawn in proportion to the stake
+	ConsensusProofOfStake ConsensusMode = "pos"
+)
+
+// ChainParams defines the rules every node of the network has to agree on.
+type ChainParams struct {
+	// ChainID identifies the network. Transaction signatures commit to it, so they can't be replayed on other networks.
+	ChainID   string        `json:"chainId"`
+	Genesis   GenesisParams `json:"genesis"`
+	Consensus ConsensusMode `json:"consensus"`
+	// Validators are the hex encoded public keys of the initial validators (authority mode only).
+	// Without validators any node can produce blocks.
+	Validators []string `json:"validators"`
+	// PowLimitBits is the compact representation of the easiest difficulty target (proof-of-work only)
+	PowLimitBits uint32 `json:"powLimitBits"`
+	// TargetBlockTime is the expected time between blocks in seconds
+	TargetBlockTime int64 `json:"targetBlockTime"`
+	// ProducerTimeout is the time in seconds after which the turn of a scheduled block producer passes to the next one
+	// (authority and proof-of-stake only)
+	ProducerTimeout int64 `json:"producerTimeout"`
+	// RetargetInterval is the number of blocks after which the difficulty target is adjusted (proof-of-work only)
+	RetargetInterval int `json:"retargetInterval"`
+	// InitialSubsidy is the amount of new coins a block producer receives for a block
+	InitialSubsidy int64 `json:"initialSubsidy"`
+	// HalvingInterval is the number of blocks after which the subsidy is halved
+	HalvingInterval int `json:"halvingInterval"`
+	// CoinbaseMaturity is the number of blocks after which coinbase outputs can be spent
+	CoinbaseMaturity int `json:"coinbaseMaturity"`
+	// UnbondingPeriod is the number of blocks after which unbonded stake can be spent (proof-of-stake only)
+	UnbondingPeriod int `json:"unbondingPeriod"`
+	// MinFeeRate is the minimum fee a transaction has to pay per byte of its serialized size
+	MinFeeRate int64 `json:"minFeeRate"`
+	// MaxBlockSize is the maximum serialized size of a block in bytes
+	MaxBlockSize int `json:"maxBlockSize"`
+	// MaxBlockTransactions is the maximum number of transactions in a block, including the coinbase transaction
+	MaxBlockTransactions int `json:"maxBlockTransactions"`
+	// MaxBlockSigOps is the maximum number of signature checks the transactions of a block require
+	MaxBlockSigOps int `json:"maxBlockSigOps"`
+}
+
+// GenesisParams defines the genesis block of the chain.
+type GenesisParams struct {
+	Version int32 `json:"version"`
+	// Timestamp is the Unix timestamp of the genesis block
+	Timestamp   int64               `json:"timestamp"`
+	Allocations []GenesisAllocation `json:"allocations"`
+}
+
+// GenesisAllocation is an output of the genesis transaction.
+type GenesisAllocation struct {
+	// Address is the hex encoded address of the recipient
+	Address string `json:"address"`
+	Amount  int64  `json:"amount"`
+}
+
+// GenesisPrivateKey returns the key that signs the genesis block and owns the genesis allocation of the default
+// network. Its seed is public, so it is only good for spending the coins of local test networks.
+func GenesisPrivateKey() cryptography.PrivateKey {
+	return cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+}
+
+// DefaultChainParams returns the parameters of the default network. The whole genesis amount
+// is allocated to the address of the genesis block key.
+func DefaultChainParams() *ChainParams {
+	privKey := GenesisPrivateKey()
+
+	return &ChainParams{
+		Genesis: GenesisParams{
+			Version:   genesisBlockVersion,
+			Timestamp: genesisBlockTimestamp,
+			Allocations: []GenesisAllocation{
+				{
+					Address: privKey.Public().Address().String(),
+					Amount:  genesisBlockAmount,
+				},
+			},
+		},
+		ChainID:              defaultChainID,
+		Consensus:            ConsensusAuthority,
+		PowLimitBits:         defaultPowLimitBits,
+		TargetBlockTime:      defaultTargetBlockTime,
+		ProducerTimeout:      defaultProducerTimeout,
+		RetargetInterval:     defaultRetargetInterval,
+		InitialSubsidy:       defaultInitialSubsidy,
+		HalvingInterval:      defaultHalvingInterval,
+		CoinbaseMaturity:     defaultCoinbaseMaturity,
+		UnbondingPeriod:      defaultUnbondingPeriod,
+		MinFeeRate:           defaultMinFeeRate,
+		MaxBlockSize:         defaultMaxBlockSize,
+		MaxBlockTransactions: defaultMaxBlockTxs,
+		MaxBlockSigOps:       defaultMaxBlockSigOps,
+	}
+}
+
+// LoadChainParams reads the chain parameters from a JSON genesis file.
+// Parameters missing in the file keep their default values.
+func LoadChainParams(path string) (*ChainParams, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read genesis file: %w", err)
+	}
+
+	params := DefaultChainParams()
+	if err := json.Unmarshal(b, params); err != nil {
+		return nil, fmt.Errorf("failed to decode genesis file: %w", err)
+	}
+
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid genesis file: %w", err)
+	}
+
+	return params, nil
+}
+
+// Validate checks that the genesis block can be built from the parameters and that the consensus rules are consistent.
+func (p *ChainParams) Validate() error {
+	switch p.Consensus {
+	case ConsensusAuthority:
+		for idx, validator := range p.Validators {
+			pubKey, err := hex.DecodeString(validator)
+			if err != nil || len(pubKey) != cryptography.PubKeyLen {
+				return fmt.Errorf("validator %d has an invalid public key %q", idx, validator)
+			}
+
+			if slices.Contains(p.Validators[:idx], validator) {
+				return fmt.Errorf("validator %d is a duplicate", idx)
+			}
+		}
+		if p.ProducerTimeout <= p.TargetBlockTime {
+			return fmt.Errorf("producer timeout is not longer than the target block time")
+		}
+	case ConsensusProofOfWork:
+		if len(p.Validators) > 0 {
+			return fmt.Errorf("validators are only allowed in the authority consensus mode")
+		}
+		if types.CompactToTarget(p.PowLimitBits).Sign() <= 0 {
+			return fmt.Errorf("invalid proof-of-work limit %#x", p.PowLimitBits)
+		}
+		// The first retarget measures the time since the genesis block, which takes one block at least
+		if p.RetargetInterval < 2 {
+			return fmt.Errorf("retarget interval %d is shorter than 2 blocks", p.RetargetInterval)
+		}
+	case ConsensusProofOfStake:
+		if len(p.Validators) > 0 {
+			return fmt.Errorf("validators are only allowed in the authority consensus mode")
+		}
+		if p.UnbondingPeriod < 0 {
+			return fmt.Errorf("negative unbonding period")
+		}
+		if p.ProducerTimeout <= p.TargetBlockTime {
+			return fmt.Errorf("producer timeout is not longer than the target block time")
+		}
+	default:
+		return fmt.Errorf("unknown consensus mode %q", p.Consensus)
+	}
+
+	if p.ChainID == "" {
+		return fmt.Errorf("empty chain ID")
+	}
+
+	if p.TargetBlockTime <= 0 {
+		return fmt.Errorf("non-positive target block time")
+	}
+
+	if p.InitialSubsidy < 0 || p.HalvingInterval <= 0 || p.CoinbaseMaturity < 0 {
+		return fmt.Errorf("invalid block subsidy parameters")
+	}
+
+	if p.MinFeeRate < 0 {
+		return fmt.Errorf("negative minimum fee rate")
+	}
+
+	if p.MaxBlockSize <= 0 || p.MaxBlockTransactions <= 0 || p.MaxBlockSigOps < 0 {
+		return fmt.Errorf("invalid block limits")
+	}
+
+	if len(p.Genesis.Allocations) == 0 {
+		return fmt.Errorf("genesis has no allocations")
+	}
+
+	for idx, allocation := range p.Genesis.Allocations {
+		address, err := hex.DecodeString(allocation.Address)
+		if err != nil || len(address) != cryptography.AddressLen {
+			return fmt.Errorf("genesis allocation %d has an invalid address %q", idx, allocation.Address)
+		}
+
+		if allocation.Amount <= 0 {
+			return fmt.Errorf("genesis allocation %d has a non-positive amount", idx)
+		}
+	}
+
+	return nil
+}
+
+// BlockSubsidy returns the amount of new coins the producer of the block at the given height receives.
+// The subsidy is halved every HalvingInterval blocks.
+func (p *ChainParams) BlockSubsidy(height int) int64 {
+	halvings := height / p.HalvingInterval
+	if halvings >= 63 {
+		return 0
+	}
+
+	return p.InitialSubsidy >> halvings
+}
+
+// MinTransactionFee returns the minimum fee the transaction has to pay to be included in a block.
+// The fee depends on the serialized size of the signed transaction.
+func (p *ChainParams) MinTransactionFee(tx *genproto.Transaction) int64 {
+	return p.MinFeeRate * int64(proto.Size(tx))
+}
+
+// GenesisHash returns the hash of the genesis block defined by the parameters.
+func (p *ChainParams) GenesisHash() []byte {
+	return types.HashBlockBytes(createGenesisBlock(p))
+}
+
+// createGenesisBlock builds the genesis block from the chain parameters. The parameters are expected to be valid.
+func createGenesisBlock(params *ChainParams) *genproto.Block {
+	privKey := cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+	block := &genproto.Block{
+		Header: &genproto.BlockHeader{
+			Version:   params.Genesis.Version,
+			Height:    genesisBlockHeight,
+			Timestamp: params.Genesis.Timestamp,
+		},
+	}
+
+	// Blocks following the genesis block start at the easiest difficulty
+	if params.Consensus == ConsensusProofOfWork {
+		block.Header.Bits = params.PowLimitBits
+	}
+
+	tx := &genproto.Transaction{
+		Version: params.Genesis.Version,
+		Inputs:  []*genproto.TxInput{},
+		Outputs: make([]*genproto.TxOutput, len(params.Genesis.Allocations)),
+	}
+
+	for idx, allocation := range params.Genesis.Allocations {
+		address, _ := hex.DecodeString(allocation.Address)
+		tx.Outputs[idx] = &genproto.TxOutput{
+			Amount:  allocation.Amount,
+			Address: address,
+		}
+	}
+
+	block.Transactions = append(block.Transactions, tx)
+
+	types.SignBlock(privKey, block)
+
+	return block
+}
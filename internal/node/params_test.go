@@ -0,0 +1,145 @@
+package node
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+const testGenesisFile = `{
+	"genesis": {
+		"version": 1,
+		"timestamp": 1700000000,
+		"allocations": [
+			{"address": "a6461be4eac9ff331cfa7709f657ab1094064007", "amount": 500},
+			{"address": "2a6f0d73653215771de243a63ac048a18b59da29", "amount": 700}
+		]
+	}
+}`
+
+func TestGenesisBlockIsDeterministic(t *testing.T) {
+	chain1 := newMemoryChain(t)
+	chain2 := newMemoryChain(t)
+
+	genesisBlock1, err := chain1.GetBlockByHeight(0)
+	require.Nil(t, err)
+	genesisBlock2, err := chain2.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	require.Equal(t, types.HashBlockBytes(genesisBlock1), types.HashBlockBytes(genesisBlock2))
+	require.Equal(t, DefaultChainParams().GenesisHash(), chain1.GenesisHash())
+	require.Equal(t, int64(genesisBlockTimestamp), genesisBlock1.Header.Timestamp)
+}
+
+func TestLoadChainParams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.Nil(t, os.WriteFile(path, []byte(testGenesisFile), 0600))
+
+	params, err := LoadChainParams(path)
+	require.Nil(t, err)
+	require.Equal(t, int64(1700000000), params.Genesis.Timestamp)
+	require.Len(t, params.Genesis.Allocations, 2)
+
+	chain, err := NewChain(params, NewMemoryStore())
+	require.Nil(t, err)
+	require.NotEqual(t, DefaultChainParams().GenesisHash(), chain.GenesisHash())
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	utxo, err := chain.utxoStore.Get(getUTXOKey(types.HashTransactionString(genesisBlock.Transactions[0]), 1))
+	require.Nil(t, err)
+	require.Equal(t, int64(700), utxo.Amount)
+}
+
+func TestLoadInvalidChainParams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	require.Nil(t, os.WriteFile(path, []byte(`{"genesis": {"allocations": [{"address": "xyz", "amount": 1}]}}`), 0600))
+
+	_, err := LoadChainParams(path)
+	require.NotNil(t, err)
+
+	params := DefaultChainParams()
+	params.Consensus = "unknown"
+	require.NotNil(t, params.Validate())
+
+	params.Consensus = ConsensusProofOfWork
+	params.PowLimitBits = 0
+	require.NotNil(t, params.Validate())
+
+	params = newProofOfWorkChainParams()
+	params.RetargetInterval = 1
+	require.NotNil(t, params.Validate())
+
+	params = DefaultChainParams()
+	validator := cryptography.NewPrivateKey().Public().String()
+	params.Validators = []string{validator, validator}
+	require.NotNil(t, params.Validate())
+
+	// The turn of a producer has to last longer than the time between blocks
+	params = DefaultChainParams()
+	params.ProducerTimeout = params.TargetBlockTime
+	require.NotNil(t, params.Validate())
+}
+
+func TestNewChainWithDifferentGenesis(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chain.db")
+	store := openTestDiskStore(t, path)
+	defer store.Close()
+
+	_, err := NewChain(DefaultChainParams(), store)
+	require.Nil(t, err)
+
+	params := DefaultChainParams()
+	params.Genesis.Timestamp++
+
+	_, err = NewChain(params, store)
+	require.ErrorIs(t, err, ErrGenesisMismatch)
+}
+
+func TestHandshakeWithDifferentGenesis(t *testing.T) {
+	n := newTestValidatorNode(t)
+
+	params := DefaultChainParams()
+	params.Genesis.Allocations[0].Address = cryptography.NewPrivateKey().Public().Address().String()
+
+	peerChain, err := NewChain(params, NewMemoryStore())
+	require.Nil(t, err)
+	peer := NewNode(NodeConfig{Version: nodeVersion, ListenAddr: ":1"}, peerChain)
+
+	_, err = n.Handshake(context.Background(), peer.getNodeInfo())
+	require.NotNil(t, err)
+	require.Empty(t, n.getPeerList())
+}
+
+func TestHandshakeWithDifferentChainID(t *testing.T) {
+	n := newTestValidatorNode(t)
+
+	params := DefaultChainParams()
+	params.ChainID = "other-network"
+
+	peerChain, err := NewChain(params, NewMemoryStore())
+	require.Nil(t, err)
+	require.Equal(t, n.chain.GenesisHash(), peerChain.GenesisHash())
+	peer := NewNode(NodeConfig{Version: nodeVersion, ListenAddr: ":1"}, peerChain)
+
+	_, err = n.Handshake(context.Background(), peer.getNodeInfo())
+	require.NotNil(t, err)
+	require.Empty(t, n.getPeerList())
+}
+
+func TestBlockSubsidy(t *testing.T) {
+	params := DefaultChainParams()
+	params.HalvingInterval = 10
+
+	require.Equal(t, int64(defaultInitialSubsidy), params.BlockSubsidy(1))
+	require.Equal(t, int64(defaultInitialSubsidy), params.BlockSubsidy(9))
+	require.Equal(t, int64(defaultInitialSubsidy/2), params.BlockSubsidy(10))
+	require.Equal(t, int64(defaultInitialSubsidy/4), params.BlockSubsidy(25))
+	require.Equal(t, int64(0), params.BlockSubsidy(10*64))
+}
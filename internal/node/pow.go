@@ -0,0 +1,68 @@
+package node
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+)
+
+// retargetFactor limits the difficulty adjustment in a single retarget interval.
+const retargetFactor = 4
+
+// requiredBits returns the difficulty target bits of the block following the parent block.
+//
+// The target is adjusted every RetargetInterval blocks, so that the blocks of the last interval
+// would have taken TargetBlockTime each. The adjustment is limited to a factor of 4 per interval
+// and the target never exceeds the proof-of-work limit.
+func (c *Chain) requiredBits(parent *blockNode) uint32 {
+	if c.params.Consensus != ConsensusProofOfWork {
+		return 0
+	}
+
+	interval := c.params.RetargetInterval
+	if (parent.height+1)%interval != 0 {
+		return parent.header.Bits
+	}
+
+	first := parent.ancestor(max(parent.height-interval, 0))
+
+	expectedTimespan := int64(parent.height-first.height) * c.params.TargetBlockTime
+	actualTimespan := parent.header.Timestamp - first.header.Timestamp
+	actualTimespan = max(actualTimespan, expectedTimespan/retargetFactor)
+	actualTimespan = min(actualTimespan, expectedTimespan*retargetFactor)
+
+	target := types.CompactToTarget(parent.header.Bits)
+	target.Mul(target, big.NewInt(actualTimespan))
+	target.Div(target, big.NewInt(expectedTimespan))
+
+	if powLimit := types.CompactToTarget(c.params.PowLimitBits); target.Cmp(powLimit) > 0 {
+		return c.params.PowLimitBits
+	}
+
+	return types.TargetToCompact(target)
+}
+
+// checkProofOfWork checks that the header has the difficulty target required after the parent block
+// and that the header hash meets it. Outside of the proof-of-work mode headers must not have a target.
+func (c *Chain) checkProofOfWork(header *genproto.BlockHeader, parent *blockNode) error {
+	hash := types.HashBlockHeader(header)
+
+	if bits := c.requiredBits(parent); header.Bits != bits {
+		return fmt.Errorf("block with hash %x has difficulty bits %#x, expected %#x", hash, header.Bits, bits)
+	}
+
+	if c.params.Consensus != ConsensusProofOfWork {
+		if header.Nonce != 0 {
+			return fmt.Errorf("block with hash %x has a nonce outside of the proof-of-work mode", hash)
+		}
+		return nil
+	}
+
+	if !types.VerifyProofOfWork(header) {
+		return fmt.Errorf("block with hash %x doesn't meet the difficulty target", hash)
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package node
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+// testPowLimitBits is a difficulty target that is met by every second hash on average.
+const testPowLimitBits = 0x207fffff
+
+func newProofOfWorkChainParams() *ChainParams {
+	params := DefaultChainParams()
+	params.Consensus = ConsensusProofOfWork
+	params.PowLimitBits = testPowLimitBits
+
+	return params
+}
+
+func TestMineBlock(t *testing.T) {
+	var (
+		chain   = newTestChain(t, newProofOfWorkChainParams())
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	require.Equal(t, uint32(testPowLimitBits), genesisBlock.Header.Bits)
+
+	for i := 1; i <= 5; i++ {
+		block := newTestBlock(t, chain, []cryptography.PrivateKey{privKey})
+		require.Equal(t, uint32(testPowLimitBits), block.Header.Bits)
+		require.True(t, types.VerifyProofOfWork(block.Header))
+		require.Nil(t, chain.AddBlock(block))
+		require.Equal(t, i, chain.Height())
+	}
+}
+
+func TestMineBlockStale(t *testing.T) {
+	header := &genproto.BlockHeader{
+		Version: blockVersion,
+		// The target is almost impossible to meet
+		Bits: 0x03000001,
+	}
+
+	require.False(t, mineBlock(header, 4, func() bool { return true }))
+	require.Equal(t, uint64(0), header.Nonce)
+}
+
+func TestMinerKeepsLeftoverTransactions(t *testing.T) {
+	privKey := cryptography.NewPrivateKey()
+	n := NewNode(NodeConfig{
+		Version:      nodeVersion,
+		ListenAddr:   ":0",
+		PrivateKey:   &privKey,
+		MinerWorkers: 2,
+	}, newTestChain(t, newProofOfWorkChainParams()))
+
+	ownerKey := cryptography.NewPrivateKey()
+	tx := createGenesisSpendingTx(t, n.chain)
+	tx.Outputs[0].Address = ownerKey.Public().Address().Bytes()
+	signTestTx(tx, cryptography.NewPrivateKeyFromString(genesisBlockSeed), genesisBlockAmount)
+
+	// The child transaction is locked, so it is left out of the next block
+	childTx := newSpendingTx(tx, 0, ownerKey, &genproto.TxOutput{
+		Amount:  tx.Outputs[0].Amount - testTxFee,
+		Address: ownerKey.Public().Address().Bytes(),
+	})
+	childTx.LockTime = 5
+	signTestTx(childTx, ownerKey, tx.Outputs[0].Amount)
+
+	require.True(t, n.mempool.Add(tx))
+	require.True(t, n.mempool.Add(childTx))
+
+	block := n.mineNextBlock()
+	require.NotNil(t, block)
+	require.Len(t, block.Transactions, 2)
+	require.Equal(t, 1, n.chain.Height())
+
+	require.Equal(t, 1, n.mempool.Size())
+	require.Equal(t, []*genproto.Transaction{childTx}, n.mempool.Pending())
+}
+
+func TestAddBlockWithInsufficientWork(t *testing.T) {
+	var (
+		chain   = newTestChain(t, newProofOfWorkChainParams())
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	block := newTestBlock(t, chain, []cryptography.PrivateKey{privKey})
+
+	// Find a nonce that doesn't meet the target
+	for types.VerifyProofOfWork(block.Header) {
+		block.Header.Nonce++
+	}
+	types.SignBlock(privKey, block)
+	require.NotNil(t, chain.AddBlock(block))
+
+	// The block must have the required target
+	block = chain.NewBlockTemplate(nil, privKey.Public().Address().Bytes())
+	block.Header.Bits = 0x2100ffff
+	for !types.VerifyProofOfWork(block.Header) {
+		block.Header.Nonce++
+	}
+	types.SignBlock(privKey, block)
+	require.NotNil(t, chain.AddBlock(block))
+}
+
+func TestProofOfWorkChainWork(t *testing.T) {
+	var (
+		chain   = newTestChain(t, newProofOfWorkChainParams())
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	block := newTestBlock(t, chain, []cryptography.PrivateKey{privKey})
+	require.Nil(t, chain.AddBlock(block))
+
+	node, ok := chain.blockIndex.Get(types.HashBlockString(block))
+	require.True(t, ok)
+	require.Equal(t, int64(4), node.chainWork.Int64())
+
+	// Headers with a target are rejected outside of the proof-of-work mode
+	authorityChain := newMemoryChain(t)
+	block = authorityChain.NewBlockTemplate(nil, privKey.Public().Address().Bytes())
+	block.Header.Bits = testPowLimitBits
+	types.SignBlock(privKey, block)
+	require.NotNil(t, authorityChain.AddBlock(block))
+}
+
+func TestDifficultyRetarget(t *testing.T) {
+	params := newProofOfWorkChainParams()
+	params.RetargetInterval = 4
+	params.TargetBlockTime = 5
+
+	var (
+		chain     = newTestChain(t, params)
+		privKey   = cryptography.NewPrivateKey()
+		timestamp = params.Genesis.Timestamp
+		powLimit  = types.CompactToTarget(testPowLimitBits)
+	)
+
+	addBlocks := func(count int, spacing int64) {
+		for i := 0; i < count; i++ {
+			timestamp += spacing
+			require.Nil(t, chain.AddBlock(newTestBlockAt(t, chain, []cryptography.PrivateKey{privKey}, time.Unix(timestamp, 0))))
+		}
+	}
+
+	// The target can't get easier than the proof-of-work limit
+	addBlocks(7, 50)
+	require.Equal(t, 7, chain.Height())
+	require.Equal(t, uint32(testPowLimitBits), chain.requiredBits(chain.tipNode()))
+
+	// Blocks 4 to 7 took 8 seconds instead of 20, so the target gets 2.5 times harder
+	chain = newTestChain(t, params)
+	timestamp = params.Genesis.Timestamp
+	addBlocks(3, 5)
+	addBlocks(4, 2)
+
+	expectedTarget := new(big.Int).Mul(powLimit, big.NewInt(8))
+	expectedTarget.Div(expectedTarget, big.NewInt(20))
+	require.Equal(t, types.TargetToCompact(expectedTarget), chain.requiredBits(chain.tipNode()))
+
+	// Blocks that took 1/5 of the target time make the target harder by 4 times at most
+	chain = newTestChain(t, params)
+	timestamp = params.Genesis.Timestamp
+	addBlocks(3, 5)
+	addBlocks(4, 1)
+
+	expectedTarget = new(big.Int).Div(powLimit, big.NewInt(retargetFactor))
+	bits := chain.requiredBits(chain.tipNode())
+	require.Equal(t, types.TargetToCompact(expectedTarget), bits)
+
+	// A block with the previous target is rejected
+	block := chain.NewBlockTemplate(nil, privKey.Public().Address().Bytes())
+	block.Header.Timestamp = timestamp + 1
+	block.Header.Bits = testPowLimitBits
+	for !types.VerifyProofOfWork(block.Header) {
+		block.Header.Nonce++
+	}
+	types.SignBlock(privKey, block)
+	require.NotNil(t, chain.AddBlock(block))
+
+	addBlocks(1, 1)
+	require.Equal(t, bits, chain.tipNode().header.Bits)
+
+	// The target is kept between the retarget heights
+	addBlocks(3, 100)
+	require.Equal(t, bits, chain.tipNode().header.Bits)
+
+	// Slow blocks make the target easier by 4 times at most
+	expectedTarget = types.CompactToTarget(bits)
+	expectedTarget.Mul(expectedTarget, big.NewInt(retargetFactor))
+	require.Equal(t, types.TargetToCompact(expectedTarget), chain.requiredBits(chain.tipNode()))
+}
+
+func TestDifficultyRetargetEveryTwoBlocks(t *testing.T) {
+	params := newProofOfWorkChainParams()
+	params.RetargetInterval = 2
+	require.Nil(t, params.Validate())
+
+	var (
+		chain     = newTestChain(t, params)
+		privKey   = cryptography.NewPrivateKey()
+		timestamp = params.Genesis.Timestamp
+	)
+
+	// The shortest interval retargets on every second block, starting with the span of the first block
+	for range 4 {
+		timestamp += params.TargetBlockTime
+		require.Nil(t, chain.AddBlock(newTestBlockAt(t, chain, []cryptography.PrivateKey{privKey}, time.Unix(timestamp, 0))))
+	}
+	require.Equal(t, uint32(testPowLimitBits), chain.requiredBits(chain.tipNode()))
+}
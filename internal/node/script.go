@@ -0,0 +1,148 @@
+package node
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/script"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+)
+
+// verifyTransactionInput checks that the input of the transaction may spend the UTXO. Outputs with a lock script are unlocked by the unlock script of the input, multisig outputs
+// by the signatures of their policy and all other outputs by a signature of the key owning the output address.
+func (c *Chain) verifyTransactionInput(tx *genproto.Transaction, idx int, utxo *UTXO, spentAmounts []int64) error {
+	input := tx.Inputs[idx]
+	hash := types.HashTransactionString(tx)
+
+	if len(utxo.LockScript) != 0 {
+		// The signatures of script inputs are a part of the unlock script
+		if len(input.PublicKey) != 0 || len(input.Signature) != 0 || input.Multisig != nil || len(input.MultisigSignatures) != 0 {
+			return fmt.Errorf("input %d of transaction with hash %s spends a script output, but has signatures outside of the unlock script", idx, hash)
+		}
+
+		checker := &inputChecker{
+			tx:           tx,
+			idx:          idx,
+			spentAmounts: spentAmounts,
+			chainID:      c.params.ChainID,
+		}
+
+		if err := script.Verify(input.UnlockScript, utxo.LockScript, checker); err != nil {
+			return fmt.Errorf("input %d of transaction with hash %s failed to unlock utxo %s:%d: %w", idx, hash, utxo.Hash, utxo.OutIndex, err)
+		}
+
+		return nil
+	}
+
+	if len(input.UnlockScript) != 0 {
+		return fmt.Errorf("input %d of transaction with hash %s has an unlock script, but utxo %s:%d has no lock script",
+			idx, hash, utxo.Hash, utxo.OutIndex)
+	}
+
+	var address []byte
+	if utxo.Type == genproto.OutputType_MULTISIG {
+		if input.Multisig == nil || len(input.PublicKey) != 0 || len(input.Signature) != 0 {
+			return fmt.Errorf("input %d of transaction with hash %s spends a multisig output without a multisig policy", idx, hash)
+		}
+		address = types.MultisigAddress(input.Multisig)
+	} else {
+		if input.Multisig != nil || len(input.MultisigSignatures) != 0 {
+			return fmt.Errorf("input %d of transaction with hash %s has a multisig policy, but utxo %s:%d is not a multisig output",
+				idx, hash, utxo.Hash, utxo.OutIndex)
+		}
+		if len(input.PublicKey) == cryptography.PubKeyLen {
+			address = cryptography.NewPublicKeyFromBytes(input.PublicKey).Address().Bytes()
+		}
+	}
+
+	if !types.VerifyTransactionInput(tx, idx, spentAmounts, c.params.ChainID) {
+		return fmt.Errorf("transaction with hash %s is invalid", hash)
+	}
+
+	// A valid signature only proves that the input was signed by its own public key or policy,
+	// which also has to own the spent output
+	if !bytes.Equal(address, utxo.Address) {
+		return fmt.Errorf("input %d of transaction with hash %s spends utxo %s:%d of another address",
+			idx, hash, utxo.Hash, utxo.OutIndex)
+	}
+
+	return nil
+}
+
+// checkLockScripts checks that the outputs with a lock script are plain transfers without an address
+// and that the scripts are not too large. The scripts are only run when the outputs are spent.
+func checkLockScripts(tx *genproto.Transaction) error {
+	hash := types.HashTransactionString(tx)
+
+	for idx, output := range tx.Outputs {
+		if len(output.LockScript) == 0 {
+			continue
+		}
+
+		if output.Type != genproto.OutputType_TRANSFER || len(output.Address) != 0 {
+			return fmt.Errorf("output %d of transaction with hash %s has a lock script, but is not a transfer without an address", idx, hash)
+		}
+
+		if len(output.LockScript) > script.MaxScriptSize {
+			return fmt.Errorf("output %d of transaction with hash %s has a lock script of %d bytes, the limit is %d",
+				idx, hash, len(output.LockScript), script.MaxScriptSize)
+		}
+	}
+
+	return nil
+}
+
+// inputChecker checks the signatures and lock times of the scripts against the spending input.
+type inputChecker struct {
+	tx           *genproto.Transaction
+	idx          int
+	spentAmounts []int64
+	chainID      string
+	// sigHash is calculated on the first signature check, all signatures of the input sign the same hash
+	sigHash []byte
+}
+
+func (c *inputChecker) CheckSignature(sig, pubKey []byte) bool {
+	if c.sigHash == nil {
+		hash, err := types.CalculateSignatureHash(c.tx, c.idx, c.spentAmounts, c.chainID)
+		if err != nil {
+			return false
+		}
+		c.sigHash = hash
+	}
+
+	return types.VerifySignature(pubKey, sig, c.sigHash)
+}
+
+// CheckLockTime accepts lock times of the same type as the lock time of the transaction, either both heights
+// or both timestamps, that are not after it. The transaction lock time itself is checked with the other
+// transaction locks, so the script lock is reached once the transaction can be included.
+func (c *inputChecker) CheckLockTime(lockTime int64) bool {
+	txLockTime := int64(c.tx.LockTime)
+	if (lockTime < types.LockTimeThreshold) != (txLockTime < types.LockTimeThreshold) {
+		return false
+	}
+
+	return lockTime <= txLockTime
+}
+
+// CheckSequence accepts relative locks of the same type as the relative lock of the input sequence that are not
+// longer than it. Relative locks with the disable flag always pass, while inputs with a disabled relative lock
+// fail every relative lock of the script.
+func (c *inputChecker) CheckSequence(sequence int64) bool {
+	if sequence&types.SequenceLockDisabled != 0 {
+		return true
+	}
+
+	inputSequence := int64(c.tx.Inputs[c.idx].Sequence)
+	if inputSequence&types.SequenceLockDisabled != 0 {
+		return false
+	}
+	if sequence&types.SequenceLockTypeTime != inputSequence&types.SequenceLockTypeTime {
+		return false
+	}
+
+	return sequence&types.SequenceLockMask <= inputSequence&types.SequenceLockMask
+}
@@ -0,0 +1,274 @@
+package node
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/script"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHashTimeLockOutput(t *testing.T) {
+	const timeoutHeight = 3
+
+	var (
+		chain         = newMemoryChain(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+		recipientKey  = cryptography.NewPrivateKey()
+		refundKey     = cryptography.NewPrivateKey()
+		secret        = []byte("secret")
+		secretHash    = sha256.Sum256(secret)
+	)
+
+	lockScript, err := script.HashTimeLockScript(secretHash[:], recipientKey.Public().Bytes(), refundKey.Public().Bytes(), timeoutHeight)
+	require.Nil(t, err)
+
+	lockTx := createGenesisSpendingTx(t, chain)
+	lockTx.Outputs[0].Address = nil
+	lockTx.Outputs[0].LockScript = lockScript
+	signTestTx(lockTx, senderPrivKey, genesisBlockAmount)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	block := createRandomSignedBlockOnTop(genesisBlock, senderPrivKey, lockTx)
+	require.Nil(t, chain.AddBlock(block))
+
+	unlockTx := func(privKey cryptography.PrivateKey, elements ...[]byte) *genproto.Transaction {
+		return newUnlockScriptTx(t, lockTx, privKey, 0, elements...)
+	}
+
+	// The refund needs a transaction lock time at or after the timeout, which can't be included before it
+	require.ErrorIs(t, chain.ValidateTransaction(unlockTx(refundKey, nil)), script.ErrLockTimeNotMet)
+	refundTx := newUnlockScriptTx(t, lockTx, refundKey, timeoutHeight, nil)
+	require.ErrorIs(t, chain.ValidateTransaction(refundTx), ErrTransactionLocked)
+
+	require.ErrorIs(t, chain.ValidateTransaction(unlockTx(recipientKey, []byte("guess"), []byte{1})), script.ErrVerifyFailed)
+	require.ErrorIs(t, chain.ValidateTransaction(unlockTx(refundKey, secret, []byte{1})), script.ErrScriptFailed)
+
+	// Script outputs can't be spent by a signature of the input
+	signedTx := newUnsignedSpendingTx(lockTx, 0)
+	signedTx.Inputs[0].PublicKey = recipientKey.Public().Bytes()
+	signTestTx(signedTx, recipientKey, lockTx.Outputs[0].Amount)
+	require.NotNil(t, chain.ValidateTransaction(signedTx))
+
+	claimTx := unlockTx(recipientKey, secret, []byte{1})
+	require.Nil(t, chain.ValidateTransaction(claimTx))
+
+	// Past the timeout height both the claim and the refund are valid
+	for range timeoutHeight - 1 {
+		block = createRandomSignedBlockOnTop(block, senderPrivKey)
+		require.Nil(t, chain.AddBlock(block))
+	}
+	require.Nil(t, chain.ValidateTransaction(refundTx))
+	require.Nil(t, chain.ValidateTransaction(claimTx))
+
+	block = createRandomSignedBlockOnTop(block, senderPrivKey, claimTx)
+	require.Nil(t, chain.AddBlock(block))
+
+	utxo, err := chain.utxoStore.Get(getUTXOKey(types.HashTransactionString(lockTx), 0))
+	require.Nil(t, err)
+	require.True(t, utxo.IsSpent)
+	require.Equal(t, lockScript, utxo.LockScript)
+}
+
+func TestHashTimeLockRefundByTimestamp(t *testing.T) {
+	var (
+		chain         = newMemoryChain(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+		recipientKey  = cryptography.NewPrivateKey()
+		refundKey     = cryptography.NewPrivateKey()
+		secretHash    = sha256.Sum256([]byte("secret"))
+		now           = time.Now().Unix()
+	)
+
+	lockScript, err := script.HashTimeLockScript(secretHash[:], recipientKey.Public().Bytes(), refundKey.Public().Bytes(), now)
+	require.Nil(t, err)
+
+	lockTx := createGenesisSpendingTx(t, chain)
+	lockTx.Outputs[0].Address = nil
+	lockTx.Outputs[0].LockScript = lockScript
+	signTestTx(lockTx, senderPrivKey, genesisBlockAmount)
+
+	prevBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	addBlock := func(timestamp int64, txs ...*genproto.Transaction) {
+		block := createRandomSignedBlockOnTop(prevBlock, senderPrivKey, txs...)
+		block.Header.Timestamp = timestamp
+		types.SignBlock(senderPrivKey, block)
+		require.Nil(t, chain.AddBlock(block))
+		prevBlock = block
+	}
+
+	addBlock(now, lockTx)
+
+	// The lock time of the refund must be a timestamp as well
+	require.ErrorIs(t, chain.ValidateTransaction(newUnlockScriptTx(t, lockTx, refundKey, 1, nil)), script.ErrLockTimeNotMet)
+	require.ErrorIs(t, chain.ValidateTransaction(newUnlockScriptTx(t, lockTx, refundKey, uint32(now-1), nil)), script.ErrLockTimeNotMet)
+
+	// The refund is locked until the median time passes the timeout
+	refundTx := newUnlockScriptTx(t, lockTx, refundKey, uint32(now), nil)
+	require.ErrorIs(t, chain.ValidateTransaction(refundTx), ErrTransactionLocked)
+
+	addBlock(now + 1)
+	addBlock(now + 2)
+	require.Nil(t, chain.ValidateTransaction(refundTx))
+
+	addBlock(now+3, refundTx)
+}
+
+func TestCheckSequenceVerifyOutput(t *testing.T) {
+	var (
+		chain         = newMemoryChain(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+		ownerKey      = cryptography.NewPrivateKey()
+	)
+
+	lockScript, err := script.NewBuilder().AddInt(2).AddOp(script.OP_CHECKSEQUENCEVERIFY).AddOp(script.OP_DROP).
+		AddData(ownerKey.Public().Bytes()).AddOp(script.OP_CHECKSIG).Script()
+	require.Nil(t, err)
+
+	lockTx := createGenesisSpendingTx(t, chain)
+	lockTx.Outputs[0].Address = nil
+	lockTx.Outputs[0].LockScript = lockScript
+	signTestTx(lockTx, senderPrivKey, genesisBlockAmount)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	block := createRandomSignedBlockOnTop(genesisBlock, senderPrivKey, lockTx)
+	require.Nil(t, chain.AddBlock(block))
+
+	spendTx := func(sequence uint32) *genproto.Transaction {
+		tx := newUnsignedSpendingTx(lockTx, 0)
+		tx.Inputs[0].Sequence = sequence
+		return signUnlockScriptTx(t, tx, lockTx, ownerKey)
+	}
+
+	timeSequence, err := types.SequenceLockDuration(2 * time.Second << types.SequenceLockGranularity)
+	require.Nil(t, err)
+
+	// The input sequence must hold a relative lock of the same type that is at least as long
+	require.ErrorIs(t, chain.ValidateTransaction(spendTx(1)), script.ErrSequenceNotMet)
+	require.ErrorIs(t, chain.ValidateTransaction(spendTx(timeSequence)), script.ErrSequenceNotMet)
+	require.ErrorIs(t, chain.ValidateTransaction(spendTx(types.SequenceLockDisabled|2)), script.ErrSequenceNotMet)
+
+	// The relative lock of the input is enforced by the chain
+	tx := spendTx(2)
+	require.ErrorIs(t, chain.ValidateTransaction(tx), ErrTransactionLocked)
+
+	block = createRandomSignedBlockOnTop(block, senderPrivKey)
+	require.Nil(t, chain.AddBlock(block))
+	require.Nil(t, chain.ValidateTransaction(tx))
+
+	block = createRandomSignedBlockOnTop(block, senderPrivKey, tx)
+	require.Nil(t, chain.AddBlock(block))
+}
+
+// newUnlockScriptTx creates a transaction with the lock time spending the first output of the transaction
+// with an unlock script of the signature of the key followed by the elements.
+func newUnlockScriptTx(t *testing.T, lockTx *genproto.Transaction, privKey cryptography.PrivateKey, lockTime uint32, elements ...[]byte) *genproto.Transaction {
+	tx := newUnsignedSpendingTx(lockTx, 0)
+	tx.LockTime = lockTime
+	return signUnlockScriptTx(t, tx, lockTx, privKey, elements...)
+}
+
+// signUnlockScriptTx sets the unlock script of the only input of the transaction spending the first output
+// of the lock transaction to the signature of the key followed by the elements.
+func signUnlockScriptTx(t *testing.T, tx, lockTx *genproto.Transaction, privKey cryptography.PrivateKey, elements ...[]byte) *genproto.Transaction {
+	sig, err := types.CalculateInputSignature(privKey, tx, 0, []int64{lockTx.Outputs[0].Amount}, defaultChainID, types.SigHashAll)
+	require.Nil(t, err)
+
+	b := script.NewBuilder().AddData(sig)
+	for _, element := range elements {
+		b.AddData(element)
+	}
+	tx.Inputs[0].UnlockScript, err = b.Script()
+	require.Nil(t, err)
+
+	return tx
+}
+
+func TestLockScriptOutputRules(t *testing.T) {
+	var (
+		chain         = newMemoryChain(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+	)
+
+	lockScript, err := script.PubKeyScript(senderPrivKey.Public().Bytes())
+	require.Nil(t, err)
+
+	// Outputs with a lock script have no address
+	tx := createGenesisSpendingTx(t, chain)
+	tx.Outputs[0].LockScript = lockScript
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	tx.Outputs[0].Address = nil
+	tx.Outputs[0].LockScript = make([]byte, script.MaxScriptSize+1)
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	tx.Outputs[0].LockScript = lockScript
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+	require.Nil(t, chain.ValidateTransaction(tx))
+
+	// Outputs without a lock script can't be spent by an unlock script
+	tx = createGenesisSpendingTx(t, chain)
+	tx.Inputs[0].UnlockScript = []byte{byte(script.OP_1)}
+	signTestTx(tx, senderPrivKey, genesisBlockAmount)
+	require.NotNil(t, chain.ValidateTransaction(tx))
+}
+
+func TestMultisigOutput(t *testing.T) {
+	var (
+		chain         = newMemoryChain(t)
+		senderPrivKey = cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+		privKeys      = []cryptography.PrivateKey{cryptography.NewPrivateKey(), cryptography.NewPrivateKey(), cryptography.NewPrivateKey()}
+	)
+
+	policy, err := types.NewMultisigPolicy(2, []cryptography.PublicKey{privKeys[0].Public(), privKeys[1].Public(), privKeys[2].Public()})
+	require.Nil(t, err)
+
+	lockTx := createGenesisSpendingTx(t, chain)
+	lockTx.Outputs[0] = types.NewMultisigOutput(lockTx.Outputs[0].Amount, types.MultisigAddress(policy))
+	signTestTx(lockTx, senderPrivKey, genesisBlockAmount)
+
+	genesisBlock, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+	block := createRandomSignedBlockOnTop(genesisBlock, senderPrivKey, lockTx)
+	require.Nil(t, chain.AddBlock(block))
+
+	spentAmounts := []int64{lockTx.Outputs[0].Amount}
+	spendTx := func(policy *genproto.MultisigPolicy, signers ...cryptography.PrivateKey) *genproto.Transaction {
+		tx := newUnsignedSpendingTx(lockTx, 0)
+		tx.Inputs[0] = types.NewMultisigInput(types.HashTransactionBytes(lockTx), 0, policy)
+		for _, privKey := range signers {
+			require.Nil(t, types.SignMultisigInput(privKey, tx, 0, spentAmounts, defaultChainID, types.SigHashAll))
+		}
+		return tx
+	}
+
+	require.NotNil(t, chain.ValidateTransaction(spendTx(policy, privKeys[0])))
+
+	// A policy of the same keys with a lower threshold doesn't own the output
+	otherPolicy := proto.Clone(policy).(*genproto.MultisigPolicy)
+	otherPolicy.Threshold = 1
+	require.NotNil(t, chain.ValidateTransaction(spendTx(otherPolicy, privKeys[0])))
+
+	// A single key of the policy can't spend the output
+	signedTx := newUnsignedSpendingTx(lockTx, 0)
+	signedTx.Inputs[0].PublicKey = privKeys[0].Public().Bytes()
+	signTestTx(signedTx, privKeys[0], spentAmounts...)
+	require.NotNil(t, chain.ValidateTransaction(signedTx))
+
+	tx := spendTx(policy, privKeys[0], privKeys[2])
+	require.Nil(t, chain.ValidateTransaction(tx))
+
+	block = createRandomSignedBlockOnTop(block, senderPrivKey, tx)
+	require.Nil(t, chain.AddBlock(block))
+}
@@ -0,0 +1,330 @@
+package node
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+)
+
+// stakeSeedSecretTag separates the secrets of the proposer draw seed from other data derived from the producer key.
+const stakeSeedSecretTag = "stake seed"
+
+// StakeSet tracks the coins locked in stake outputs of every address and the addresses slashed for double-signing.
+// In the proof-of-stake mode the proposer of every block is drawn from the stakers in proportion to their stake.
+type StakeSet struct {
+	// stakes maps a hex encoded address to the amount of its stake
+	stakes map[string]int64
+	// slashed contains the hex encoded addresses that lost their stake
+	slashed map[string]struct{}
+	// commitments maps the hex encoded address of a block producer to the seed commitment of its last block
+	commitments map[string]SeedCommitment
+}
+
+// SeedCommitment is the hash of the secret a producer reveals in its next block. The revealed secrets of the blocks
+// make up the seed of the proposer draw. The secrets are committed to before the seed they are mixed into is known,
+// so a proposer can't grind the seed, it can only withhold its block.
+type SeedCommitment struct {
+	Hash []byte
+	// Height is the height of the block that made the commitment
+	Height int
+}
+
+func newStakeSet() *StakeSet {
+	return &StakeSet{
+		stakes:      make(map[string]int64),
+		slashed:     make(map[string]struct{}),
+		commitments: make(map[string]SeedCommitment),
+	}
+}
+
+func (ss *StakeSet) clone() *StakeSet {
+	return &StakeSet{
+		stakes:      maps.Clone(ss.stakes),
+		slashed:     maps.Clone(ss.slashed),
+		commitments: maps.Clone(ss.commitments),
+	}
+}
+
+// add changes the stake of the address by the given amount, which is negative when the stake is unbonded.
+func (ss *StakeSet) add(address []byte, amount int64) {
+	key := hex.EncodeToString(address)
+
+	ss.stakes[key] += amount
+	if ss.stakes[key] == 0 {
+		delete(ss.stakes, key)
+	}
+}
+
+func (ss *StakeSet) isSlashed(address []byte) bool {
+	_, ok := ss.slashed[hex.EncodeToString(address)]
+	return ok
+}
+
+// proposer draws the address of the proposer with the given rank of the block at the given height on top of the block
+// with the given draw seed. Every staker that is not slashed wins with a probability proportional to its stake.
+// Every rank is an independent draw, so every node gets the same proposers, and the proposer of the next rank
+// takes over when the previous one doesn't propose its block in time.
+// Nil is returned if nobody has stake, which lets any node propose the block.
+func (ss *StakeSet) proposer(seed []byte, height, rank int) []byte {
+	stakers, totalStake := ss.eligible()
+	if len(stakers) == 0 {
+		return nil
+	}
+
+	preimage := binary.BigEndian.AppendUint64(slices.Clone(seed), uint64(height))
+	preimage = binary.BigEndian.AppendUint64(preimage, uint64(rank))
+	draw := sha256.Sum256(preimage)
+	ticket := new(big.Int).SetBytes(draw[:])
+	ticket.Mod(ticket, totalStake)
+
+	for _, address := range stakers {
+		ticket.Sub(ticket, big.NewInt(ss.stakes[address]))
+		if ticket.Sign() < 0 {
+			proposer, _ := hex.DecodeString(address)
+			return proposer
+		}
+	}
+
+	// Unreachable, since the ticket is less than the total stake
+	return nil
+}
+
+// eligible returns the sorted addresses of the stakers that can be drawn as proposers and their total stake.
+func (ss *StakeSet) eligible() ([]string, *big.Int) {
+	stakers := make([]string, 0, len(ss.stakes))
+	totalStake := new(big.Int)
+
+	for address, stake := range ss.stakes {
+		if _, ok := ss.slashed[address]; ok || stake <= 0 {
+			continue
+		}
+
+		stakers = append(stakers, address)
+		totalStake.Add(totalStake, big.NewInt(stake))
+	}
+
+	slices.Sort(stakers)
+	return stakers, totalStake
+}
+
+// checkSeed checks the seed fields of the header of a block produced by the address. The block has to commit
+// to a new secret and reveal the secret of the last commitment of the producer, if it has one.
+func (ss *StakeSet) checkSeed(header *genproto.BlockHeader, address []byte) error {
+	if len(header.SeedCommitment) != sha256.Size {
+		return fmt.Errorf("block doesn't commit to a seed secret")
+	}
+
+	commitment, ok := ss.commitments[hex.EncodeToString(address)]
+	if !ok {
+		if len(header.SeedReveal) > 0 {
+			return fmt.Errorf("block reveals a seed secret without a commitment")
+		}
+		return nil
+	}
+
+	if reveal := sha256.Sum256(header.SeedReveal); !bytes.Equal(reveal[:], commitment.Hash) {
+		return fmt.Errorf("block doesn't reveal the seed secret committed to at height %d", commitment.Height)
+	}
+
+	return nil
+}
+
+// commit replaces the seed commitment of the address with the one of the block header.
+// The previous commitment is returned, so it can be restored when the block is disconnected.
+func (ss *StakeSet) commit(address []byte, header *genproto.BlockHeader) *SeedCommitment {
+	key := hex.EncodeToString(address)
+
+	var prev *SeedCommitment
+	if commitment, ok := ss.commitments[key]; ok {
+		prev = &commitment
+	}
+
+	ss.commitments[key] = SeedCommitment{Hash: header.SeedCommitment, Height: int(header.Height)}
+	return prev
+}
+
+// uncommit restores the previous seed commitment of the address when its block is disconnected.
+func (ss *StakeSet) uncommit(address []byte, prev *SeedCommitment) {
+	if prev == nil {
+		delete(ss.commitments, hex.EncodeToString(address))
+		return
+	}
+
+	ss.commitments[hex.EncodeToString(address)] = *prev
+}
+
+// blockProducerAddress returns the address of the producer that signed the block.
+func blockProducerAddress(block *genproto.Block) []byte {
+	return cryptography.NewPublicKeyFromBytes(block.PublicKey).Address().Bytes()
+}
+
+// stakeSeedSecret derives the seed secret of the block at the given height from the private key of its producer.
+// Deriving the secret lets the producer reveal it later without keeping any state.
+func stakeSeedSecret(privKey cryptography.PrivateKey, height int) []byte {
+	preimage := append(slices.Clone(privKey.Bytes()), stakeSeedSecretTag...)
+	preimage = binary.BigEndian.AppendUint64(preimage, uint64(height))
+
+	secret := sha256.Sum256(preimage)
+	return secret[:]
+}
+
+// slash checks the evidence of the slashing transaction and marks the address of the double-signing validator
+// as slashed. A slashed address is not drawn as a proposer anymore and its stake can never be unbonded.
+func (ss *StakeSet) slash(tx *genproto.Transaction) error {
+	evidence := tx.SlashingEvidence
+	hash := types.HashTransactionString(tx)
+
+	if len(tx.Inputs) > 0 || len(tx.Outputs) > 0 || tx.CoinbaseHeight != 0 || tx.Governance != nil {
+		return fmt.Errorf("slashing transaction %s transfers coins", hash)
+	}
+
+	if evidence.Header1 == nil || evidence.Header2 == nil || evidence.Header1.Height != evidence.Header2.Height {
+		return fmt.Errorf("slashing transaction %s doesn't have two headers of the same height", hash)
+	}
+
+	header1Hash := types.HashBlockHeader(evidence.Header1)
+	header2Hash := types.HashBlockHeader(evidence.Header2)
+	if bytes.Equal(header1Hash, header2Hash) {
+		return fmt.Errorf("slashing transaction %s has the same header twice", hash)
+	}
+
+	if len(evidence.PublicKey) != cryptography.PubKeyLen ||
+		len(evidence.Signature1) != cryptography.SigLen || len(evidence.Signature2) != cryptography.SigLen {
+		return fmt.Errorf("slashing transaction %s has a malformed signature", hash)
+	}
+
+	pubKey := cryptography.NewPublicKeyFromBytes(evidence.PublicKey)
+	if !cryptography.NewSignatureFromBytes(evidence.Signature1).Verify(pubKey, header1Hash) ||
+		!cryptography.NewSignatureFromBytes(evidence.Signature2).Verify(pubKey, header2Hash) {
+		return fmt.Errorf("slashing transaction %s has an invalid signature", hash)
+	}
+
+	address := pubKey.Address().Bytes()
+	if ss.isSlashed(address) {
+		return fmt.Errorf("slashing transaction %s slashes an already slashed validator", hash)
+	}
+
+	if ss.stakes[hex.EncodeToString(address)] <= 0 {
+		return fmt.Errorf("slashing transaction %s slashes a validator without stake", hash)
+	}
+
+	ss.slashed[hex.EncodeToString(address)] = struct{}{}
+	return nil
+}
+
+// unslash reverts the slashing transaction when its block is disconnected.
+func (ss *StakeSet) unslash(tx *genproto.Transaction) {
+	pubKey := cryptography.NewPublicKeyFromBytes(tx.SlashingEvidence.PublicKey)
+	delete(ss.slashed, hex.EncodeToString(pubKey.Address().Bytes()))
+}
+
+// Stake returns the amount of coins locked in the stake outputs of the address.
+func (c *Chain) Stake(address []byte) int64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.stakes.stakes[hex.EncodeToString(address)]
+}
+
+// SetStakeSeed sets the seed fields of the header of a block produced with the private key in the proof-of-stake mode:
+// the secret the producer committed to in its previous block and the commitment to the secret of this block.
+// It has to be called before the block is signed. In the other modes the block is left unchanged.
+func (c *Chain) SetStakeSeed(block *genproto.Block, privKey cryptography.PrivateKey) {
+	if c.params.Consensus != ConsensusProofOfStake {
+		return
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	block.Header.SeedReveal = nil
+	if commitment, ok := c.stakes.commitments[privKey.Public().Address().String()]; ok {
+		block.Header.SeedReveal = stakeSeedSecret(privKey, commitment.Height)
+	}
+
+	commitment := sha256.Sum256(stakeSeedSecret(privKey, int(block.Header.Height)))
+	block.Header.SeedCommitment = commitment[:]
+}
+
+// checkStakeSeed checks the seed fields of the block header against the seed commitments at the chain tip.
+// Only proof-of-stake blocks have seed fields.
+func (c *Chain) checkStakeSeed(block *genproto.Block) error {
+	hash := types.HashBlockString(block)
+
+	if c.params.Consensus != ConsensusProofOfStake {
+		if len(block.Header.SeedReveal) > 0 || len(block.Header.SeedCommitment) > 0 {
+			return fmt.Errorf("block with hash %s has seed fields outside of the proof-of-stake mode", hash)
+		}
+		return nil
+	}
+
+	if err := c.stakes.checkSeed(block.Header, blockProducerAddress(block)); err != nil {
+		return fmt.Errorf("block with hash %s: %w", hash, err)
+	}
+
+	return nil
+}
+
+// IsSlashed checks whether the validator with the address was slashed for double-signing.
+func (c *Chain) IsSlashed(address []byte) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.stakes.isSlashed(address)
+}
+
+// validateOutputTypes checks the rules of stake and unbonding outputs. A transaction spending stake outputs
+// unbonds the stake: it must only spend stake outputs of a single address and only create unbonding outputs
+// paying the same address. Other transactions can't create unbonding outputs.
+func (c *Chain) validateOutputTypes(tx *genproto.Transaction, inputs []*UTXO) error {
+	hash := types.HashTransactionString(tx)
+
+	for _, output := range tx.Outputs {
+		switch output.Type {
+		case genproto.OutputType_TRANSFER, genproto.OutputType_UNBONDING, genproto.OutputType_MULTISIG, genproto.OutputType_DATA:
+		case genproto.OutputType_STAKE:
+			if c.params.Consensus != ConsensusProofOfStake {
+				return fmt.Errorf("transaction with hash %s has a stake output outside of the proof-of-stake mode", hash)
+			}
+		default:
+			return fmt.Errorf("transaction with hash %s has an output of unknown type %d", hash, output.Type)
+		}
+	}
+
+	isUnbonding := slices.ContainsFunc(inputs, func(utxo *UTXO) bool {
+		return utxo.Type == genproto.OutputType_STAKE
+	})
+
+	if !isUnbonding {
+		for _, output := range tx.Outputs {
+			if output.Type == genproto.OutputType_UNBONDING {
+				return fmt.Errorf("transaction with hash %s creates an unbonding output without spending stake", hash)
+			}
+		}
+		return nil
+	}
+
+	stakeAddress := inputs[0].Address
+	for _, utxo := range inputs {
+		if utxo.Type != genproto.OutputType_STAKE || !bytes.Equal(utxo.Address, stakeAddress) {
+			return fmt.Errorf("transaction with hash %s unbonds stake of several addresses or spends other outputs", hash)
+		}
+	}
+
+	for _, output := range tx.Outputs {
+		if output.Type != genproto.OutputType_UNBONDING || !bytes.Equal(output.Address, stakeAddress) {
+			return fmt.Errorf("transaction with hash %s unbonds stake into an output that is not unbonding to the staker", hash)
+		}
+	}
+
+	return nil
+}
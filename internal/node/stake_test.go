@@ -0,0 +1,336 @@
+package node
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	testUnbondingPeriod = 2
+	testStakeAmount     = 10000
+)
+
+func newProofOfStakeChainParams() *ChainParams {
+	params := DefaultChainParams()
+	params.Consensus = ConsensusProofOfStake
+	params.UnbondingPeriod = testUnbondingPeriod
+
+	return params
+}
+
+// newStakingTx locks stake of every staker from the genesis output and returns the change to the genesis key.
+// The stake outputs go first, in the order of the stakers.
+func newStakingTx(t *testing.T, chain *Chain, stakers ...cryptography.PrivateKey) *genproto.Transaction {
+	genesisKey := cryptography.NewPrivateKeyFromString(genesisBlockSeed)
+
+	tx := createGenesisSpendingTx(t, chain)
+	tx.Outputs = make([]*genproto.TxOutput, 0, len(stakers)+1)
+	for _, staker := range stakers {
+		tx.Outputs = append(tx.Outputs, &genproto.TxOutput{
+			Amount:  testStakeAmount,
+			Address: staker.Public().Address().Bytes(),
+			Type:    genproto.OutputType_STAKE,
+		})
+	}
+	tx.Outputs = append(tx.Outputs, &genproto.TxOutput{
+		Amount:  genesisBlockAmount - int64(len(stakers))*testStakeAmount - testTxFee,
+		Address: genesisKey.Public().Address().Bytes(),
+	})
+	signTestTx(tx, genesisKey, genesisBlockAmount)
+
+	return tx
+}
+
+func TestStakeProposer(t *testing.T) {
+	var (
+		stakes   = newStakeSet()
+		address1 = cryptography.NewPrivateKey().Public().Address().Bytes()
+		address2 = cryptography.NewPrivateKey().Public().Address().Bytes()
+		seed     = types.HashBlockHeader(&genproto.BlockHeader{Height: 1})
+	)
+
+	// Without stake nobody is drawn
+	require.Nil(t, stakes.proposer(seed, 2, 0))
+
+	stakes.add(address1, 1000)
+	stakes.add(address2, 3000)
+
+	// The draw is deterministic
+	require.Equal(t, stakes.proposer(seed, 2, 0), stakes.clone().proposer(seed, 2, 0))
+
+	const draws = 4000
+	var heightWins1, rankWins1 int
+	for i := 0; i < draws; i++ {
+		if bytes.Equal(stakes.proposer(seed, i, 0), address1) {
+			heightWins1++
+		}
+		if bytes.Equal(stakes.proposer(seed, 2, i), address1) {
+			rankWins1++
+		}
+	}
+
+	// The first address has a quarter of the stake, the fallback ranks are drawn the same way
+	require.InDelta(t, draws/4, heightWins1, draws/20)
+	require.InDelta(t, draws/4, rankWins1, draws/20)
+
+	// Unbonded and slashed stakers are not drawn anymore
+	stakes.add(address2, -3000)
+	require.Equal(t, address1, stakes.proposer(seed, 2, 0))
+
+	stakes.add(address2, 3000)
+	stakes.slashed[hex.EncodeToString(address2)] = struct{}{}
+	require.Equal(t, address1, stakes.proposer(seed, 2, 0))
+}
+
+func TestProposerFallback(t *testing.T) {
+	var (
+		chain   = newTestChain(t, newProofOfStakeChainParams())
+		stakers = newTestValidators(2)
+		timeout = chain.Params().ProducerTimeout
+	)
+
+	// The blocks are dated back, so the turns of the test are in the past
+	stakingBlock := newTestBlockAt(t, chain, stakers, time.Unix(tipTimestamp(t, chain)+1, 0), newStakingTx(t, chain, stakers...))
+	require.Nil(t, chain.AddBlock(stakingBlock))
+
+	timestamp := tipTimestamp(t, chain)
+	scheduled := newTestBlockAt(t, chain, stakers, time.Unix(timestamp+1, 0)).PublicKey
+
+	// Find the first turn the scheduled proposer is not drawn for
+	rank := 1
+	for chain.IsNextProducer(scheduled, time.Unix(timestamp+int64(rank)*timeout, 0)) {
+		rank++
+	}
+	now := time.Unix(timestamp+int64(rank)*timeout, 0)
+
+	// The turn of the scheduled proposer is over
+	for _, staker := range stakers {
+		if bytes.Equal(staker.Public().Bytes(), scheduled) {
+			block := chain.NewBlockTemplateAt(nil, staker.Public().Address().Bytes(), now)
+			chain.SetStakeSeed(block, staker)
+			types.SignBlock(staker, block)
+			require.NotNil(t, chain.AddBlock(block))
+		}
+	}
+
+	block := newTestBlockAt(t, chain, stakers, now)
+	require.NotEqual(t, scheduled, block.PublicKey)
+	require.Nil(t, chain.AddBlock(block))
+}
+
+func TestStakeSeedCommitments(t *testing.T) {
+	var (
+		chain  = newTestChain(t, newProofOfStakeChainParams())
+		staker = cryptography.NewPrivateKey()
+	)
+
+	// The first block of a producer only commits to a secret
+	block1 := newTestBlock(t, chain, []cryptography.PrivateKey{staker}, newStakingTx(t, chain, staker))
+	require.Empty(t, block1.Header.SeedReveal)
+	require.Len(t, block1.Header.SeedCommitment, sha256.Size)
+	require.Nil(t, chain.AddBlock(block1))
+
+	// The next block reveals the committed secret
+	block2 := newTestBlock(t, chain, []cryptography.PrivateKey{staker})
+	reveal := sha256.Sum256(block2.Header.SeedReveal)
+	require.Equal(t, block1.Header.SeedCommitment, reveal[:])
+
+	// Blocks with another secret or without a commitment are rejected
+	invalidBlock := proto.Clone(block2).(*genproto.Block)
+	invalidBlock.Header.SeedReveal = stakeSeedSecret(staker, 2)
+	types.SignBlock(staker, invalidBlock)
+	require.NotNil(t, chain.AddBlock(invalidBlock))
+
+	invalidBlock = proto.Clone(block2).(*genproto.Block)
+	invalidBlock.Header.SeedCommitment = nil
+	types.SignBlock(staker, invalidBlock)
+	require.NotNil(t, chain.AddBlock(invalidBlock))
+
+	require.Nil(t, chain.AddBlock(block2))
+
+	// The revealed secret is mixed into the draw seed
+	tip := chain.tipNode()
+	seed := sha256.Sum256(append(slices.Clone(tip.parent.seed), block2.Header.SeedReveal...))
+	require.Equal(t, seed[:], tip.seed)
+
+	// Disconnecting the blocks restores the commitments
+	address := staker.Public().Address().String()
+	_, err := chain.DisconnectTip()
+	require.Nil(t, err)
+	require.Equal(t, 1, chain.stakes.commitments[address].Height)
+
+	_, err = chain.DisconnectTip()
+	require.Nil(t, err)
+	require.NotContains(t, chain.stakes.commitments, address)
+
+	// Seed fields are only allowed in the proof-of-stake mode
+	chain = newMemoryChain(t)
+	block := chain.NewBlockTemplate(nil, staker.Public().Address().Bytes())
+	block.Header.SeedCommitment = block1.Header.SeedCommitment
+	types.SignBlock(staker, block)
+	require.NotNil(t, chain.AddBlock(block))
+}
+
+func TestStakeAndUnbond(t *testing.T) {
+	var (
+		chain  = newTestChain(t, newProofOfStakeChainParams())
+		staker = cryptography.NewPrivateKey()
+		other  = cryptography.NewPrivateKey()
+	)
+
+	// Anybody can produce blocks until somebody stakes
+	require.True(t, chain.IsNextProducer(other.Public().Bytes(), time.Now()))
+
+	stakingTx := newStakingTx(t, chain, staker)
+	require.Nil(t, chain.AddBlock(newTestBlock(t, chain, []cryptography.PrivateKey{other}, stakingTx)))
+	require.Equal(t, int64(testStakeAmount), chain.Stake(staker.Public().Address().Bytes()))
+
+	// Only the staker is drawn now
+	require.True(t, chain.IsNextProducer(staker.Public().Bytes(), time.Now()))
+	require.False(t, chain.IsNextProducer(other.Public().Bytes(), time.Now()))
+
+	block := chain.NewBlockTemplate(nil, other.Public().Address().Bytes())
+	types.SignBlock(other, block)
+	require.NotNil(t, chain.AddBlock(block))
+
+	// Stake can only be unbonded to the staker
+	tx := newSpendingTx(stakingTx, 0, staker, &genproto.TxOutput{
+		Amount:  testStakeAmount - testTxFee,
+		Address: staker.Public().Address().Bytes(),
+	})
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	tx = newSpendingTx(stakingTx, 0, staker, &genproto.TxOutput{
+		Amount:  testStakeAmount - testTxFee,
+		Address: other.Public().Address().Bytes(),
+		Type:    genproto.OutputType_UNBONDING,
+	})
+	require.NotNil(t, chain.ValidateTransaction(tx))
+
+	unbondingTx := newSpendingTx(stakingTx, 0, staker, &genproto.TxOutput{
+		Amount:  testStakeAmount - testTxFee,
+		Address: staker.Public().Address().Bytes(),
+		Type:    genproto.OutputType_UNBONDING,
+	})
+	require.Nil(t, chain.ValidateTransaction(unbondingTx))
+
+	require.Nil(t, chain.AddBlock(newTestBlock(t, chain, []cryptography.PrivateKey{staker}, unbondingTx)))
+	require.Equal(t, int64(0), chain.Stake(staker.Public().Address().Bytes()))
+
+	// Unbonded coins are locked for the unbonding period
+	spendingTx := newSpendingTx(unbondingTx, 0, staker, &genproto.TxOutput{
+		Amount:  testStakeAmount - 2*testTxFee,
+		Address: other.Public().Address().Bytes(),
+	})
+	require.NotNil(t, chain.ValidateTransaction(spendingTx))
+
+	for i := 0; i < testUnbondingPeriod; i++ {
+		require.Nil(t, chain.AddBlock(newTestBlock(t, chain, []cryptography.PrivateKey{other})))
+	}
+	require.Nil(t, chain.ValidateTransaction(spendingTx))
+
+	// Disconnecting the unbonding block restores the stake
+	for i := 0; i < testUnbondingPeriod+1; i++ {
+		_, err := chain.DisconnectTip()
+		require.Nil(t, err)
+	}
+	require.Equal(t, int64(testStakeAmount), chain.Stake(staker.Public().Address().Bytes()))
+}
+
+func TestStakeOutsideProofOfStake(t *testing.T) {
+	chain := newMemoryChain(t)
+
+	tx := newStakingTx(t, chain, cryptography.NewPrivateKey())
+	require.NotNil(t, chain.ValidateTransaction(tx))
+}
+
+func TestSlashDoubleSigning(t *testing.T) {
+	var (
+		chain    = newTestChain(t, newProofOfStakeChainParams())
+		staker   = cryptography.NewPrivateKey()
+		reporter = cryptography.NewPrivateKey()
+	)
+
+	stakingTx := newStakingTx(t, chain, staker)
+	require.Nil(t, chain.AddBlock(newTestBlock(t, chain, []cryptography.PrivateKey{reporter}, stakingTx)))
+
+	// The staker signs two different blocks at the same height
+	block1 := newTestBlock(t, chain, []cryptography.PrivateKey{staker})
+	block2 := newTestBlock(t, chain, []cryptography.PrivateKey{staker})
+	block2.Header.Timestamp++
+	types.SignBlock(staker, block2)
+	require.Nil(t, chain.AddBlock(block1))
+
+	// The same block twice is no evidence
+	require.NotNil(t, chain.ValidateTransaction(types.NewSlashingTransaction(block1, block1)))
+
+	slashingTx := types.NewSlashingTransaction(block1, block2)
+	require.Nil(t, chain.ValidateTransaction(slashingTx))
+
+	require.Nil(t, chain.AddBlock(newTestBlock(t, chain, []cryptography.PrivateKey{staker}, slashingTx)))
+	require.True(t, chain.IsSlashed(staker.Public().Address().Bytes()))
+
+	// A slashed staker can't be slashed again or unbond
+	require.NotNil(t, chain.ValidateTransaction(slashingTx))
+
+	unbondingTx := newSpendingTx(stakingTx, 0, staker, &genproto.TxOutput{
+		Amount:  testStakeAmount - testTxFee,
+		Address: staker.Public().Address().Bytes(),
+		Type:    genproto.OutputType_UNBONDING,
+	})
+	require.NotNil(t, chain.ValidateTransaction(unbondingTx))
+
+	_, err := chain.DisconnectTip()
+	require.Nil(t, err)
+	require.False(t, chain.IsSlashed(staker.Public().Address().Bytes()))
+}
+
+func TestStakeSetRestartFromDisk(t *testing.T) {
+	var (
+		path   = filepath.Join(t.TempDir(), "chain.db")
+		store  = openTestDiskStore(t, path)
+		params = newProofOfStakeChainParams()
+		staker = cryptography.NewPrivateKey()
+	)
+
+	chain, err := NewChain(params, store)
+	require.Nil(t, err)
+
+	stakingTx := newStakingTx(t, chain, staker)
+	require.Nil(t, chain.AddBlock(newTestBlock(t, chain, []cryptography.PrivateKey{staker}, stakingTx)))
+
+	unbondingTx := newSpendingTx(stakingTx, 0, staker, &genproto.TxOutput{
+		Amount:  testStakeAmount - testTxFee,
+		Address: staker.Public().Address().Bytes(),
+		Type:    genproto.OutputType_UNBONDING,
+	})
+	require.Nil(t, chain.AddBlock(newTestBlock(t, chain, []cryptography.PrivateKey{staker}, unbondingTx)))
+	require.Nil(t, store.Close())
+
+	store = openTestDiskStore(t, path)
+	defer store.Close()
+
+	chain, err = NewChain(params, store)
+	require.Nil(t, err)
+	require.Equal(t, int64(0), chain.Stake(staker.Public().Address().Bytes()))
+
+	// The seed commitment of the staker is replayed, so it can reveal its secret
+	require.Nil(t, chain.AddBlock(newTestBlock(t, chain, []cryptography.PrivateKey{staker})))
+	_, err = chain.DisconnectTip()
+	require.Nil(t, err)
+
+	_, err = chain.DisconnectTip()
+	require.Nil(t, err)
+	require.Equal(t, int64(testStakeAmount), chain.Stake(staker.Public().Address().Bytes()))
+}
@@ -56,6 +56,14 @@ func (s *MemoryTxStore) Put(tx *genproto.Transaction) error {
 type UTXOStore interface {
 	Get(hash string) (*UTXO, error)
 	Put(utxo *UTXO) error
+	Delete(hash string) error
+}
+
+// UTXOBatch is a set of changes to the UTXO store that are applied together.
+type UTXOBatch struct {
+	Put []*UTXO
+	// Delete contains the keys of the deleted UTXOs
+	Delete []string
 }
 
 type MemoryUTXOStore struct {
@@ -91,6 +99,15 @@ func (s *MemoryUTXOStore) Put(utxo *UTXO) error {
 	return nil
 }
 
+func (s *MemoryUTXOStore) Delete(hash string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.utxoMap, hash)
+
+	return nil
+}
+
 func getUTXOKey(hash string, outIndex int) string {
 	return fmt.Sprintf("%s:%d", hash, outIndex)
 }
@@ -102,16 +119,31 @@ func getUTXOKey(hash string, outIndex int) string {
 type BlockStore interface {
 	Put(*genproto.Block) error
 	Get(hash string) (*genproto.Block, error)
+	// PutUndo stores the undo record of the block with the given hash.
+	PutUndo(hash string, undo *BlockUndo) error
+	GetUndo(hash string) (*BlockUndo, error)
+	// PutTip stores the hash of the block at the tip of the main chain.
+	PutTip(hash string) error
+	// GetTip returns the hash of the block at the tip of the main chain or an empty string if the store is empty.
+	GetTip() (string, error)
+	// PutFinalized stores the hash of the last finalized block.
+	PutFinalized(hash string) error
+	// GetFinalized returns the hash of the last finalized block or an empty string if no block was finalized.
+	GetFinalized() (string, error)
 }
 
 type MemoryBlockStore struct {
 	sync.RWMutex
-	blocks map[string]*genproto.Block
+	blocks    map[string]*genproto.Block
+	undos     map[string]*BlockUndo
+	tip       string
+	finalized string
 }
 
 func NewMemoryBlockStore() *MemoryBlockStore {
 	return &MemoryBlockStore{
 		blocks: make(map[string]*genproto.Block),
+		undos:  make(map[string]*BlockUndo),
 	}
 }
 
@@ -134,3 +166,133 @@ func (s *MemoryBlockStore) Get(hash string) (*genproto.Block, error) {
 
 	return block, nil
 }
+
+func (s *MemoryBlockStore) PutUndo(hash string, undo *BlockUndo) error {
+	s.Lock()
+	defer s.Unlock()
+	s.undos[hash] = undo
+
+	return nil
+}
+
+func (s *MemoryBlockStore) GetUndo(hash string) (*BlockUndo, error) {
+	s.RLock()
+	defer s.RUnlock()
+	undo, ok := s.undos[hash]
+	if !ok {
+		return nil, fmt.Errorf("undo record of block [%s] is not found", hash)
+	}
+
+	return undo, nil
+}
+
+func (s *MemoryBlockStore) PutTip(hash string) error {
+	s.Lock()
+	defer s.Unlock()
+	s.tip = hash
+
+	return nil
+}
+
+func (s *MemoryBlockStore) GetTip() (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.tip, nil
+}
+
+func (s *MemoryBlockStore) PutFinalized(hash string) error {
+	s.Lock()
+	defer s.Unlock()
+	s.finalized = hash
+
+	return nil
+}
+
+func (s *MemoryBlockStore) GetFinalized() (string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.finalized, nil
+}
+
+//-----------------------------------------------------------------------------
+//  ChainStore
+//-----------------------------------------------------------------------------
+
+// ChainStore holds the blocks, the transactions and the UTXOs of a chain. The changes of a connected or disconnected
+// block are committed to all of them at once, so the stores never get out of sync with each other or with the stored tip.
+type ChainStore interface {
+	BlockStore() BlockStore
+	TxStore() TxStore
+	UTXOStore() UTXOStore
+	// Commit writes all the changes at once.
+	Commit(changes *BlockChanges) error
+}
+
+// BlockChanges are the changes a connected or disconnected block makes to the stores of the chain.
+type BlockChanges struct {
+	// Block is the connected block, stored along with its transactions and Undo. It is nil for a disconnected block.
+	Block *genproto.Block
+	Undo  *BlockUndo
+	// UTXOs are the changes to the UTXO set
+	UTXOs *UTXOBatch
+	// Tip is the hash of the block at the tip of the main chain after the change
+	Tip string
+}
+
+// MemoryStore holds the stores of a chain in memory.
+type MemoryStore struct {
+	blocks *MemoryBlockStore
+	txs    *MemoryTxStore
+	utxos  *MemoryUTXOStore
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		blocks: NewMemoryBlockStore(),
+		txs:    NewMemoryTxStore(),
+		utxos:  NewMemoryUTXOStore(),
+	}
+}
+
+func (s *MemoryStore) BlockStore() BlockStore {
+	return s.blocks
+}
+
+func (s *MemoryStore) TxStore() TxStore {
+	return s.txs
+}
+
+func (s *MemoryStore) UTXOStore() UTXOStore {
+	return s.utxos
+}
+
+// Commit applies the changes store by store. Memory stores don't survive a crash, so it is enough
+// that the tip is changed last.
+func (s *MemoryStore) Commit(changes *BlockChanges) error {
+	if changes.Block != nil {
+		s.txs.Lock()
+		for _, tx := range changes.Block.Transactions {
+			s.txs.txMap[types.HashTransactionString(tx)] = tx
+		}
+		s.txs.Unlock()
+
+		hash := types.HashBlockString(changes.Block)
+		s.blocks.Lock()
+		s.blocks.blocks[hash] = changes.Block
+		s.blocks.undos[hash] = changes.Undo
+		s.blocks.Unlock()
+	}
+
+	s.utxos.Lock()
+	for _, utxo := range changes.UTXOs.Put {
+		s.utxos.utxoMap[getUTXOKey(utxo.Hash, utxo.OutIndex)] = utxo
+	}
+	for _, key := range changes.UTXOs.Delete {
+		delete(s.utxos.utxoMap, key)
+	}
+	s.utxos.Unlock()
+
+	return s.blocks.PutTip(changes.Tip)
+}
@@ -0,0 +1,176 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+)
+
+const (
+	maxHeadersPerRequest = 2000
+	maxBlocksPerRequest  = 100
+	syncRequestTimeout   = 30 * time.Second
+)
+
+// syncManager downloads the blocks the local chain is missing from peers that are ahead of it.
+// Headers are downloaded and checked to link up first, then the corresponding blocks are downloaded,
+// validated and appended to the chain in order.
+type syncManager struct {
+	chain *Chain
+	log   *slog.Logger
+
+	running atomic.Bool
+	// synced is set once the initial block download has finished
+	synced atomic.Bool
+}
+
+func newSyncManager(chain *Chain, log *slog.Logger) *syncManager {
+	return &syncManager{
+		chain: chain,
+		log:   log,
+	}
+}
+
+// IsSynced reports whether the initial block download has finished.
+func (s *syncManager) IsSynced() bool {
+	return s.synced.Load()
+}
+
+// Sync catches up with the given peers, starting with the one that has the highest chain.
+// Only one sync runs at a time, concurrent calls return immediately.
+func (s *syncManager) Sync(peers []ConnectedPeer) {
+	if !s.running.CompareAndSwap(false, true) {
+		return
+	}
+	defer s.running.Store(false)
+	defer s.synced.Store(true)
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].nodeInfo.Height > peers[j].nodeInfo.Height
+	})
+
+	for _, peer := range peers {
+		if int(peer.nodeInfo.Height) <= s.chain.Height() {
+			break
+		}
+
+		s.log.Debug("syncing chain", "peer", peer.nodeInfo.ListenAddr, "height", s.chain.Height(), "peerHeight", peer.nodeInfo.Height)
+
+		if err := s.syncWithPeer(peer); err != nil {
+			s.log.Error("failed to sync chain", "peer", peer.nodeInfo.ListenAddr, "error", err)
+			continue
+		}
+	}
+
+	s.log.Debug("chain synced", "height", s.chain.Height())
+}
+
+func (s *syncManager) syncWithPeer(peer ConnectedPeer) error {
+	for {
+		headers, err := s.downloadHeaders(peer)
+		if err != nil {
+			return err
+		}
+
+		if len(headers) == 0 {
+			return nil
+		}
+
+		if err := s.downloadBlocks(peer, headers); err != nil {
+			return err
+		}
+	}
+}
+
+// downloadHeaders requests the headers of the blocks the local chain is missing from the peer.
+// If the chain of the peer has forked from the local main chain, the start of the request is moved back
+// until the returned headers link to a known block. Headers of already known blocks are skipped.
+func (s *syncManager) downloadHeaders(peer ConnectedPeer) ([]*genproto.BlockHeader, error) {
+	fromHeight := s.chain.Height() + 1
+
+	for step := 1; ; step *= 2 {
+		ctx, cancel := context.WithTimeout(context.Background(), syncRequestTimeout)
+		resp, err := peer.peerClient.GetBlockHeaders(ctx, &genproto.BlockRangeRequest{
+			FromHeight: int32(fromHeight),
+			Count:      maxHeadersPerRequest,
+		})
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block headers: %w", err)
+		}
+
+		if len(resp.Headers) == 0 {
+			return nil, nil
+		}
+
+		if !s.chain.HasBlock(resp.Headers[0].PrevHash) {
+			if fromHeight == 1 {
+				return nil, fmt.Errorf("peer chain has a different genesis block")
+			}
+			fromHeight = max(1, fromHeight-step)
+			continue
+		}
+
+		prevHash := resp.Headers[0].PrevHash
+		for _, header := range resp.Headers {
+			if !bytes.Equal(header.PrevHash, prevHash) {
+				return nil, fmt.Errorf("block header at height %d doesn't link to the previous header", header.Height)
+			}
+			prevHash = types.HashBlockHeader(header)
+		}
+
+		headers := slices.DeleteFunc(resp.Headers, func(header *genproto.BlockHeader) bool {
+			return s.chain.HasBlock(types.HashBlockHeader(header))
+		})
+
+		if len(headers) == 0 && len(resp.Headers) == maxHeadersPerRequest {
+			fromHeight += maxHeadersPerRequest
+			continue
+		}
+
+		return headers, nil
+	}
+}
+
+// downloadBlocks requests the blocks for the given headers in batches and appends them to the chain.
+func (s *syncManager) downloadBlocks(peer ConnectedPeer, headers []*genproto.BlockHeader) error {
+	for len(headers) > 0 {
+		batch := headers[:min(len(headers), maxBlocksPerRequest)]
+		headers = headers[len(batch):]
+
+		ctx, cancel := context.WithTimeout(context.Background(), syncRequestTimeout)
+		resp, err := peer.peerClient.GetBlocks(ctx, &genproto.BlockRangeRequest{
+			FromHash: types.HashBlockHeader(batch[0]),
+			Count:    int32(len(batch)),
+		})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to get blocks: %w", err)
+		}
+
+		if len(resp.Blocks) != len(batch) {
+			return fmt.Errorf("expected %d blocks, got %d", len(batch), len(resp.Blocks))
+		}
+
+		for idx, block := range resp.Blocks {
+			if !bytes.Equal(types.HashBlockBytes(block), types.HashBlockHeader(batch[idx])) {
+				return fmt.Errorf("block at height %d doesn't match its header", batch[idx].Height)
+			}
+
+			if err := s.chain.AddBlock(block); err != nil && !errors.Is(err, ErrBlockExists) {
+				return fmt.Errorf("failed to add block at height %d: %w", batch[idx].Height, err)
+			}
+		}
+	}
+
+	return nil
+}
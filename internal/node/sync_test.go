@@ -0,0 +1,128 @@
+package node
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// localNodeClient calls the gRPC service methods of a node directly, without the network.
+type localNodeClient struct {
+	genproto.NodeClient
+	node *Node
+}
+
+func (c localNodeClient) GetBlockHeaders(ctx context.Context, req *genproto.BlockRangeRequest, _ ...grpc.CallOption) (*genproto.BlockHeaders, error) {
+	return c.node.GetBlockHeaders(ctx, req)
+}
+
+func (c localNodeClient) GetBlocks(ctx context.Context, req *genproto.BlockRangeRequest, _ ...grpc.CallOption) (*genproto.Blocks, error) {
+	return c.node.GetBlocks(ctx, req)
+}
+
+func newLocalPeer(n *Node) ConnectedPeer {
+	return ConnectedPeer{
+		peerClient: localNodeClient{node: n},
+		nodeInfo:   n.getNodeInfo(),
+	}
+}
+
+func TestSync(t *testing.T) {
+	var (
+		source  = newTestValidatorNode(t)
+		target  = NewNode(NodeConfig{Version: nodeVersion, ListenAddr: ":0"}, newMemoryChain(t))
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	// More blocks than fit into a single blocks request
+	blockCount := maxBlocksPerRequest + 5
+	for i := 0; i < blockCount; i++ {
+		block, err := createRandomSignedBlock(source.chain, privKey)
+		require.Nil(t, err)
+		require.Nil(t, source.chain.AddBlock(block))
+	}
+
+	require.False(t, target.syncManager.IsSynced())
+
+	target.syncManager.Sync([]ConnectedPeer{newLocalPeer(source)})
+
+	require.True(t, target.syncManager.IsSynced())
+	require.Equal(t, blockCount, target.chain.Height())
+
+	sourceTip, err := source.chain.GetBlockByHeight(blockCount)
+	require.Nil(t, err)
+	targetTip, err := target.chain.GetBlockByHeight(blockCount)
+	require.Nil(t, err)
+	require.Equal(t, sourceTip, targetTip)
+}
+
+func TestGetBlocksByHash(t *testing.T) {
+	n := newTestValidatorNode(t)
+	privKey := cryptography.NewPrivateKey()
+
+	for i := 0; i < 5; i++ {
+		block, err := createRandomSignedBlock(n.chain, privKey)
+		require.Nil(t, err)
+		require.Nil(t, n.chain.AddBlock(block))
+	}
+
+	block, err := n.chain.GetBlockByHeight(2)
+	require.Nil(t, err)
+
+	headers, err := n.GetBlockHeaders(context.Background(), &genproto.BlockRangeRequest{FromHeight: 2, Count: 10})
+	require.Nil(t, err)
+	require.Len(t, headers.Headers, 4)
+	require.Equal(t, block.Header, headers.Headers[0])
+
+	blocks, err := n.GetBlocks(context.Background(), &genproto.BlockRangeRequest{FromHash: headers.Headers[1].PrevHash, Count: 2})
+	require.Nil(t, err)
+	require.Len(t, blocks.Blocks, 2)
+	require.Equal(t, block, blocks.Blocks[0])
+}
+
+func TestGetBlocksWithInvalidCount(t *testing.T) {
+	n := newTestValidatorNode(t)
+
+	for _, count := range []int32{0, -1, math.MinInt32} {
+		blocks, err := n.GetBlocks(context.Background(), &genproto.BlockRangeRequest{FromHeight: 0, Count: count})
+		require.Nil(t, err)
+		require.Empty(t, blocks.Blocks)
+
+		headers, err := n.GetBlockHeaders(context.Background(), &genproto.BlockRangeRequest{FromHeight: 0, Count: count})
+		require.Nil(t, err)
+		require.Empty(t, headers.Headers)
+	}
+}
+
+func TestSyncForkedChain(t *testing.T) {
+	var (
+		source  = newTestValidatorNode(t)
+		target  = NewNode(NodeConfig{Version: nodeVersion, ListenAddr: ":0"}, newMemoryChain(t))
+		privKey = cryptography.NewPrivateKey()
+	)
+
+	for i := 0; i < 3; i++ {
+		block, err := createRandomSignedBlock(target.chain, privKey)
+		require.Nil(t, err)
+		require.Nil(t, target.chain.AddBlock(block))
+	}
+
+	for i := 0; i < 5; i++ {
+		block, err := createRandomSignedBlock(source.chain, privKey)
+		require.Nil(t, err)
+		require.Nil(t, source.chain.AddBlock(block))
+	}
+
+	target.syncManager.Sync([]ConnectedPeer{newLocalPeer(source)})
+
+	require.Equal(t, 5, target.chain.Height())
+
+	_, sourceTipHash := source.chain.Tip()
+	_, targetTipHash := target.chain.Tip()
+	require.Equal(t, sourceTipHash, targetTipHash)
+}
@@ -1,5 +1,7 @@
 package node
 
+import "github.com/oleglegun/blockchain-btc/internal/genproto"
+
 type UTXO struct {
 	Hash string
 	// OutIndex is an index of the output in the transaction
@@ -8,6 +10,15 @@ type UTXO struct {
 	// Every UTXO is considered “unspent” until it is used as an input in a new transaction.
 	// Once it is used, it is no longer a valid UTXO. The blockchain tracks all UTXOs to know what funds are available to be spent.
 	IsSpent bool
+	// Height is the height of the block that created the output
+	Height int
+	// IsCoinbase is set for outputs of coinbase transactions, which can only be spent after the coinbase maturity period
+	IsCoinbase bool
+	// Address is the address of the output owner
+	Address []byte
+	Type    genproto.OutputType
+	// LockScript is the condition of spending outputs without an address
+	LockScript []byte
 }
 
 func NewUTXO(hash string, outIndex int, amount int64) *UTXO {
@@ -20,3 +31,17 @@ func NewUTXO(hash string, outIndex int, amount int64) *UTXO {
 		IsSpent:  isSpent,
 	}
 }
+
+// BlockUndo contains the changes a block made to the UTXO set, so they can be reverted
+// when the block is disconnected from the main chain.
+type BlockUndo struct {
+	// SpentUTXOs are the UTXOs spent by the block in the state before they were spent
+	SpentUTXOs []*UTXO
+	// CreatedUTXOs are the keys of the UTXOs created by the block
+	CreatedUTXOs []string
+	// ValidatorSet is the validator set before the block. It is only set if the block changed the set.
+	ValidatorSet *ValidatorSet `json:",omitempty"`
+	// SeedCommitment is the seed commitment of the block producer before the block (proof-of-stake only).
+	// It is nil if the producer had no commitment.
+	SeedCommitment *SeedCommitment `json:",omitempty"`
+}
@@ -0,0 +1,143 @@
+package node
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/types"
+)
+
+// utxoView is a layer of UTXO changes on top of the UTXO store. The transactions of a block are validated
+// against the view in order, so every transaction sees the outputs created and spent by the previous ones.
+// The store itself is never changed by the view. When a block is connected or disconnected, its changes
+// are staged in a view and written to the store in a single batch once all of them succeeded.
+type utxoView struct {
+	store UTXOStore
+	// pool contains the changes of the pending transactions between the store and the view, if any
+	pool PendingUTXOs
+	// poolHeight is the height the outputs of the pending transactions are seen at
+	poolHeight int
+	// utxos contains the UTXOs changed in the view by their keys. Deleted UTXOs are nil.
+	utxos map[string]*UTXO
+}
+
+// PendingUTXOs are the changes the pending transactions of a mempool make to the UTXO set.
+type PendingUTXOs interface {
+	// PendingOutput returns the output with the given key created by a pending transaction.
+	PendingOutput(key string) (*UTXO, bool)
+	// IsPendingSpent reports whether a pending transaction spends the output with the given key.
+	IsPendingSpent(key string) bool
+}
+
+func newUTXOView(store UTXOStore) *utxoView {
+	return &utxoView{
+		store: store,
+		utxos: make(map[string]*UTXO),
+	}
+}
+
+// newPoolUTXOView creates a view of the UTXO store with the changes of the pending transactions applied.
+// The outputs of the pending transactions are seen as if they were included in the block at the given height.
+func newPoolUTXOView(store UTXOStore, pool PendingUTXOs, height int) *utxoView {
+	view := newUTXOView(store)
+	view.pool = pool
+	view.poolHeight = height
+
+	return view
+}
+
+// Get returns the UTXO with the given key as seen by the view.
+func (v *utxoView) Get(key string) (*UTXO, error) {
+	if utxo, ok := v.utxos[key]; ok {
+		if utxo == nil {
+			return nil, fmt.Errorf("utxo [%s] is not found", key)
+		}
+		return utxo, nil
+	}
+
+	if v.pool == nil {
+		return v.store.Get(key)
+	}
+
+	if output, ok := v.pool.PendingOutput(key); ok {
+		utxo := *output
+		utxo.Height = v.poolHeight
+		utxo.IsSpent = v.pool.IsPendingSpent(key)
+		return &utxo, nil
+	}
+
+	utxo, err := v.store.Get(key)
+	if err != nil || !v.pool.IsPendingSpent(key) {
+		return utxo, err
+	}
+
+	spentUTXO := *utxo
+	spentUTXO.IsSpent = true
+	return &spentUTXO, nil
+}
+
+// put adds the UTXO to the view, replacing the UTXO with the same key.
+func (v *utxoView) put(utxo *UTXO) {
+	v.utxos[getUTXOKey(utxo.Hash, utxo.OutIndex)] = utxo
+}
+
+// delete removes the UTXO with the given key from the view.
+func (v *utxoView) delete(key string) {
+	v.utxos[key] = nil
+}
+
+// batch returns the changes of the view as a batch for the UTXO store.
+func (v *utxoView) batch() *UTXOBatch {
+	batch := &UTXOBatch{}
+
+	for key, utxo := range v.utxos {
+		if utxo == nil {
+			batch.Delete = append(batch.Delete, key)
+		} else {
+			batch.Put = append(batch.Put, utxo)
+		}
+	}
+
+	return batch
+}
+
+// apply adds the outputs of the transaction included in the block at the given height to the view
+// and marks the UTXOs spent by its inputs as spent. Data outputs can't be spent and are never added.
+// The transaction is expected to be valid.
+func (v *utxoView) apply(tx *genproto.Transaction, height int, isCoinbase bool) error {
+	hash := types.HashTransactionString(tx)
+
+	for _, txInput := range tx.Inputs {
+		key := getUTXOKey(hex.EncodeToString(txInput.PrevTxHash), int(txInput.PrevTxOutIndex))
+		utxo, err := v.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to get utxo: %w", err)
+		}
+
+		spentUTXO := *utxo
+		spentUTXO.IsSpent = true
+		v.utxos[key] = &spentUTXO
+	}
+
+	for idx, txOutput := range tx.Outputs {
+		if txOutput.Type == genproto.OutputType_DATA {
+			continue
+		}
+		v.utxos[getUTXOKey(hash, idx)] = newOutputUTXO(hash, idx, txOutput, height, isCoinbase)
+	}
+
+	return nil
+}
+
+// newOutputUTXO creates the UTXO of the output of a transaction included in the block at the given height.
+func newOutputUTXO(hash string, idx int, txOutput *genproto.TxOutput, height int, isCoinbase bool) *UTXO {
+	utxo := NewUTXO(hash, idx, txOutput.Amount)
+	utxo.Height = height
+	utxo.IsCoinbase = isCoinbase
+	utxo.Address = txOutput.Address
+	utxo.Type = txOutput.Type
+	utxo.LockScript = txOutput.LockScript
+
+	return utxo
+}
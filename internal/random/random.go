@@ -26,7 +26,7 @@ func RandomBlock() *genproto.Block {
 		Height:    int32(mathrand.IntN(1e3)),
 		PrevHash:  Random32ByteHash(),
 		RootHash:  Random32ByteHash(),
-		Timestamp: time.Now().UnixNano(),
+		Timestamp: time.Now().Unix(),
 	}
 
 	return &genproto.Block{
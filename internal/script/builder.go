@@ -0,0 +1,82 @@
+package script
+
+import "fmt"
+
+// Builder builds a script instruction by instruction, always using the shortest pushes.
+type Builder struct {
+	script []byte
+	err    error
+}
+
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+func (b *Builder) AddOp(op Opcode) *Builder {
+	b.script = append(b.script, byte(op))
+	return b
+}
+
+func (b *Builder) AddData(data []byte) *Builder {
+	if len(data) > MaxElementSize {
+		b.err = fmt.Errorf("%w: element has %d bytes, the limit is %d", ErrLimitExceeded, len(data), MaxElementSize)
+		return b
+	}
+
+	b.script = pushData(b.script, data)
+	return b
+}
+
+func (b *Builder) AddInt(n int64) *Builder {
+	b.script = pushData(b.script, encodeNumber(n))
+	return b
+}
+
+// Script returns the built script or the first error that occurred while building it.
+func (b *Builder) Script() ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if len(b.script) > MaxScriptSize {
+		return nil, fmt.Errorf("%w: script has %d bytes, the limit is %d", ErrLimitExceeded, len(b.script), MaxScriptSize)
+	}
+
+	return b.script, nil
+}
+
+// PubKeyScript locks an output to the public key. It is unlocked by a signature of the key.
+func PubKeyScript(pubKey []byte) ([]byte, error) {
+	return NewBuilder().AddData(pubKey).AddOp(OP_CHECKSIG).Script()
+}
+
+// MultisigScript locks an output to M of the public keys. It is unlocked by M signatures
+// in the same order as their keys.
+func MultisigScript(m int, pubKeys [][]byte) ([]byte, error) {
+	if len(pubKeys) == 0 || len(pubKeys) > MaxMultisigKeys || m < 1 || m > len(pubKeys) {
+		return nil, fmt.Errorf("%w: %d of %d multisig", ErrInvalidScript, m, len(pubKeys))
+	}
+
+	b := NewBuilder().AddInt(int64(m))
+	for _, pubKey := range pubKeys {
+		b.AddData(pubKey)
+	}
+
+	return b.AddInt(int64(len(pubKeys))).AddOp(OP_CHECKMULTISIG).Script()
+}
+
+// HashTimeLockScript locks an output to the recipient key and the SHA-256 hash of a secret.
+// After the timeout, a block height or a timestamp like a transaction lock time, the output can be refunded
+// with the refund key instead. The recipient unlocks it with <signature> <secret> OP_1, the refund
+// with <signature> OP_0 in a transaction whose lock time is at or after the timeout.
+func HashTimeLockScript(secretHash, recipientPubKey, refundPubKey []byte, timeout int64) ([]byte, error) {
+	return NewBuilder().
+		AddOp(OP_IF).
+		AddOp(OP_SHA256).AddData(secretHash).AddOp(OP_EQUALVERIFY).
+		AddData(recipientPubKey).AddOp(OP_CHECKSIG).
+		AddOp(OP_ELSE).
+		AddInt(timeout).AddOp(OP_CHECKLOCKTIMEVERIFY).AddOp(OP_DROP).
+		AddData(refundPubKey).AddOp(OP_CHECKSIG).
+		AddOp(OP_ENDIF).
+		Script()
+}
@@ -0,0 +1,361 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"slices"
+)
+
+// Verify checks that the unlock script of an input unlocks the lock script of the spent output.
+// The unlock script may only push data. Its stack is passed to the lock script, which must finish
+// with a single true element on the stack. The scripts can't loop, so the evaluation is bounded
+// by the script size and the limits on the number of opcodes and stack elements.
+func Verify(unlockScript, lockScript []byte, checker Checker) error {
+	unlock, err := parse(unlockScript)
+	if err != nil {
+		return fmt.Errorf("unlock script: %w", err)
+	}
+
+	for _, ins := range unlock {
+		if !ins.op.isPush() {
+			return ErrUnlockNotPushes
+		}
+	}
+
+	lock, err := parse(lockScript)
+	if err != nil {
+		return fmt.Errorf("lock script: %w", err)
+	}
+
+	e := &engine{checker: checker}
+
+	if err := e.execute(unlock); err != nil {
+		return fmt.Errorf("unlock script: %w", err)
+	}
+
+	if err := e.execute(lock); err != nil {
+		return fmt.Errorf("lock script: %w", err)
+	}
+
+	if len(e.stack) == 0 || !asBool(e.stack[len(e.stack)-1]) {
+		return ErrScriptFailed
+	}
+
+	// Extra elements could be added to the unlock script by anybody without breaking its signatures
+	if len(e.stack) != 1 {
+		return fmt.Errorf("%w: %d elements are left on the stack", ErrInvalidScript, len(e.stack))
+	}
+
+	return nil
+}
+
+// CountSigOps returns the number of signature checks in the script. Multisig checks count as many checks
+// as their number of keys if it is pushed right before the check, otherwise as MaxMultisigKeys.
+// Only the instructions before the first parsing error are counted.
+func CountSigOps(script []byte) int {
+	instructions, _ := parse(script)
+
+	var count int
+	for idx, ins := range instructions {
+		switch ins.op {
+		case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+			count++
+		case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+			if idx > 0 && instructions[idx-1].op >= OP_1 && instructions[idx-1].op <= OP_16 {
+				count += int(instructions[idx-1].op-OP_1) + 1
+			} else {
+				count += MaxMultisigKeys
+			}
+		}
+	}
+
+	return count
+}
+
+// engine is the state of a script evaluation.
+type engine struct {
+	checker Checker
+	stack   [][]byte
+	// conds holds an entry for every enclosing OP_IF, false if its current branch is skipped
+	conds []bool
+	ops   int
+}
+
+func (e *engine) execute(instructions []instruction) error {
+	for _, ins := range instructions {
+		if !ins.op.isPush() {
+			e.ops++
+			if e.ops > MaxOps {
+				return fmt.Errorf("%w: more than %d opcodes", ErrLimitExceeded, MaxOps)
+			}
+		}
+
+		isExecuted := !slices.Contains(e.conds, false)
+		if !isExecuted && (ins.op < OP_IF || ins.op > OP_ENDIF) {
+			continue
+		}
+
+		if err := e.step(ins, isExecuted); err != nil {
+			return fmt.Errorf("%s: %w", ins.op, err)
+		}
+
+		if len(e.stack) > MaxStackSize {
+			return fmt.Errorf("%w: more than %d stack elements", ErrLimitExceeded, MaxStackSize)
+		}
+	}
+
+	if len(e.conds) != 0 {
+		return fmt.Errorf("%w: OP_IF without OP_ENDIF", ErrInvalidScript)
+	}
+
+	return nil
+}
+
+func (e *engine) step(ins instruction, isExecuted bool) error {
+	if ins.op.isPush() {
+		value := ins.pushedValue()
+		if len(value) > MaxElementSize {
+			return fmt.Errorf("%w: element has %d bytes, the limit is %d", ErrLimitExceeded, len(value), MaxElementSize)
+		}
+		e.push(value)
+		return nil
+	}
+
+	switch ins.op {
+	case OP_NOP:
+
+	case OP_IF, OP_NOTIF:
+		var cond bool
+		if isExecuted {
+			value, err := e.pop()
+			if err != nil {
+				return err
+			}
+
+			// Any other true value could replace the branch selector without breaking the signatures
+			if len(value) > 1 || (len(value) == 1 && value[0] != 1) {
+				return fmt.Errorf("%w: branch selector must be empty or 1", ErrInvalidScript)
+			}
+
+			cond = asBool(value) == (ins.op == OP_IF)
+		}
+		e.conds = append(e.conds, cond)
+
+	case OP_ELSE:
+		if len(e.conds) == 0 {
+			return fmt.Errorf("%w: OP_ELSE without OP_IF", ErrInvalidScript)
+		}
+		e.conds[len(e.conds)-1] = !e.conds[len(e.conds)-1]
+
+	case OP_ENDIF:
+		if len(e.conds) == 0 {
+			return fmt.Errorf("%w: OP_ENDIF without OP_IF", ErrInvalidScript)
+		}
+		e.conds = e.conds[:len(e.conds)-1]
+
+	case OP_VERIFY:
+		return e.verify()
+
+	case OP_RETURN:
+		return ErrEarlyReturn
+
+	case OP_DROP:
+		_, err := e.pop()
+		return err
+
+	case OP_DUP:
+		value, err := e.peek()
+		if err != nil {
+			return err
+		}
+		e.push(value)
+
+	case OP_SWAP:
+		if len(e.stack) < 2 {
+			return ErrStackUnderflow
+		}
+		top := len(e.stack) - 1
+		e.stack[top], e.stack[top-1] = e.stack[top-1], e.stack[top]
+
+	case OP_SIZE:
+		value, err := e.peek()
+		if err != nil {
+			return err
+		}
+		e.push(encodeNumber(int64(len(value))))
+
+	case OP_EQUAL, OP_EQUALVERIFY:
+		a, err := e.pop()
+		if err != nil {
+			return err
+		}
+		b, err := e.pop()
+		if err != nil {
+			return err
+		}
+		e.push(fromBool(bytes.Equal(a, b)))
+
+		if ins.op == OP_EQUALVERIFY {
+			return e.verify()
+		}
+
+	case OP_SHA256:
+		value, err := e.pop()
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(value)
+		e.push(hash[:])
+
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		pubKey, err := e.pop()
+		if err != nil {
+			return err
+		}
+		sig, err := e.pop()
+		if err != nil {
+			return err
+		}
+		e.push(fromBool(e.checker.CheckSignature(sig, pubKey)))
+
+		if ins.op == OP_CHECKSIGVERIFY {
+			return e.verify()
+		}
+
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		if err := e.checkMultisig(); err != nil {
+			return err
+		}
+
+		if ins.op == OP_CHECKMULTISIGVERIFY {
+			return e.verify()
+		}
+
+	case OP_CHECKLOCKTIMEVERIFY, OP_CHECKSEQUENCEVERIFY:
+		// The lock time is left on the stack, so the opcodes can be redefined without breaking old scripts
+		value, err := e.peek()
+		if err != nil {
+			return err
+		}
+		n, err := decodeNumber(value)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return fmt.Errorf("%w: negative lock time %d", ErrInvalidScript, n)
+		}
+
+		if ins.op == OP_CHECKLOCKTIMEVERIFY && !e.checker.CheckLockTime(n) {
+			return fmt.Errorf("%w: lock time %d", ErrLockTimeNotMet, n)
+		}
+		if ins.op == OP_CHECKSEQUENCEVERIFY && !e.checker.CheckSequence(n) {
+			return fmt.Errorf("%w: sequence %d", ErrSequenceNotMet, n)
+		}
+
+	default:
+		return fmt.Errorf("%w: unexpected opcode", ErrInvalidScript)
+	}
+
+	return nil
+}
+
+// checkMultisig checks M of N signatures. The stack holds the signatures, M, the public keys and N, with N on top.
+// The signatures must be in the same order as their keys.
+func (e *engine) checkMultisig() error {
+	keyCount, err := e.popNumber()
+	if err != nil {
+		return err
+	}
+	if keyCount < 0 || keyCount > MaxMultisigKeys {
+		return fmt.Errorf("%w: %d keys, the limit is %d", ErrInvalidScript, keyCount, MaxMultisigKeys)
+	}
+
+	e.ops += int(keyCount)
+	if e.ops > MaxOps {
+		return fmt.Errorf("%w: more than %d opcodes", ErrLimitExceeded, MaxOps)
+	}
+
+	pubKeys, err := e.popN(int(keyCount))
+	if err != nil {
+		return err
+	}
+
+	sigCount, err := e.popNumber()
+	if err != nil {
+		return err
+	}
+	if sigCount < 0 || sigCount > keyCount {
+		return fmt.Errorf("%w: %d signatures for %d keys", ErrInvalidScript, sigCount, keyCount)
+	}
+
+	sigs, err := e.popN(int(sigCount))
+	if err != nil {
+		return err
+	}
+
+	// Every key is checked against the next unmatched signature at most once
+	var matched int
+	for keyIdx, pubKey := range pubKeys {
+		// All signatures are matched or there are fewer keys left than unmatched signatures
+		if matched == len(sigs) || len(sigs)-matched > len(pubKeys)-keyIdx {
+			break
+		}
+		if e.checker.CheckSignature(sigs[matched], pubKey) {
+			matched++
+		}
+	}
+
+	e.push(fromBool(matched == len(sigs)))
+	return nil
+}
+
+func (e *engine) push(value []byte) {
+	e.stack = append(e.stack, value)
+}
+
+func (e *engine) peek() ([]byte, error) {
+	if len(e.stack) == 0 {
+		return nil, ErrStackUnderflow
+	}
+	return e.stack[len(e.stack)-1], nil
+}
+
+func (e *engine) pop() ([]byte, error) {
+	value, err := e.peek()
+	if err != nil {
+		return nil, err
+	}
+	e.stack = e.stack[:len(e.stack)-1]
+	return value, nil
+}
+
+// popN pops n elements and returns them in the order they were pushed.
+func (e *engine) popN(n int) ([][]byte, error) {
+	if len(e.stack) < n {
+		return nil, ErrStackUnderflow
+	}
+	values := slices.Clone(e.stack[len(e.stack)-n:])
+	e.stack = e.stack[:len(e.stack)-n]
+	return values, nil
+}
+
+func (e *engine) popNumber() (int64, error) {
+	value, err := e.pop()
+	if err != nil {
+		return 0, err
+	}
+	return decodeNumber(value)
+}
+
+// verify pops the top element and fails unless it is true.
+func (e *engine) verify() error {
+	value, err := e.pop()
+	if err != nil {
+		return err
+	}
+	if !asBool(value) {
+		return ErrVerifyFailed
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package script
+
+import "fmt"
+
+// Opcode is an instruction of the script language. The values follow the Bitcoin script opcodes.
+type Opcode byte
+
+const (
+	// OP_0 pushes an empty element, which is false.
+	OP_0 Opcode = 0x00
+	// Opcodes from 0x01 to 0x4b push the given number of bytes following the opcode.
+	OP_DATA_1  Opcode = 0x01
+	OP_DATA_75 Opcode = 0x4b
+	// OP_PUSHDATA1 pushes the number of bytes given by the next byte.
+	OP_PUSHDATA1 Opcode = 0x4c
+	// OP_PUSHDATA2 pushes the number of bytes given by the next two bytes in little-endian order.
+	OP_PUSHDATA2 Opcode = 0x4d
+	OP_1NEGATE   Opcode = 0x4f
+	// Opcodes from OP_1 to OP_16 push the numbers from 1 to 16.
+	OP_1  Opcode = 0x51
+	OP_16 Opcode = 0x60
+
+	OP_NOP    Opcode = 0x61
+	OP_IF     Opcode = 0x63
+	OP_NOTIF  Opcode = 0x64
+	OP_ELSE   Opcode = 0x67
+	OP_ENDIF  Opcode = 0x68
+	OP_VERIFY Opcode = 0x69
+	OP_RETURN Opcode = 0x6a
+
+	OP_DROP Opcode = 0x75
+	OP_DUP  Opcode = 0x76
+	OP_SWAP Opcode = 0x7c
+	OP_SIZE Opcode = 0x82
+
+	OP_EQUAL       Opcode = 0x87
+	OP_EQUALVERIFY Opcode = 0x88
+
+	OP_SHA256 Opcode = 0xa8
+
+	OP_CHECKSIG            Opcode = 0xac
+	OP_CHECKSIGVERIFY      Opcode = 0xad
+	OP_CHECKMULTISIG       Opcode = 0xae
+	OP_CHECKMULTISIGVERIFY Opcode = 0xaf
+
+	// OP_CHECKLOCKTIMEVERIFY fails the script unless the spending transaction is included
+	// at or above the block height on top of the stack.
+	OP_CHECKLOCKTIMEVERIFY Opcode = 0xb1
+	// OP_CHECKSEQUENCEVERIFY fails the script unless the spent output is included
+	// at least the number of blocks on top of the stack before the spending transaction.
+	OP_CHECKSEQUENCEVERIFY Opcode = 0xb2
+)
+
+var opcodeNames = map[Opcode]string{
+	OP_0:                   "OP_0",
+	OP_PUSHDATA1:           "OP_PUSHDATA1",
+	OP_PUSHDATA2:           "OP_PUSHDATA2",
+	OP_1NEGATE:             "OP_1NEGATE",
+	OP_NOP:                 "OP_NOP",
+	OP_IF:                  "OP_IF",
+	OP_NOTIF:               "OP_NOTIF",
+	OP_ELSE:                "OP_ELSE",
+	OP_ENDIF:               "OP_ENDIF",
+	OP_VERIFY:              "OP_VERIFY",
+	OP_RETURN:              "OP_RETURN",
+	OP_DROP:                "OP_DROP",
+	OP_DUP:                 "OP_DUP",
+	OP_SWAP:                "OP_SWAP",
+	OP_SIZE:                "OP_SIZE",
+	OP_EQUAL:               "OP_EQUAL",
+	OP_EQUALVERIFY:         "OP_EQUALVERIFY",
+	OP_SHA256:              "OP_SHA256",
+	OP_CHECKSIG:            "OP_CHECKSIG",
+	OP_CHECKSIGVERIFY:      "OP_CHECKSIGVERIFY",
+	OP_CHECKMULTISIG:       "OP_CHECKMULTISIG",
+	OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY",
+	OP_CHECKLOCKTIMEVERIFY: "OP_CHECKLOCKTIMEVERIFY",
+	OP_CHECKSEQUENCEVERIFY: "OP_CHECKSEQUENCEVERIFY",
+}
+
+func (op Opcode) String() string {
+	switch {
+	case op >= OP_DATA_1 && op <= OP_DATA_75:
+		return fmt.Sprintf("OP_DATA_%d", op)
+	case op >= OP_1 && op <= OP_16:
+		return fmt.Sprintf("OP_%d", op-OP_1+1)
+	}
+
+	if name, ok := opcodeNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("OP_UNKNOWN_%#x", byte(op))
+}
+
+// isKnown reports whether the opcode is a part of the language. Scripts with unknown opcodes are invalid
+// even if the opcodes are never executed.
+func (op Opcode) isKnown() bool {
+	if op <= OP_PUSHDATA2 || (op >= OP_1 && op <= OP_16) {
+		return true
+	}
+	_, ok := opcodeNames[op]
+	return ok
+}
+
+// isPush reports whether the opcode only pushes data or a number to the stack.
+func (op Opcode) isPush() bool {
+	return op <= OP_PUSHDATA2 || op == OP_1NEGATE || (op >= OP_1 && op <= OP_16)
+}
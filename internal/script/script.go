@@ -0,0 +1,220 @@
+package script
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// MaxScriptSize is the maximum size of a lock or unlock script in bytes.
+	MaxScriptSize = 10000
+	// MaxElementSize is the maximum size of a stack element in bytes.
+	MaxElementSize = 520
+	// MaxOps is the maximum number of non-push opcodes a script can execute. The keys of multisig checks count as well.
+	MaxOps = 201
+	// MaxStackSize is the maximum number of elements on the stack.
+	MaxStackSize = 1000
+	// MaxMultisigKeys is the maximum number of public keys of a multisig check.
+	MaxMultisigKeys = 20
+	// maxNumberSize is the maximum size of an element interpreted as a number.
+	maxNumberSize = 4
+)
+
+var (
+	ErrScriptFailed    = errors.New("script evaluated to false")
+	ErrVerifyFailed    = errors.New("verify failed")
+	ErrEarlyReturn     = errors.New("script returned early")
+	ErrInvalidScript   = errors.New("invalid script")
+	ErrLimitExceeded   = errors.New("script limit exceeded")
+	ErrLockTimeNotMet  = errors.New("lock time is not reached")
+	ErrSequenceNotMet  = errors.New("relative lock time is not reached")
+	ErrStackUnderflow  = errors.New("not enough elements on the stack")
+	ErrUnlockNotPushes = errors.New("unlock script must only push data")
+)
+
+// Checker provides the parts of the script evaluation that depend on the spending transaction.
+type Checker interface {
+	// CheckSignature reports whether the signature signs the spending input with the public key.
+	CheckSignature(sig, pubKey []byte) bool
+	// CheckLockTime reports whether the lock time of the spending transaction, a block height or a timestamp,
+	// satisfies the lock time.
+	CheckLockTime(lockTime int64) bool
+	// CheckSequence reports whether the sequence of the spending input satisfies the relative lock.
+	CheckSequence(sequence int64) bool
+}
+
+// instruction is a parsed opcode along with the data it pushes.
+type instruction struct {
+	op   Opcode
+	data []byte
+}
+
+// parse splits the script into instructions. Scripts with unknown opcodes, truncated pushes
+// or pushes that don't use the shortest encoding are invalid.
+func parse(script []byte) ([]instruction, error) {
+	if len(script) > MaxScriptSize {
+		return nil, fmt.Errorf("%w: script has %d bytes, the limit is %d", ErrLimitExceeded, len(script), MaxScriptSize)
+	}
+
+	var instructions []instruction
+	for pos := 0; pos < len(script); {
+		start := pos
+		op := Opcode(script[pos])
+		pos++
+
+		if !op.isKnown() {
+			return nil, fmt.Errorf("%w: unknown opcode %s", ErrInvalidScript, op)
+		}
+
+		var size int
+		switch {
+		case op >= OP_DATA_1 && op <= OP_DATA_75:
+			size = int(op)
+		case op == OP_PUSHDATA1:
+			if pos+1 > len(script) {
+				return nil, fmt.Errorf("%w: truncated %s", ErrInvalidScript, op)
+			}
+			size = int(script[pos])
+			pos++
+		case op == OP_PUSHDATA2:
+			if pos+2 > len(script) {
+				return nil, fmt.Errorf("%w: truncated %s", ErrInvalidScript, op)
+			}
+			size = int(binary.LittleEndian.Uint16(script[pos:]))
+			pos += 2
+		}
+
+		if pos+size > len(script) {
+			return nil, fmt.Errorf("%w: %s pushes %d bytes, only %d left", ErrInvalidScript, op, size, len(script)-pos)
+		}
+
+		ins := instruction{op: op, data: script[pos : pos+size]}
+		pos += size
+
+		if op.isPush() && !bytes.Equal(pushData(nil, ins.pushedValue()), script[start:pos]) {
+			return nil, fmt.Errorf("%w: %s doesn't push its data with the shortest encoding", ErrInvalidScript, op)
+		}
+
+		instructions = append(instructions, ins)
+	}
+
+	return instructions, nil
+}
+
+// pushPrefix returns the opcode and the length bytes of the shortest push of the data.
+func pushPrefix(data []byte) []byte {
+	switch {
+	case len(data) == 0:
+		return []byte{byte(OP_0)}
+	case len(data) == 1 && data[0] >= 1 && data[0] <= 16:
+		return []byte{byte(OP_1) + data[0] - 1}
+	case len(data) == 1 && data[0] == 0x81:
+		return []byte{byte(OP_1NEGATE)}
+	case len(data) <= int(OP_DATA_75):
+		return []byte{byte(len(data))}
+	case len(data) <= 0xff:
+		return []byte{byte(OP_PUSHDATA1), byte(len(data))}
+	default:
+		return binary.LittleEndian.AppendUint16([]byte{byte(OP_PUSHDATA2)}, uint16(len(data)))
+	}
+}
+
+// pushData appends the shortest push of the data to the script.
+func pushData(script, data []byte) []byte {
+	prefix := pushPrefix(data)
+	script = append(script, prefix...)
+	if len(prefix) == 1 && (Opcode(prefix[0]) == OP_0 || Opcode(prefix[0]) > OP_PUSHDATA2) {
+		// Small numbers are pushed by the opcode alone
+		return script
+	}
+	return append(script, data...)
+}
+
+// pushedValue returns the element pushed by the push instruction.
+func (ins instruction) pushedValue() []byte {
+	switch {
+	case ins.op == OP_1NEGATE:
+		return encodeNumber(-1)
+	case ins.op >= OP_1 && ins.op <= OP_16:
+		return encodeNumber(int64(ins.op - OP_1 + 1))
+	}
+	return ins.data
+}
+
+// encodeNumber encodes the number as a stack element: little-endian magnitude with the sign in the highest bit.
+func encodeNumber(n int64) []byte {
+	if n == 0 {
+		return []byte{}
+	}
+
+	negative := n < 0
+	if negative {
+		n = -n
+	}
+
+	var b []byte
+	for n > 0 {
+		b = append(b, byte(n&0xff))
+		n >>= 8
+	}
+
+	// The highest bit is the sign, so an extra byte is added if it is taken by the magnitude
+	if b[len(b)-1]&0x80 != 0 {
+		if negative {
+			b = append(b, 0x80)
+		} else {
+			b = append(b, 0x00)
+		}
+	} else if negative {
+		b[len(b)-1] |= 0x80
+	}
+
+	return b
+}
+
+// decodeNumber decodes a stack element encoded by encodeNumber. Elements that are too long
+// or don't use the shortest encoding are rejected.
+func decodeNumber(b []byte) (int64, error) {
+	if len(b) > maxNumberSize {
+		return 0, fmt.Errorf("%w: number has %d bytes, the limit is %d", ErrInvalidScript, len(b), maxNumberSize)
+	}
+
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	// The highest byte may only be zero (apart from the sign) if the sign doesn't fit into the byte below
+	if b[len(b)-1]&0x7f == 0 && (len(b) == 1 || b[len(b)-2]&0x80 == 0) {
+		return 0, fmt.Errorf("%w: number doesn't use the shortest encoding", ErrInvalidScript)
+	}
+
+	var n int64
+	for idx, v := range b {
+		n |= int64(v) << (8 * idx)
+	}
+
+	if signBit := int64(0x80) << (8 * (len(b) - 1)); n&signBit != 0 {
+		return -(n &^ signBit), nil
+	}
+	return n, nil
+}
+
+// asBool interprets the stack element as a boolean. Zero and negative zero of any length are false.
+func asBool(b []byte) bool {
+	for idx, v := range b {
+		if v != 0 {
+			// Negative zero
+			return idx != len(b)-1 || v != 0x80
+		}
+	}
+	return false
+}
+
+func fromBool(v bool) []byte {
+	if v {
+		return []byte{1}
+	}
+	return []byte{}
+}
@@ -0,0 +1,227 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testChecker accepts the signatures made by testSign and compares the lock times with a fixed lock time
+// and sequence of the spending input.
+type testChecker struct {
+	lockTime int64
+	sequence int64
+}
+
+func (c *testChecker) CheckSignature(sig, pubKey []byte) bool {
+	return bytes.Equal(sig, testSign(pubKey))
+}
+
+func (c *testChecker) CheckLockTime(lockTime int64) bool {
+	return c.lockTime >= lockTime
+}
+
+func (c *testChecker) CheckSequence(sequence int64) bool {
+	return c.sequence >= sequence
+}
+
+func testSign(pubKey []byte) []byte {
+	return append([]byte("sig:"), pubKey...)
+}
+
+func unlockScript(t *testing.T, elements ...[]byte) []byte {
+	b := NewBuilder()
+	for _, element := range elements {
+		b.AddData(element)
+	}
+
+	script, err := b.Script()
+	require.Nil(t, err)
+	return script
+}
+
+func TestNumberEncoding(t *testing.T) {
+	for _, n := range []int64{0, 1, -1, 16, 127, 128, -128, 255, 256, 1 << 20, -(1 << 20), 1<<31 - 1} {
+		decoded, err := decodeNumber(encodeNumber(n))
+		require.Nil(t, err)
+		assert.Equal(t, n, decoded)
+	}
+
+	assert.Equal(t, []byte{0x80, 0x00}, encodeNumber(128))
+	assert.Equal(t, []byte{0x80, 0x80}, encodeNumber(-128))
+
+	// Numbers must use the shortest encoding and fit into 4 bytes
+	for _, b := range [][]byte{{0x00}, {0x80}, {0x01, 0x00}, {0x01, 0x02, 0x03, 0x04, 0x05}} {
+		_, err := decodeNumber(b)
+		assert.ErrorIs(t, err, ErrInvalidScript)
+	}
+
+	assert.False(t, asBool([]byte{0x00, 0x80}))
+	assert.True(t, asBool([]byte{0x80, 0x00}))
+}
+
+func TestParse(t *testing.T) {
+	script, err := NewBuilder().AddInt(5).AddData(make([]byte, 80)).AddData(make([]byte, 300)).AddOp(OP_DROP).Script()
+	require.Nil(t, err)
+
+	instructions, err := parse(script)
+	require.Nil(t, err)
+	require.Len(t, instructions, 4)
+	assert.Equal(t, OP_1+4, instructions[0].op)
+	assert.Equal(t, OP_PUSHDATA1, instructions[1].op)
+	assert.Equal(t, OP_PUSHDATA2, instructions[2].op)
+	assert.Len(t, instructions[2].data, 300)
+
+	for _, script := range [][]byte{
+		// Unknown opcode
+		{0xff},
+		// Truncated pushes
+		{0x02, 0x01},
+		{byte(OP_PUSHDATA1)},
+		// Pushes not using the shortest encoding
+		{0x01, 0x05},
+		{byte(OP_PUSHDATA1), 0x01, 0xaa},
+	} {
+		_, err := parse(script)
+		assert.ErrorIs(t, err, ErrInvalidScript, "script %x", script)
+	}
+}
+
+func TestPubKeyScript(t *testing.T) {
+	var (
+		pubKey  = []byte("alice")
+		checker = &testChecker{}
+	)
+
+	lockScript, err := PubKeyScript(pubKey)
+	require.Nil(t, err)
+
+	assert.Nil(t, Verify(unlockScript(t, testSign(pubKey)), lockScript, checker))
+	assert.ErrorIs(t, Verify(unlockScript(t, testSign([]byte("bob"))), lockScript, checker), ErrScriptFailed)
+	assert.ErrorIs(t, Verify(nil, lockScript, checker), ErrStackUnderflow)
+
+	// Extra elements are not allowed
+	assert.ErrorIs(t, Verify(unlockScript(t, []byte{1}, testSign(pubKey)), lockScript, checker), ErrInvalidScript)
+
+	// Unlock scripts can only push data
+	unlock := append(unlockScript(t, testSign(pubKey)), byte(OP_DUP), byte(OP_DROP))
+	assert.ErrorIs(t, Verify(unlock, lockScript, checker), ErrUnlockNotPushes)
+}
+
+func TestMultisigScript(t *testing.T) {
+	var (
+		pubKeys = [][]byte{[]byte("alice"), []byte("bob"), []byte("carol")}
+		checker = &testChecker{}
+	)
+
+	lockScript, err := MultisigScript(2, pubKeys)
+	require.Nil(t, err)
+	assert.Equal(t, 3, CountSigOps(lockScript))
+
+	assert.Nil(t, Verify(unlockScript(t, testSign(pubKeys[0]), testSign(pubKeys[2])), lockScript, checker))
+	assert.Nil(t, Verify(unlockScript(t, testSign(pubKeys[1]), testSign(pubKeys[2])), lockScript, checker))
+
+	// The signatures must be in the order of the keys
+	assert.ErrorIs(t, Verify(unlockScript(t, testSign(pubKeys[2]), testSign(pubKeys[0])), lockScript, checker), ErrScriptFailed)
+	// The same signature can't be used twice
+	assert.ErrorIs(t, Verify(unlockScript(t, testSign(pubKeys[0]), testSign(pubKeys[0])), lockScript, checker), ErrScriptFailed)
+	assert.ErrorIs(t, Verify(unlockScript(t, testSign(pubKeys[0])), lockScript, checker), ErrStackUnderflow)
+
+	_, err = MultisigScript(4, pubKeys)
+	assert.ErrorIs(t, err, ErrInvalidScript)
+}
+
+func TestHashTimeLockScript(t *testing.T) {
+	var (
+		secret     = []byte("secret")
+		secretHash = sha256.Sum256(secret)
+		recipient  = []byte("alice")
+		refund     = []byte("bob")
+	)
+
+	lockScript, err := HashTimeLockScript(secretHash[:], recipient, refund, 100)
+	require.Nil(t, err)
+	assert.Equal(t, 2, CountSigOps(lockScript))
+
+	claim := append(unlockScript(t, testSign(recipient), secret), byte(OP_1))
+	assert.Nil(t, Verify(claim, lockScript, &testChecker{lockTime: 1}))
+
+	wrongSecret := append(unlockScript(t, testSign(recipient), []byte("guess")), byte(OP_1))
+	assert.ErrorIs(t, Verify(wrongSecret, lockScript, &testChecker{lockTime: 1}), ErrVerifyFailed)
+
+	refundScript := append(unlockScript(t, testSign(refund)), byte(OP_0))
+	assert.ErrorIs(t, Verify(refundScript, lockScript, &testChecker{lockTime: 99}), ErrLockTimeNotMet)
+	assert.Nil(t, Verify(refundScript, lockScript, &testChecker{lockTime: 100}))
+
+	// The branch selector must be minimal
+	nonMinimal := unlockScript(t, testSign(refund), []byte{0x00})
+	assert.ErrorIs(t, Verify(nonMinimal, lockScript, &testChecker{lockTime: 100}), ErrInvalidScript)
+}
+
+func TestCheckSequenceVerify(t *testing.T) {
+	pubKey := []byte("alice")
+
+	lockScript, err := NewBuilder().AddInt(10).AddOp(OP_CHECKSEQUENCEVERIFY).AddOp(OP_DROP).
+		AddData(pubKey).AddOp(OP_CHECKSIG).Script()
+	require.Nil(t, err)
+
+	unlock := unlockScript(t, testSign(pubKey))
+	assert.ErrorIs(t, Verify(unlock, lockScript, &testChecker{sequence: 9}), ErrSequenceNotMet)
+	assert.Nil(t, Verify(unlock, lockScript, &testChecker{sequence: 10}))
+}
+
+func TestScriptLimits(t *testing.T) {
+	checker := &testChecker{}
+
+	// Opcodes are counted in skipped branches as well
+	b := NewBuilder().AddOp(OP_0).AddOp(OP_IF)
+	for range MaxOps {
+		b.AddOp(OP_NOP)
+	}
+	lockScript, err := b.AddOp(OP_ENDIF).AddOp(OP_1).Script()
+	require.Nil(t, err)
+	assert.ErrorIs(t, Verify(nil, lockScript, checker), ErrLimitExceeded)
+
+	b = NewBuilder()
+	for range MaxStackSize {
+		b.AddOp(OP_1)
+	}
+	lockScript, err = b.AddOp(OP_DUP).Script()
+	require.Nil(t, err)
+	assert.ErrorIs(t, Verify(nil, lockScript, checker), ErrLimitExceeded)
+
+	_, err = NewBuilder().AddData(make([]byte, MaxElementSize+1)).Script()
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+
+	assert.ErrorIs(t, Verify(nil, make([]byte, MaxScriptSize+1), checker), ErrLimitExceeded)
+}
+
+func TestScriptControlFlow(t *testing.T) {
+	checker := &testChecker{}
+
+	for _, tc := range []struct {
+		ops []Opcode
+		err error
+	}{
+		{[]Opcode{OP_1, OP_IF, OP_1, OP_ELSE, OP_0, OP_ENDIF}, nil},
+		{[]Opcode{OP_0, OP_IF, OP_0, OP_ELSE, OP_1, OP_ENDIF}, nil},
+		{[]Opcode{OP_0, OP_NOTIF, OP_1, OP_ENDIF}, nil},
+		{[]Opcode{OP_1, OP_IF, OP_1}, ErrInvalidScript},
+		{[]Opcode{OP_1, OP_ENDIF}, ErrInvalidScript},
+		{[]Opcode{OP_1, OP_RETURN}, ErrEarlyReturn},
+		{[]Opcode{OP_1, OP_0, OP_VERIFY}, ErrVerifyFailed},
+		{[]Opcode{OP_1, OP_1 + 1, OP_SWAP, OP_DROP, OP_1 + 1, OP_EQUAL}, nil},
+	} {
+		b := NewBuilder()
+		for _, op := range tc.ops {
+			b.AddOp(op)
+		}
+		lockScript, err := b.Script()
+		require.Nil(t, err)
+
+		assert.ErrorIs(t, Verify(nil, lockScript, checker), tc.err, "script %v", tc.ops)
+	}
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+)
+
+// multisigAddressPrefix marks the string form of multisig addresses. Addresses of public keys are plain hex.
+const multisigAddressPrefix = "ms"
+
+// EncodeAddress returns the string form of the address of the outputs of the given type.
+func EncodeAddress(address []byte, outputType genproto.OutputType) string {
+	if outputType == genproto.OutputType_MULTISIG {
+		return multisigAddressPrefix + hex.EncodeToString(address)
+	}
+
+	return hex.EncodeToString(address)
+}
+
+// DecodeAddress parses the string form of an address and returns the address
+// along with the type of the outputs paying to it.
+func DecodeAddress(s string) ([]byte, genproto.OutputType, error) {
+	outputType := genproto.OutputType_TRANSFER
+	if rest, ok := strings.CutPrefix(s, multisigAddressPrefix); ok {
+		s = rest
+		outputType = genproto.OutputType_MULTISIG
+	}
+
+	address, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode address: %w", err)
+	}
+
+	if len(address) != cryptography.AddressLen {
+		return nil, 0, fmt.Errorf("address has %d bytes, expected %d", len(address), cryptography.AddressLen)
+	}
+
+	return address, outputType, nil
+}
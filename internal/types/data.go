@@ -0,0 +1,60 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+)
+
+const (
+	// MaxDataSize is the maximum size of the payload of a data output in bytes.
+	MaxDataSize = 80
+	// MaxDataOutputs is the maximum number of data outputs of a transaction.
+	MaxDataOutputs = 1
+)
+
+// NewDataOutput creates an unspendable output carrying the data.
+func NewDataOutput(data []byte) (*genproto.TxOutput, error) {
+	if len(data) == 0 || len(data) > MaxDataSize {
+		return nil, fmt.Errorf("data output has %d bytes, expected from 1 to %d", len(data), MaxDataSize)
+	}
+
+	return &genproto.TxOutput{
+		Type: genproto.OutputType_DATA,
+		Data: data,
+	}, nil
+}
+
+// NewDataTransaction creates a transaction spending the inputs into the outputs with a data output carrying
+// the data appended. The outputs can't contain a data output already, since a transaction has only one.
+// The inputs pay the fee of the transaction and are signed by the caller afterwards.
+func NewDataTransaction(inputs []*genproto.TxInput, outputs []*genproto.TxOutput, data []byte) (*genproto.Transaction, error) {
+	for _, output := range outputs {
+		if output.Type == genproto.OutputType_DATA {
+			return nil, fmt.Errorf("transaction already has a data output")
+		}
+	}
+
+	dataOutput, err := NewDataOutput(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &genproto.Transaction{
+		Version: 1,
+		Inputs:  inputs,
+		Outputs: append(append([]*genproto.TxOutput{}, outputs...), dataOutput),
+	}, nil
+}
+
+// DataPayloads returns the payloads of the data outputs of the transaction in their order.
+func DataPayloads(tx *genproto.Transaction) [][]byte {
+	var payloads [][]byte
+	for _, output := range tx.Outputs {
+		if output.Type == genproto.OutputType_DATA {
+			payloads = append(payloads, output.Data)
+		}
+	}
+
+	return payloads
+}
@@ -0,0 +1,29 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataTransaction(t *testing.T) {
+	outputs := []*genproto.TxOutput{{Amount: 10, Address: make([]byte, 20)}}
+
+	tx, err := NewDataTransaction(nil, outputs, []byte("data"))
+	require.Nil(t, err)
+	require.Len(t, tx.Outputs, 2)
+	assert.Len(t, outputs, 1)
+	assert.Equal(t, genproto.OutputType_DATA, tx.Outputs[1].Type)
+	assert.Equal(t, [][]byte{[]byte("data")}, DataPayloads(tx))
+
+	_, err = NewDataTransaction(nil, outputs, nil)
+	assert.NotNil(t, err)
+	_, err = NewDataOutput(make([]byte, MaxDataSize+1))
+	assert.NotNil(t, err)
+
+	// A transaction has only one data output
+	_, err = NewDataTransaction(nil, tx.Outputs, []byte("more data"))
+	assert.NotNil(t, err)
+}
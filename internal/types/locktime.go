@@ -0,0 +1,42 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// LockTimeThreshold separates transaction lock times by block height (below) from lock times by Unix timestamp.
+	LockTimeThreshold = 500_000_000
+	// SequenceLockDisabled is the flag of input sequences without a relative lock.
+	SequenceLockDisabled = 1 << 31
+	// SequenceLockTypeTime is the flag of relative locks by time. Relative locks without it count blocks.
+	SequenceLockTypeTime = 1 << 22
+	// SequenceLockMask selects the value of the relative lock from the sequence.
+	SequenceLockMask = 0xffff
+	// SequenceLockGranularity is the binary logarithm of the unit of relative locks by time (512 seconds).
+	SequenceLockGranularity = 9
+)
+
+// SequenceLockBlocks returns the sequence of an input that can only be included once the spent output
+// is at least the given number of blocks old.
+func SequenceLockBlocks(blocks int) (uint32, error) {
+	if blocks < 0 || blocks > SequenceLockMask {
+		return 0, fmt.Errorf("relative lock of %d blocks is out of range", blocks)
+	}
+
+	return uint32(blocks), nil
+}
+
+// SequenceLockDuration returns the sequence of an input that can only be included once the spent output
+// is at least the given duration old. The duration is rounded up to the units of 512 seconds.
+func SequenceLockDuration(d time.Duration) (uint32, error) {
+	const unit = time.Second << SequenceLockGranularity
+
+	units := (d + unit - 1) / unit
+	if d < 0 || units > SequenceLockMask {
+		return 0, fmt.Errorf("relative lock of %s is out of range", d)
+	}
+
+	return SequenceLockTypeTime | uint32(units), nil
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceLocks(t *testing.T) {
+	sequence, err := SequenceLockBlocks(10)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(10), sequence)
+
+	// Durations are rounded up to the units of 512 seconds
+	sequence, err = SequenceLockDuration(513 * time.Second)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(SequenceLockTypeTime|2), sequence)
+
+	_, err = SequenceLockBlocks(SequenceLockMask + 1)
+	assert.NotNil(t, err)
+	_, err = SequenceLockDuration(-time.Second)
+	assert.NotNil(t, err)
+	_, err = SequenceLockDuration((SequenceLockMask + 1) << SequenceLockGranularity * time.Second)
+	assert.NotNil(t, err)
+}
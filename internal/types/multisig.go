@@ -0,0 +1,188 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"google.golang.org/protobuf/proto"
+)
+
+// MaxMultisigKeys is the maximum number of public keys of a multisig policy.
+const MaxMultisigKeys = 20
+
+// NewMultisigPolicy creates a policy requiring the signatures of threshold out of the public keys.
+func NewMultisigPolicy(threshold int, pubKeys []cryptography.PublicKey) (*genproto.MultisigPolicy, error) {
+	policy := &genproto.MultisigPolicy{Threshold: uint32(threshold)}
+	for _, pubKey := range pubKeys {
+		policy.PublicKeys = append(policy.PublicKeys, pubKey.Bytes())
+	}
+
+	if err := ValidateMultisigPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// ValidateMultisigPolicy checks that the policy has between 1 and MaxMultisigKeys distinct public keys
+// and a threshold between 1 and the number of keys.
+func ValidateMultisigPolicy(policy *genproto.MultisigPolicy) error {
+	if len(policy.PublicKeys) == 0 || len(policy.PublicKeys) > MaxMultisigKeys {
+		return fmt.Errorf("multisig policy has %d public keys, expected from 1 to %d", len(policy.PublicKeys), MaxMultisigKeys)
+	}
+
+	if policy.Threshold == 0 || int(policy.Threshold) > len(policy.PublicKeys) {
+		return fmt.Errorf("multisig policy has threshold %d for %d public keys", policy.Threshold, len(policy.PublicKeys))
+	}
+
+	for idx, pubKey := range policy.PublicKeys {
+		if len(pubKey) != cryptography.PubKeyLen {
+			return fmt.Errorf("multisig policy has a malformed public key %d", idx)
+		}
+
+		for _, other := range policy.PublicKeys[:idx] {
+			if bytes.Equal(pubKey, other) {
+				return fmt.Errorf("multisig policy has a duplicate public key %d", idx)
+			}
+		}
+	}
+
+	return nil
+}
+
+// MultisigAddress returns the address of the policy, which commits to the threshold and the public keys in their order.
+func MultisigAddress(policy *genproto.MultisigPolicy) []byte {
+	b := binary.BigEndian.AppendUint32(nil, policy.Threshold)
+	for _, pubKey := range policy.PublicKeys {
+		b = append(b, pubKey...)
+	}
+
+	hash := sha256.Sum256(b)
+	return hash[:cryptography.AddressLen]
+}
+
+// NewMultisigOutput creates an output paying the amount to the multisig address.
+func NewMultisigOutput(amount int64, address []byte) *genproto.TxOutput {
+	return &genproto.TxOutput{
+		Amount:  amount,
+		Address: address,
+		Type:    genproto.OutputType_MULTISIG,
+	}
+}
+
+// NewMultisigInput creates an input spending an output of the multisig policy with an empty signature slot for every key.
+func NewMultisigInput(prevTxHash []byte, prevTxOutIndex uint32, policy *genproto.MultisigPolicy) *genproto.TxInput {
+	return &genproto.TxInput{
+		PrevTxHash:         prevTxHash,
+		PrevTxOutIndex:     prevTxOutIndex,
+		Multisig:           policy,
+		MultisigSignatures: make([][]byte, len(policy.PublicKeys)),
+	}
+}
+
+// SignMultisigInput fills the signature slot of the key in the multisig input with the given index.
+// Every co-signer signs the same unsigned transaction, the signatures are merged by CombineMultisigSignatures.
+// All signatures of an input have to use the same signature hash type.
+func SignMultisigInput(privKey cryptography.PrivateKey, tx *genproto.Transaction, idx int, spentAmounts []int64, chainID string, hashType SigHashType) error {
+	if idx < 0 || idx >= len(tx.Inputs) {
+		return fmt.Errorf("input index %d is out of range", idx)
+	}
+
+	input := tx.Inputs[idx]
+	if input.Multisig == nil {
+		return fmt.Errorf("input %d doesn't spend a multisig output", idx)
+	}
+
+	keyIdx := -1
+	for i, pubKey := range input.Multisig.PublicKeys {
+		if bytes.Equal(pubKey, privKey.Public().Bytes()) {
+			keyIdx = i
+		}
+	}
+	if keyIdx < 0 {
+		return fmt.Errorf("key is not a part of the multisig policy of input %d", idx)
+	}
+
+	if len(input.MultisigSignatures) != len(input.Multisig.PublicKeys) {
+		return fmt.Errorf("input %d has %d signature slots for %d public keys", idx, len(input.MultisigSignatures), len(input.Multisig.PublicKeys))
+	}
+
+	sig, err := CalculateInputSignature(privKey, tx, idx, spentAmounts, chainID, hashType)
+	if err != nil {
+		return err
+	}
+
+	input.MultisigSignatures[keyIdx] = sig
+	return nil
+}
+
+// CombineMultisigSignatures copies the multisig signatures of the other copy of the transaction to the empty slots of tx
+// until the threshold of the input is reached. Both copies must be the same transaction apart from their signatures.
+func CombineMultisigSignatures(tx, other *genproto.Transaction) error {
+	if !bytes.Equal(hashUnsigned(tx), hashUnsigned(other)) {
+		return fmt.Errorf("transactions differ in more than their multisig signatures")
+	}
+
+	for idx, input := range tx.Inputs {
+		if input.Multisig == nil {
+			continue
+		}
+
+		signed := countSignatures(input)
+		for keyIdx, sig := range other.Inputs[idx].MultisigSignatures {
+			if signed < int(input.Multisig.Threshold) && len(input.MultisigSignatures[keyIdx]) == 0 && len(sig) != 0 {
+				input.MultisigSignatures[keyIdx] = sig
+				signed++
+			}
+		}
+	}
+
+	return nil
+}
+
+// countSignatures returns the number of filled signature slots of the multisig input.
+func countSignatures(input *genproto.TxInput) int {
+	var count int
+	for _, sig := range input.MultisigSignatures {
+		if len(sig) != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// hashUnsigned returns the hash of the transaction without the multisig signatures.
+func hashUnsigned(tx *genproto.Transaction) []byte {
+	txCopy := proto.Clone(tx).(*genproto.Transaction)
+	for _, input := range txCopy.Inputs {
+		input.MultisigSignatures = make([][]byte, len(input.MultisigSignatures))
+	}
+
+	return HashTransactionBytes(txCopy)
+}
+
+// verifyMultisigInput checks that exactly threshold signature slots of the multisig input are filled
+// and that all of them sign the signature hash of the input with their keys.
+func verifyMultisigInput(tx *genproto.Transaction, idx int, hash []byte) bool {
+	input := tx.Inputs[idx]
+	if ValidateMultisigPolicy(input.Multisig) != nil || len(input.MultisigSignatures) != len(input.Multisig.PublicKeys) {
+		return false
+	}
+
+	// More signatures than the threshold would let the co-signers change the transaction hash
+	if countSignatures(input) != int(input.Multisig.Threshold) {
+		return false
+	}
+
+	for keyIdx, sig := range input.MultisigSignatures {
+		if len(sig) != 0 && !VerifySignature(input.Multisig.PublicKeys[keyIdx], sig, hash) {
+			return false
+		}
+	}
+
+	return true
+}
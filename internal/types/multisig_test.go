@@ -0,0 +1,116 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMultisigPolicy(t *testing.T) {
+	var (
+		key1 = cryptography.NewPrivateKey().Public()
+		key2 = cryptography.NewPrivateKey().Public()
+	)
+
+	policy, err := NewMultisigPolicy(2, []cryptography.PublicKey{key1, key2})
+	require.Nil(t, err)
+	assert.Len(t, MultisigAddress(policy), cryptography.AddressLen)
+
+	// The address commits to the threshold and the order of the keys
+	other, err := NewMultisigPolicy(1, []cryptography.PublicKey{key1, key2})
+	require.Nil(t, err)
+	assert.NotEqual(t, MultisigAddress(policy), MultisigAddress(other))
+
+	other, err = NewMultisigPolicy(2, []cryptography.PublicKey{key2, key1})
+	require.Nil(t, err)
+	assert.NotEqual(t, MultisigAddress(policy), MultisigAddress(other))
+
+	_, err = NewMultisigPolicy(0, []cryptography.PublicKey{key1})
+	assert.NotNil(t, err)
+	_, err = NewMultisigPolicy(3, []cryptography.PublicKey{key1, key2})
+	assert.NotNil(t, err)
+	_, err = NewMultisigPolicy(1, []cryptography.PublicKey{key1, key1})
+	assert.NotNil(t, err)
+	_, err = NewMultisigPolicy(1, nil)
+	assert.NotNil(t, err)
+}
+
+func TestAddressEncoding(t *testing.T) {
+	var (
+		keyAddress = cryptography.NewPrivateKey().Public().Address().Bytes()
+		policy, _  = NewMultisigPolicy(1, []cryptography.PublicKey{cryptography.NewPrivateKey().Public()})
+		msAddress  = MultisigAddress(policy)
+	)
+
+	address, outputType, err := DecodeAddress(EncodeAddress(keyAddress, genproto.OutputType_TRANSFER))
+	require.Nil(t, err)
+	assert.Equal(t, keyAddress, address)
+	assert.Equal(t, genproto.OutputType_TRANSFER, outputType)
+
+	encoded := EncodeAddress(msAddress, genproto.OutputType_MULTISIG)
+	assert.NotEqual(t, EncodeAddress(msAddress, genproto.OutputType_TRANSFER), encoded)
+
+	address, outputType, err = DecodeAddress(encoded)
+	require.Nil(t, err)
+	assert.Equal(t, msAddress, address)
+	assert.Equal(t, genproto.OutputType_MULTISIG, outputType)
+
+	_, _, err = DecodeAddress("ms1234")
+	assert.NotNil(t, err)
+	_, _, err = DecodeAddress("not an address")
+	assert.NotNil(t, err)
+}
+
+func TestCoSignMultisigInput(t *testing.T) {
+	var (
+		privKeys     = []cryptography.PrivateKey{cryptography.NewPrivateKey(), cryptography.NewPrivateKey(), cryptography.NewPrivateKey()}
+		spentAmounts = []int64{100}
+	)
+
+	policy, err := NewMultisigPolicy(2, []cryptography.PublicKey{privKeys[0].Public(), privKeys[1].Public(), privKeys[2].Public()})
+	require.Nil(t, err)
+
+	tx := &genproto.Transaction{
+		Version: 1,
+		Inputs:  []*genproto.TxInput{NewMultisigInput(random.Random32ByteHash(), 0, policy)},
+		Outputs: []*genproto.TxOutput{{Amount: 90, Address: cryptography.NewPrivateKey().Public().Address().Bytes()}},
+	}
+
+	// Every co-signer signs its own copy of the unsigned transaction
+	tx1 := proto.Clone(tx).(*genproto.Transaction)
+	tx2 := proto.Clone(tx).(*genproto.Transaction)
+	tx3 := proto.Clone(tx).(*genproto.Transaction)
+	require.Nil(t, SignMultisigInput(privKeys[0], tx1, 0, spentAmounts, testChainID, SigHashAll))
+	require.Nil(t, SignMultisigInput(privKeys[2], tx2, 0, spentAmounts, testChainID, SigHashAll))
+	require.Nil(t, SignMultisigInput(privKeys[1], tx3, 0, spentAmounts, testChainID, SigHashAll))
+
+	assert.False(t, VerifyTransaction(tx1, spentAmounts, testChainID))
+	assert.Equal(t, 2, CountSignatureChecks(tx1))
+
+	require.Nil(t, CombineMultisigSignatures(tx1, tx2))
+	assert.True(t, VerifyTransaction(tx1, spentAmounts, testChainID))
+	assert.False(t, VerifyTransaction(tx1, spentAmounts, "other"))
+
+	// Signatures above the threshold are not combined and not allowed
+	require.Nil(t, CombineMultisigSignatures(tx1, tx3))
+	assert.True(t, VerifyTransaction(tx1, spentAmounts, testChainID))
+
+	tx1.Inputs[0].MultisigSignatures[1] = tx3.Inputs[0].MultisigSignatures[1]
+	assert.False(t, VerifyTransaction(tx1, spentAmounts, testChainID))
+	tx1.Inputs[0].MultisigSignatures[1] = nil
+
+	// Signatures of another key in the slot are invalid
+	tx1.Inputs[0].MultisigSignatures[0], tx1.Inputs[0].MultisigSignatures[1] = nil, tx1.Inputs[0].MultisigSignatures[0]
+	assert.False(t, VerifyTransaction(tx1, spentAmounts, testChainID))
+
+	// Only copies of the same transaction can be combined
+	tx3.Outputs[0].Amount++
+	assert.NotNil(t, CombineMultisigSignatures(tx1, tx3))
+
+	assert.NotNil(t, SignMultisigInput(cryptography.NewPrivateKey(), tx, 0, spentAmounts, testChainID, SigHashAll))
+}
@@ -0,0 +1,77 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+)
+
+var oneLsh256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// CompactToTarget converts the compact representation of a difficulty target (block header bits) to a number.
+// The highest byte of the compact form is the length of the target in bytes and the lower 3 bytes are
+// its most significant digits. Negative targets are not valid, so zero is returned for them.
+func CompactToTarget(bits uint32) *big.Int {
+	mantissa := int64(bits & 0x007fffff)
+	exponent := uint(bits >> 24)
+
+	if bits&0x00800000 != 0 {
+		return new(big.Int)
+	}
+
+	target := big.NewInt(mantissa)
+	if exponent <= 3 {
+		return target.Rsh(target, 8*(3-exponent))
+	}
+
+	return target.Lsh(target, 8*(exponent-3))
+}
+
+// TargetToCompact converts the difficulty target to its compact representation.
+// Digits that don't fit into the 3 byte mantissa are truncated.
+func TargetToCompact(target *big.Int) uint32 {
+	if target.Sign() <= 0 {
+		return 0
+	}
+
+	exponent := uint(len(target.Bytes()))
+
+	var mantissa uint64
+	if exponent <= 3 {
+		mantissa = target.Uint64() << (8 * (3 - exponent))
+	} else {
+		mantissa = new(big.Int).Rsh(target, 8*(exponent-3)).Uint64()
+	}
+
+	// The sign bit of the mantissa must not be set
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+
+	return uint32(exponent<<24) | uint32(mantissa)
+}
+
+// VerifyProofOfWork checks that the header hash, read as a big-endian number, doesn't exceed
+// the difficulty target of the header.
+func VerifyProofOfWork(header *genproto.BlockHeader) bool {
+	target := CompactToTarget(header.Bits)
+	if target.Sign() <= 0 {
+		return false
+	}
+
+	hash := new(big.Int).SetBytes(HashBlockHeader(header))
+
+	return hash.Cmp(target) <= 0
+}
+
+// CalculateWork returns the expected number of hashes needed to find a header hash that meets the target,
+// which is 2^256 / (target + 1).
+func CalculateWork(bits uint32) *big.Int {
+	target := CompactToTarget(bits)
+	if target.Sign() <= 0 {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Div(oneLsh256, target.Add(target, big.NewInt(1)))
+}
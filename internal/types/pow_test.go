@@ -0,0 +1,45 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompactTarget(t *testing.T) {
+	target := CompactToTarget(0x1d00ffff)
+	expected, _ := new(big.Int).SetString("ffff0000000000000000000000000000000000000000000000000000", 16)
+	assert.Equal(t, expected, target)
+	assert.Equal(t, uint32(0x1d00ffff), TargetToCompact(target))
+
+	// The mantissa is shifted when its sign bit would be set
+	assert.Equal(t, uint32(0x02008000), TargetToCompact(big.NewInt(0x80)))
+	assert.Equal(t, big.NewInt(0x80), CompactToTarget(0x02008000))
+
+	assert.Equal(t, 0, CompactToTarget(0x04923456).Sign())
+	assert.Equal(t, uint32(0), TargetToCompact(new(big.Int)))
+}
+
+func TestVerifyProofOfWork(t *testing.T) {
+	header := &genproto.BlockHeader{
+		Version: 1,
+		Bits:    0x207fffff,
+	}
+
+	for !VerifyProofOfWork(header) {
+		header.Nonce++
+	}
+
+	hash := new(big.Int).SetBytes(HashBlockHeader(header))
+	assert.True(t, hash.Cmp(CompactToTarget(header.Bits)) <= 0)
+
+	header.Bits = 0
+	assert.False(t, VerifyProofOfWork(header))
+}
+
+func TestCalculateWork(t *testing.T) {
+	assert.Equal(t, big.NewInt(2), CalculateWork(0x207fffff))
+	assert.Equal(t, 1, CalculateWork(0x1d00ffff).Cmp(CalculateWork(0x1e00ffff)))
+}
@@ -0,0 +1,136 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"google.golang.org/protobuf/proto"
+)
+
+// SigHashType selects the parts of a transaction an input signature commits to.
+type SigHashType uint32
+
+const (
+	// SigHashAll commits to all inputs and outputs.
+	SigHashAll SigHashType = 0
+	// SigHashNone commits to all inputs and no outputs, so anybody can choose where the coins go.
+	SigHashNone SigHashType = 1
+	// SigHashSingle commits to all inputs and only the output with the same index as the signed input.
+	SigHashSingle SigHashType = 2
+	// SigHashAnyoneCanPay is a flag combined with the other types. The signature only commits to the signed input,
+	// so other parties can add their own inputs to the transaction.
+	SigHashAnyoneCanPay SigHashType = 0x80
+
+	sigHashBaseMask = 0x7f
+)
+
+// IsValid checks that the type is one of the base types, optionally combined with SigHashAnyoneCanPay.
+func (t SigHashType) IsValid() bool {
+	return t&^(sigHashBaseMask|SigHashAnyoneCanPay) == 0 && t&sigHashBaseMask <= SigHashSingle
+}
+
+// CalculateSignatureHash computes the hash the signature of the input with the given index signs.
+// The hash commits to the chain ID, so signatures can't be replayed on another network, to the input index
+// and to the amounts of the outputs spent by the inputs, given in the order of the inputs. Which inputs
+// and outputs it commits to is selected by the sigHashType of the input.
+//
+// Signatures, unlock scripts, public keys and multisig policies of the other inputs are never committed to, so the inputs
+// can be signed by different parties in any order.
+func CalculateSignatureHash(tx *genproto.Transaction, idx int, spentAmounts []int64, chainID string) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.Inputs) {
+		return nil, fmt.Errorf("input index %d is out of range", idx)
+	}
+
+	if len(spentAmounts) != len(tx.Inputs) {
+		return nil, fmt.Errorf("got %d spent amounts for %d inputs", len(spentAmounts), len(tx.Inputs))
+	}
+
+	hashType := SigHashType(tx.Inputs[idx].SigHashType)
+	if !hashType.IsValid() {
+		return nil, fmt.Errorf("input %d has an invalid signature hash type %#x", idx, hashType)
+	}
+
+	txCopy := proto.Clone(tx).(*genproto.Transaction)
+	for inputIdx, input := range txCopy.Inputs {
+		input.Signature = nil
+		input.UnlockScript = nil
+		input.MultisigSignatures = nil
+		if inputIdx != idx {
+			input.PublicKey = nil
+			input.Multisig = nil
+			input.SigHashType = 0
+		}
+	}
+
+	switch hashType & sigHashBaseMask {
+	case SigHashNone:
+		txCopy.Outputs = nil
+	case SigHashSingle:
+		if idx >= len(txCopy.Outputs) {
+			return nil, fmt.Errorf("input %d signs a single output, but there is no output with the same index", idx)
+		}
+
+		// Only the position of the other outputs is committed to
+		txCopy.Outputs = txCopy.Outputs[:idx+1]
+		for outputIdx := range idx {
+			txCopy.Outputs[outputIdx] = &genproto.TxOutput{}
+		}
+	}
+
+	committedIdx := idx
+	if hashType&SigHashAnyoneCanPay != 0 {
+		txCopy.Inputs = txCopy.Inputs[idx : idx+1]
+		spentAmounts = spentAmounts[idx : idx+1]
+		committedIdx = 0
+	}
+
+	b, err := proto.Marshal(txCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	preimage := binary.AppendUvarint(nil, uint64(len(chainID)))
+	preimage = append(preimage, chainID...)
+	preimage = binary.BigEndian.AppendUint32(preimage, uint32(hashType))
+	preimage = binary.BigEndian.AppendUint32(preimage, uint32(committedIdx))
+	for _, amount := range spentAmounts {
+		preimage = binary.BigEndian.AppendUint64(preimage, uint64(amount))
+	}
+	preimage = append(preimage, b...)
+
+	hash := sha256.Sum256(preimage)
+	return hash[:], nil
+}
+
+// SignTransactionInput signs the input with the given index using the signature hash type.
+// The spent amounts are the amounts of the outputs spent by the inputs, in the order of the inputs.
+func SignTransactionInput(privKey cryptography.PrivateKey, tx *genproto.Transaction, idx int, spentAmounts []int64, chainID string, hashType SigHashType) error {
+	sig, err := CalculateInputSignature(privKey, tx, idx, spentAmounts, chainID, hashType)
+	if err != nil {
+		return err
+	}
+
+	tx.Inputs[idx].Signature = sig
+	return nil
+}
+
+// CalculateInputSignature sets the signature hash type of the input with the given index and returns
+// the signature of its signature hash. It is used to sign inputs spending outputs with a lock script,
+// which carry their signatures in the unlock script. All signatures of an input use the same hash type.
+func CalculateInputSignature(privKey cryptography.PrivateKey, tx *genproto.Transaction, idx int, spentAmounts []int64, chainID string, hashType SigHashType) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.Inputs) {
+		return nil, fmt.Errorf("input index %d is out of range", idx)
+	}
+
+	tx.Inputs[idx].SigHashType = uint32(hashType)
+
+	hash, err := CalculateSignatureHash(tx, idx, spentAmounts, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	return privKey.Sign(hash).Bytes(), nil
+}
@@ -0,0 +1,140 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+const testChainID = "test"
+
+func newSigHashTestTx(privKey cryptography.PrivateKey, inputs, outputs int) *genproto.Transaction {
+	tx := &genproto.Transaction{Version: 1}
+
+	for range inputs {
+		tx.Inputs = append(tx.Inputs, &genproto.TxInput{
+			PrevTxHash: random.Random32ByteHash(),
+			PublicKey:  privKey.Public().Bytes(),
+		})
+	}
+
+	for idx := range outputs {
+		tx.Outputs = append(tx.Outputs, &genproto.TxOutput{
+			Amount:  int64(idx + 1),
+			Address: cryptography.NewPrivateKey().Public().Address().Bytes(),
+		})
+	}
+
+	return tx
+}
+
+func TestSigHashAll(t *testing.T) {
+	var (
+		privKey      = cryptography.NewPrivateKey()
+		tx           = newSigHashTestTx(privKey, 2, 2)
+		spentAmounts = []int64{10, 20}
+	)
+
+	require.Nil(t, SignTransactionInput(privKey, tx, 0, spentAmounts, testChainID, SigHashAll))
+	require.Nil(t, SignTransactionInput(privKey, tx, 1, spentAmounts, testChainID, SigHashAll))
+	assert.True(t, VerifyTransaction(tx, spentAmounts, testChainID))
+
+	// Signatures are bound to the network
+	assert.False(t, VerifyTransaction(tx, spentAmounts, "other"))
+
+	// Signatures are bound to the spent amounts
+	assert.False(t, VerifyTransaction(tx, []int64{20, 10}, testChainID))
+
+	// Signatures are bound to the input index
+	swapped := proto.Clone(tx).(*genproto.Transaction)
+	swapped.Inputs[0].Signature, swapped.Inputs[1].Signature = swapped.Inputs[1].Signature, swapped.Inputs[0].Signature
+	assert.False(t, VerifyTransaction(swapped, spentAmounts, testChainID))
+
+	tx.Outputs[1].Amount++
+	assert.False(t, VerifyTransaction(tx, spentAmounts, testChainID))
+}
+
+func TestSigHashNone(t *testing.T) {
+	var (
+		privKey      = cryptography.NewPrivateKey()
+		tx           = newSigHashTestTx(privKey, 1, 2)
+		spentAmounts = []int64{10}
+	)
+
+	require.Nil(t, SignTransactionInput(privKey, tx, 0, spentAmounts, testChainID, SigHashNone))
+
+	// The outputs can be changed by anybody
+	tx.Outputs = tx.Outputs[:1]
+	tx.Outputs[0].Amount = 5
+	assert.True(t, VerifyTransaction(tx, spentAmounts, testChainID))
+
+	// The inputs can't
+	tx.Inputs[0].PrevTxOutIndex = 1
+	assert.False(t, VerifyTransaction(tx, spentAmounts, testChainID))
+}
+
+func TestSigHashSingle(t *testing.T) {
+	var (
+		privKey      = cryptography.NewPrivateKey()
+		tx           = newSigHashTestTx(privKey, 2, 2)
+		spentAmounts = []int64{10, 20}
+	)
+
+	require.Nil(t, SignTransactionInput(privKey, tx, 1, spentAmounts, testChainID, SigHashSingle))
+	require.Nil(t, SignTransactionInput(privKey, tx, 0, spentAmounts, testChainID, SigHashAll))
+	require.True(t, VerifyTransaction(tx, spentAmounts, testChainID))
+
+	// Only the output with the index of the input is committed to by its signature
+	require.Nil(t, SignTransactionInput(privKey, tx, 0, spentAmounts, testChainID, SigHashNone))
+	tx.Outputs[0].Amount = 100
+	tx.Outputs = append(tx.Outputs, &genproto.TxOutput{Amount: 1})
+	assert.True(t, VerifyTransaction(tx, spentAmounts, testChainID))
+
+	tx.Outputs[1].Amount = 100
+	assert.False(t, VerifyTransaction(tx, spentAmounts, testChainID))
+
+	// There must be an output with the index of the input
+	tx.Outputs = tx.Outputs[:1]
+	assert.NotNil(t, SignTransactionInput(privKey, tx, 1, spentAmounts, testChainID, SigHashSingle))
+}
+
+func TestSigHashAnyoneCanPay(t *testing.T) {
+	var (
+		privKey      = cryptography.NewPrivateKey()
+		otherPrivKey = cryptography.NewPrivateKey()
+		tx           = newSigHashTestTx(privKey, 1, 1)
+	)
+
+	require.Nil(t, SignTransactionInput(privKey, tx, 0, []int64{10}, testChainID, SigHashAll|SigHashAnyoneCanPay))
+	require.True(t, VerifyTransaction(tx, []int64{10}, testChainID))
+
+	// Another party adds and signs its own input without breaking the first signature
+	tx.Inputs = append([]*genproto.TxInput{{
+		PrevTxHash: random.Random32ByteHash(),
+		PublicKey:  otherPrivKey.Public().Bytes(),
+	}}, tx.Inputs...)
+	spentAmounts := []int64{5, 10}
+
+	require.Nil(t, SignTransactionInput(otherPrivKey, tx, 0, spentAmounts, testChainID, SigHashAll))
+	assert.True(t, VerifyTransaction(tx, spentAmounts, testChainID))
+
+	// The outputs are still committed to
+	tx.Outputs[0].Amount++
+	assert.False(t, VerifyTransaction(tx, spentAmounts, testChainID))
+}
+
+func TestSigHashTypeIsValid(t *testing.T) {
+	assert.True(t, SigHashAll.IsValid())
+	assert.True(t, (SigHashSingle | SigHashAnyoneCanPay).IsValid())
+	assert.False(t, SigHashType(3).IsValid())
+	assert.False(t, SigHashType(0x100).IsValid())
+
+	privKey := cryptography.NewPrivateKey()
+	tx := newSigHashTestTx(privKey, 1, 1)
+	assert.NotNil(t, SignTransactionInput(privKey, tx, 0, []int64{1}, testChainID, SigHashType(3)))
+}
@@ -2,10 +2,12 @@ package types
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 
 	"github.com/oleglegun/blockchain-btc/internal/cryptography"
 	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"github.com/oleglegun/blockchain-btc/internal/script"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -23,39 +25,139 @@ func HashTransactionString(tx *genproto.Transaction) string {
 	return hex.EncodeToString(HashTransactionBytes(tx))
 }
 
-func CalculateTransactionSignature(privKey cryptography.PrivateKey, tx *genproto.Transaction) cryptography.Signature {
-	return privKey.Sign(HashTransactionBytes(tx))
+// IsCoinbaseTransaction checks if the transaction is a coinbase transaction, which has no inputs
+// and creates new coins paying the block producer.
+func IsCoinbaseTransaction(tx *genproto.Transaction) bool {
+	return len(tx.Inputs) == 0 && !IsGovernanceTransaction(tx) && !IsSlashingTransaction(tx)
 }
 
-// VerifyTransaction verifies the transaction by checking the signature of each input.
-func VerifyTransaction(tx *genproto.Transaction) bool {
-	var isValid = true
+// IsGovernanceTransaction checks if the transaction changes the validator set instead of transferring coins.
+func IsGovernanceTransaction(tx *genproto.Transaction) bool {
+	return tx.Governance != nil
+}
+
+// IsSlashingTransaction checks if the transaction carries evidence of a validator signing conflicting blocks.
+func IsSlashingTransaction(tx *genproto.Transaction) bool {
+	return tx.SlashingEvidence != nil
+}
+
+// NewSlashingTransaction creates a slashing transaction from two different blocks signed by the same validator.
+func NewSlashingTransaction(block1, block2 *genproto.Block) *genproto.Transaction {
+	return &genproto.Transaction{
+		Version: 1,
+		SlashingEvidence: &genproto.SlashingEvidence{
+			PublicKey:  block1.PublicKey,
+			Header1:    block1.Header,
+			Signature1: block1.Signature,
+			Header2:    block2.Header,
+			Signature2: block2.Signature,
+		},
+	}
+}
+
+// NewGovernanceTransaction creates an unsigned governance transaction for the given action.
+func NewGovernanceTransaction(action *genproto.GovernanceAction) *genproto.Transaction {
+	return &genproto.Transaction{
+		Version:    1,
+		Governance: action,
+	}
+}
 
-	inputSignatures := make([][]byte, len(tx.Inputs))
+// HashGovernanceAction computes the hash the validators sign to approve the action. It commits to the chain ID,
+// so the approval can't be replayed on another network with the same validators.
+func HashGovernanceAction(action *genproto.GovernanceAction, chainID string) []byte {
+	b, err := proto.Marshal(action)
+	if err != nil {
+		panic(err)
+	}
+
+	preimage := binary.AppendUvarint(nil, uint64(len(chainID)))
+	preimage = append(preimage, chainID...)
+	preimage = append(preimage, b...)
+
+	hash := sha256.Sum256(preimage)
+	return hash[:]
+}
+
+// SignGovernanceTransaction adds the validator signature of the governance action to the transaction.
+func SignGovernanceTransaction(privKey cryptography.PrivateKey, tx *genproto.Transaction, chainID string) {
+	tx.GovernanceSignatures = append(tx.GovernanceSignatures, &genproto.ValidatorSignature{
+		PublicKey: privKey.Public().Bytes(),
+		Signature: privKey.Sign(HashGovernanceAction(tx.Governance, chainID)).Bytes(),
+	})
+}
 
-	// Remove signatures
-	for idx, input := range tx.Inputs {
-		if len(input.Signature) == 0 {
-			panic("tx signature is empty")
+// NewCoinbaseTransaction creates a coinbase transaction for the block at the given height paying the amount to the address.
+func NewCoinbaseTransaction(height int32, address []byte, amount int64) *genproto.Transaction {
+	return &genproto.Transaction{
+		Version:        1,
+		Inputs:         []*genproto.TxInput{},
+		Outputs:        []*genproto.TxOutput{{Amount: amount, Address: address}},
+		CoinbaseHeight: height,
+	}
+}
+
+// CountSignatureChecks returns the number of signatures that have to be verified to validate the transaction.
+// Inputs spending multisig outputs need a check for every required signature, inputs spending other outputs
+// without a lock script a single check. The checks of a lock script
+// are counted in the transaction creating the output.
+func CountSignatureChecks(tx *genproto.Transaction) int {
+	count := len(tx.GovernanceSignatures)
+	if IsSlashingTransaction(tx) {
+		count += 2
+	}
+
+	for _, input := range tx.Inputs {
+		switch {
+		case input.Multisig != nil:
+			count += int(input.Multisig.Threshold)
+		case len(input.UnlockScript) != 0:
+			count += script.CountSigOps(input.UnlockScript)
+		default:
+			count++
 		}
-		inputSignatures[idx] = input.Signature
-		input.Signature = nil
 	}
 
-	for idx, input := range tx.Inputs {
-		sig := cryptography.NewSignatureFromBytes(inputSignatures[idx])
-		pubKey := cryptography.NewPublicKeyFromBytes(input.PublicKey)
+	for _, output := range tx.Outputs {
+		count += script.CountSigOps(output.LockScript)
+	}
+
+	return count
+}
 
-		if !sig.Verify(pubKey, HashTransactionBytes(tx)) {
-			isValid = false
-			break
+// VerifyTransaction verifies the transaction by checking the signature of each input against its signature hash.
+// The spent amounts are the amounts of the outputs spent by the inputs, in the order of the inputs.
+// Inputs with a malformed public key or signature make the transaction invalid.
+func VerifyTransaction(tx *genproto.Transaction, spentAmounts []int64, chainID string) bool {
+	for idx := range tx.Inputs {
+		if !VerifyTransactionInput(tx, idx, spentAmounts, chainID) {
+			return false
 		}
 	}
 
-	// Put back signatures
-	for idx, input := range tx.Inputs {
-		input.Signature = inputSignatures[idx]
+	return true
+}
+
+// VerifyTransactionInput checks the signature of the input with the given index against its signature hash.
+// Inputs spending multisig outputs must have the signatures of the threshold number of keys of their policy.
+func VerifyTransactionInput(tx *genproto.Transaction, idx int, spentAmounts []int64, chainID string) bool {
+	hash, err := CalculateSignatureHash(tx, idx, spentAmounts, chainID)
+	if err != nil {
+		return false
+	}
+
+	if tx.Inputs[idx].Multisig != nil {
+		return verifyMultisigInput(tx, idx, hash)
+	}
+
+	return VerifySignature(tx.Inputs[idx].PublicKey, tx.Inputs[idx].Signature, hash)
+}
+
+// VerifySignature checks the signature of the hash with the public key. Malformed keys and signatures are invalid.
+func VerifySignature(pubKey, sig, hash []byte) bool {
+	if len(pubKey) != cryptography.PubKeyLen || len(sig) != cryptography.SigLen {
+		return false
 	}
 
-	return isValid
+	return cryptography.NewSignatureFromBytes(sig).Verify(cryptography.NewPublicKeyFromBytes(pubKey), hash)
 }
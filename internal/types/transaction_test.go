@@ -50,10 +50,32 @@ func TestCalculateTransactionHash(t *testing.T) {
 		Outputs: []*genproto.TxOutput{txOut1, txOut2},
 	}
 
-	sig1 := CalculateTransactionSignature(sender1PrivKey, tx)
-	sig2 := CalculateTransactionSignature(sender2PrivKey, tx)
-	txIn1.Signature = sig1.Bytes()
-	txIn2.Signature = sig2.Bytes()
+	spentAmounts := []int64{4, 6}
+	assert.Nil(t, SignTransactionInput(sender1PrivKey, tx, 0, spentAmounts, testChainID, SigHashAll))
+	assert.Nil(t, SignTransactionInput(sender2PrivKey, tx, 1, spentAmounts, testChainID, SigHashAll))
 
-	assert.True(t, VerifyTransaction(tx))
+	assert.True(t, VerifyTransaction(tx, spentAmounts, testChainID))
+}
+
+func TestVerifyTransactionWithoutSignature(t *testing.T) {
+	privKey := cryptography.NewPrivateKey()
+
+	tx := &genproto.Transaction{
+		Version: 1,
+		Inputs: []*genproto.TxInput{
+			{
+				PrevTxHash:     random.Random32ByteHash(),
+				PrevTxOutIndex: 0,
+				PublicKey:      privKey.Public().Bytes(),
+			},
+		},
+		Outputs: []*genproto.TxOutput{
+			{
+				Amount:  1,
+				Address: privKey.Public().Address().Bytes(),
+			},
+		},
+	}
+
+	assert.False(t, VerifyTransaction(tx, []int64{1}, testChainID))
 }
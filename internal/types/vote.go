@@ -0,0 +1,46 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/oleglegun/blockchain-btc/internal/cryptography"
+	"github.com/oleglegun/blockchain-btc/internal/genproto"
+	"google.golang.org/protobuf/proto"
+)
+
+// HashVote computes the hash of the vote without its signature. Like the governance hash it commits to the chain ID,
+// so the vote can't be replayed on another network with the same validators.
+func HashVote(vote *genproto.Vote, chainID string) []byte {
+	unsigned := proto.Clone(vote).(*genproto.Vote)
+	unsigned.Signature = nil
+
+	b, err := proto.Marshal(unsigned)
+	if err != nil {
+		panic(err)
+	}
+
+	preimage := binary.AppendUvarint(nil, uint64(len(chainID)))
+	preimage = append(preimage, chainID...)
+	preimage = append(preimage, b...)
+
+	hash := sha256.Sum256(preimage)
+	return hash[:]
+}
+
+// SignVote sets the public key and the signature of the validator casting the vote.
+func SignVote(privKey cryptography.PrivateKey, vote *genproto.Vote, chainID string) {
+	vote.PublicKey = privKey.Public().Bytes()
+	vote.Signature = privKey.Sign(HashVote(vote, chainID)).Bytes()
+}
+
+func VerifyVote(vote *genproto.Vote, chainID string) bool {
+	if len(vote.PublicKey) != cryptography.PubKeyLen || len(vote.Signature) != cryptography.SigLen {
+		return false
+	}
+
+	pubKey := cryptography.NewPublicKeyFromBytes(vote.PublicKey)
+	signature := cryptography.NewSignatureFromBytes(vote.Signature)
+
+	return signature.Verify(pubKey, HashVote(vote, chainID))
+}